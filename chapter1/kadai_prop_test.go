@@ -0,0 +1,69 @@
+package chapter1
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/apbgo/go-study-group/tools/prop"
+)
+
+// calc Calcの結果を返す。エラーになった場合はパニックしてプロパティを失敗させる
+func calc(op string, x, y int) int {
+	result, err := Calc(op, x, y)
+	if err != nil {
+		panic(fmt.Sprintf("Calc(%q, %d, %d): %v", op, x, y, err))
+	}
+	return result
+}
+
+func TestCalc_Property(t *testing.T) {
+	// 掛け算があふれない範囲にする
+	ints := prop.Int(-1<<20, 1<<20)
+
+	t.Run("足し算と引き算は逆の演算", func(t *testing.T) {
+		t.Parallel()
+		prop.Check(t, func(x, y int) bool {
+			return calc("-", calc("+", x, y), y) == x && calc("+", calc("-", x, y), y) == x
+		}, ints, ints)
+	})
+
+	t.Run("掛け算した値を割ると元に戻る", func(t *testing.T) {
+		t.Parallel()
+		prop.Check(t, func(x, y int) bool {
+			if y == 0 {
+				return true
+			}
+			return calc("÷", calc("×", x, y), y) == x
+		}, ints, ints)
+	})
+
+	t.Run("割り算の商と余りから元に戻る", func(t *testing.T) {
+		t.Parallel()
+		prop.Check(t, func(x, y int) bool {
+			if y == 0 {
+				return true
+			}
+			return calc("+", calc("×", calc("÷", x, y), y), x%y) == x
+		}, ints, ints)
+	})
+
+	t.Run("0で割るとエラー", func(t *testing.T) {
+		t.Parallel()
+		prop.Check(t, func(x int) bool {
+			result, err := Calc("÷", x, 0)
+			return result == 0 && err != nil
+		}, ints)
+	})
+
+	t.Run("想定していない演算子はエラー", func(t *testing.T) {
+		t.Parallel()
+		prop.Check(t, func(op string, x, y int) bool {
+			switch op {
+			case "+", "-", "×", "÷":
+				return true
+			}
+			result, err := Calc(op, x, y)
+			return result == 0 && err != nil
+		}, prop.String(0, 2), ints, ints)
+	})
+}
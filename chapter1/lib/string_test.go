@@ -0,0 +1,92 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/apbgo/go-study-group/tools/prop"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToCamel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "hoge_fuga", want: "HogeFuga"},
+		{in: "hoge-fuga piyo", want: "HogeFugaPiyo"},
+		{in: "item2name", want: "Item2Name"},
+		{in: "", want: ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.in, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, ToCamel(tt.in))
+		})
+	}
+}
+
+func TestToSnake(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "HogeFuga", want: "hoge_fuga"},
+		{in: "hogeFugaPiyo", want: "hoge_fuga_piyo"},
+		{in: "UserID", want: "user_id"},
+		{in: "", want: ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.in, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, ToSnake(tt.in))
+		})
+	}
+}
+
+func TestToSnake_ToCamel_Property(t *testing.T) {
+	// 1文字の単語は"AB"のように大文字が続いて区切れなくなるので2文字以上にする
+	words := prop.SliceOf(prop.StringOf("abcdefghijklmnopqrstuvwxyz", 2, 8), 1, 5)
+
+	t.Run("スネークケースをキャメルケースにして戻す", func(t *testing.T) {
+		t.Parallel()
+		prop.Check(t, func(words []string) bool {
+			snake := strings.Join(words, "_")
+			return ToSnake(ToCamel(snake)) == snake
+		}, words)
+	})
+
+	t.Run("キャメルケースをスネークケースにして戻す", func(t *testing.T) {
+		t.Parallel()
+		prop.Check(t, func(words []string) bool {
+			camel := ""
+			for _, w := range words {
+				camel += strings.ToUpper(w[:1]) + w[1:]
+			}
+			return ToCamel(ToSnake(camel)) == camel
+		}, words)
+	})
+
+	t.Run("マルチバイトの文字を含んでもスネークケースは2回適用して変わらない", func(t *testing.T) {
+		t.Parallel()
+		prop.Check(t, func(s string) bool {
+			snake := ToSnake(s)
+			return ToSnake(snake) == snake
+		}, prop.String(0, 20))
+	})
+
+	t.Run("キャメルケースは英数字だけになる", func(t *testing.T) {
+		t.Parallel()
+		// ToCamelは英数字以外の文字を取り除くが、その文字の次は大文字にしないので2回適用すると変わることがある ("いa" -> "a" -> "A")
+		prop.Check(t, func(s string) bool {
+			for _, r := range ToCamel(s) {
+				if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+					return false
+				}
+			}
+			return true
+		}, prop.String(0, 20))
+	})
+}
@@ -0,0 +1,60 @@
+package chapter2
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/apbgo/go-study-group/tools/prop"
+)
+
+func TestUnique_Property(t *testing.T) {
+	// 重複が起きやすいように狭い範囲の値にする
+	ints := prop.SliceOf(prop.Int(-5, 5), 0, 30)
+	for name, unique := range map[string]func([]int) []int{"Unique": Unique, "UniqueAns": UniqueAns} {
+		unique := unique
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			t.Run("2回適用しても変わらない", func(t *testing.T) {
+				t.Parallel()
+				prop.Check(t, func(s []int) bool {
+					once := unique(s)
+					return reflect.DeepEqual(unique(once), once)
+				}, ints)
+			})
+
+			t.Run("最初に現れた順番のまま重複を取り除く", func(t *testing.T) {
+				t.Parallel()
+				prop.Check(t, func(s []int) error {
+					var want []int
+					seen := map[int]bool{}
+					for _, v := range s {
+						if !seen[v] {
+							seen[v] = true
+							want = append(want, v)
+						}
+					}
+					got := unique(s)
+					if len(got) != len(want) {
+						return fmt.Errorf("%vの長さは%dになるはずです", got, len(want))
+					}
+					for i := range want {
+						if got[i] != want[i] {
+							return fmt.Errorf("%d番目が%dになるはずです: %v", i, want[i], got)
+						}
+					}
+					return nil
+				}, ints)
+			})
+
+			t.Run("引数を変更しない", func(t *testing.T) {
+				t.Parallel()
+				prop.Check(t, func(s []int) bool {
+					original := append([]int{}, s...)
+					unique(s)
+					return reflect.DeepEqual(original, s)
+				}, ints)
+			})
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Package cutflag go-cutとgo-joinで共通のフラグをパースする
+//
+// 同じフラグのエラーメッセージがコマンドごとに変わらないように、パースはここにまとめる。
+// パースできない場合はflag.ExitOnErrorと同様に、フラグ名を付けたエラーを標準エラーに書き出して終了する。
+package cutflag
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/apbgo/go-study-group/cut"
+)
+
+// List -fや--left-fieldsで指定されたリストをパースする。空の場合はnilを返す
+func List(name, s string) cut.List {
+	if s == "" {
+		return nil
+	}
+	list, err := cut.ParseList(s)
+	exitIf(name, err)
+	return list
+}
+
+// RegexDelimiter --regex-delimiterで指定された正規表現をコンパイルする。空の場合はnilを返す
+func RegexDelimiter(s string) *regexp.Regexp {
+	if s == "" {
+		return nil
+	}
+	re, err := regexp.Compile(s)
+	exitIf("regex-delimiter", err)
+	return re
+}
+
+// RecordSeparator -z, --record-separatorで指定された行の区切りを返す
+// --record-separatorでは\nや\x00のようなエスケープが使える
+func RecordSeparator(zero bool, s string) string {
+	if zero {
+		if s != "" {
+			exit("-zと--record-separatorは同時に指定できません")
+		}
+		return "\x00"
+	}
+	if s == "" {
+		return ""
+	}
+	sep, err := cut.ParseRecordSeparator(s)
+	exitIf("record-separator", err)
+	return sep
+}
+
+// Size --memory-limitのように単位を付けて指定されたバイト数をパースする。空の場合は0を返す
+func Size(name, s string) int {
+	if s == "" {
+		return 0
+	}
+	n, err := cut.ParseSize(s)
+	exitIf(name, err)
+	return n
+}
+
+// exitIf errがあればフラグ名を付けて書き出し、終了する
+// 1文字のフラグは-f、それ以外は--memory-limitのように書く
+func exitIf(name string, err error) {
+	if err == nil {
+		return
+	}
+	if len(name) == 1 {
+		exit(fmt.Sprintf("-%s: %v", name, err))
+	}
+	exit(fmt.Sprintf("--%s: %v", name, err))
+}
+
+func exit(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+	os.Exit(1)
+}
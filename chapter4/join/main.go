@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/apbgo/go-study-group/chapter4/internal/cutflag"
+	"github.com/apbgo/go-study-group/cut"
+)
+
+var delimiter = flag.String("d", ",", "区切り文字を指定してください")
+var regexDelimiter = flag.String("regex-delimiter", "", "区切り文字を正規表現で指定してください (例: '\\s*[,;]\\s*')")
+var whitespace = flag.Bool("whitespace", false, "awkと同様に連続する空白とタブを1つの区切りとして扱います")
+var trim = flag.Bool("trim", false, "各フィールドの前後の空白を取り除きます")
+var header = flag.Bool("header", false, "1行目をカラム名のヘッダとして扱います")
+var csvMode = flag.Bool("csv", false, "入力をRFC 4180形式のCSVとして読み込みます")
+var lazyQuotes = flag.Bool("lazy-quotes", false, "--csvで不正なクォートを許容します")
+var quote = flag.String("quote", "minimal", "--csvで出力する際のクォート方法 (minimal, all, none)")
+var zeroTerminated = flag.Bool("z", false, "行の区切りを改行ではなくNULにします")
+var recordSeparator = flag.String("record-separator", "", "入力と出力の行の区切りを指定してください (例: '\\r\\n', ';')")
+var maxLineLength = flag.Int("max-line-length", 0, "1行の最大バイト数 (0は上限なし)")
+var outputDelimiter = flag.String("output-delimiter", "", "出力の区切り文字を指定してください (デフォルトは-dと同じ)")
+var format = flag.String("format", "text", "出力形式 (text, csv, tsv, json, ndjson, markdown)")
+var key = flag.String("k", "1", "結合するキーのフィールド番号かカラム名 (左右共通)")
+var leftKey = flag.String("left-key", "", "左のファイルのキー (デフォルトは-kと同じ)")
+var rightKey = flag.String("right-key", "", "右のファイルのキー (デフォルトは-kと同じ)")
+var leftFields = flag.String("left-fields", "", "左のファイルから出力するフィールド (例: 1,3 / 2-4 / 3-)")
+var rightFields = flag.String("right-fields", "", "右のファイルから出力するフィールド (例: 1,3 / 2-4 / 3-)")
+var joinType = flag.String("type", "inner", "結合の種類 (inner, left, full)")
+var method = flag.String("method", "auto", "結合の方法 (auto, hash, merge)")
+var memoryLimit = flag.String("memory-limit", "", "結合に使うメモリの目安。超える場合はソートマージ結合にします (例: 512K, 64M, 1G)")
+
+// go-joinコマンド
+// 2つのファイルをキーのフィールドで結合する
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "使い方: go-join [オプション] 左のファイル 右のファイル")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	quoteMode, err := cut.ParseQuoteMode(*quote)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	outputFormat, err := cut.ParseFormat(*format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	typ, err := cut.ParseJoinType(*joinType)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	joinMethod, err := cut.ParseJoinMethod(*method)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	opts := cut.JoinOptions{
+		Delimiter:       *delimiter,
+		RegexDelimiter:  cutflag.RegexDelimiter(*regexDelimiter),
+		Whitespace:      *whitespace,
+		Trim:            *trim,
+		CSV:             *csvMode,
+		LazyQuotes:      *lazyQuotes,
+		Header:          *header,
+		RecordSeparator: cutflag.RecordSeparator(*zeroTerminated, *recordSeparator),
+		MaxLineLength:   *maxLineLength,
+		OutputDelimiter: *outputDelimiter,
+		Format:          outputFormat,
+		Quote:           quoteMode,
+		LeftKey:         keyOr(*leftKey, *key),
+		RightKey:        keyOr(*rightKey, *key),
+		LeftFields:      cutflag.List("left-fields", *leftFields),
+		RightFields:     cutflag.List("right-fields", *rightFields),
+		Type:            typ,
+		Method:          joinMethod,
+		MemoryLimit:     cutflag.Size("memory-limit", *memoryLimit),
+	}
+
+	// どちらか一方のファイルは"-"で標準入力から読み込める
+	err = cut.JoinFiles(flag.Arg(0), flag.Arg(1), os.Stdin, os.Stdout, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// keyOr 左右それぞれのキーが指定されていなければ-kのキーを返す
+func keyOr(s, key string) string {
+	if s != "" {
+		return s
+	}
+	return key
+}
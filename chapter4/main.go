@@ -1,44 +1,208 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
+
+	"github.com/apbgo/go-study-group/chapter4/internal/cutflag"
+	"github.com/apbgo/go-study-group/cut"
 )
 
 var delimiter = flag.String("d", ",", "区切り文字を指定してください")
-var fields = flag.Int("f", 1, "フィールドの何番目を取り出すか指定してください")
+var fields = flag.String("f", "", "取り出すフィールドを指定してください (例: 1,3 / 2-4 / 3- / -2)")
+var names = flag.String("F", "", "--headerと一緒に取り出すカラム名を指定してください (例: name,count / 'created_*')")
+var header = flag.Bool("header", false, "1行目をカラム名のヘッダとして扱います")
+var bytesList = flag.String("b", "", "取り出すバイト位置を指定してください")
+var characters = flag.String("c", "", "取り出す文字位置を指定してください (UTF-8の文字単位)")
+var noSplit = flag.Bool("n", false, "-bでマルチバイト文字を分割しません")
+var complement = flag.Bool("complement", false, "指定したフィールド以外を取り出します")
+var regexDelimiter = flag.String("regex-delimiter", "", "区切り文字を正規表現で指定してください (例: '\\s*[,;]\\s*')")
+var whitespace = flag.Bool("whitespace", false, "awkと同様に連続する空白とタブを1つの区切りとして扱います")
+var trim = flag.Bool("trim", false, "各フィールドの前後の空白を取り除きます")
+var outputDelimiter = flag.String("output-delimiter", "", "出力の区切り文字を指定してください (デフォルトは-dと同じ)")
+var onlyDelimited = flag.Bool("s", false, "区切り文字を含まない行を出力しません")
+var csvMode = flag.Bool("csv", false, "入力をRFC 4180形式のCSVとして読み込みます")
+var lazyQuotes = flag.Bool("lazy-quotes", false, "--csvで不正なクォートを許容します")
+var quote = flag.String("quote", "minimal", "--csvで出力する際のクォート方法 (minimal, all, none)")
+var zeroTerminated = flag.Bool("z", false, "行の区切りを改行ではなくNULにします (find -print0の出力など)")
+var recordSeparator = flag.String("record-separator", "", "入力と出力の行の区切りを指定してください (例: '\\r\\n', ';')")
+var where = flag.String("where", "", "出力する行を絞り込む条件式 (例: '$4 == \"TRUE\" && trim($3) =~ /^Go/')")
+var maxLineLength = flag.Int("max-line-length", 0, "1行の最大バイト数 (0は上限なし)")
+var withFilename = flag.Bool("with-filename", false, "出力する各行の先頭にファイル名を付けます")
+var strict = flag.Bool("strict", false, "指定したフィールドがない行があればエラーにして処理を止めます")
+var lenient = flag.Bool("lenient", false, "指定したフィールドがない行は存在するフィールドだけを出力します (デフォルト)")
+var skip = flag.Bool("skip", false, "指定したフィールドがない行を出力せず、最後に読み飛ばした行を報告します")
+var groupBy = flag.Bool("group-by", false, "切り出したフィールドをキーにしてグループごとに集計します")
+var agg = flag.String("agg", "", "--group-byで求める集計 (例: count,sum(3),avg(price)。デフォルトはcount)")
+var memoryLimit = flag.String("memory-limit", "", "集計に使うメモリの目安。超える場合は一時ファイルに書き出します (例: 512K, 64M, 1G)")
+var describe = flag.Bool("describe", false, "カラムごとに型、空の値の数、異なる値の数、最小値と最大値、上位の値を集計します")
+var top = flag.Int("top", cut.DefaultTopN, "--describeで表示する上位の値の数")
+var follow = flag.Bool("follow", false, "tail -fと同様に、ファイルに追記された行を読み込み続けます (Ctrl+Cで終了)")
+var pollInterval = flag.Duration("poll-interval", cut.DefaultPollInterval, "--followで追記を確認する間隔")
+var jobs = flag.Int("j", 1, "並列に処理する数 (ファイルを指定した場合のみ有効)")
+var format = flag.String("format", "text", "出力形式 (text, csv, tsv, json, ndjson, markdown)")
 
 // go-cutコマンドを実装しよう
 func main() {
 	flag.Parse()
-	if flag.NArg() == 0 {
-		fmt.Fprintln(os.Stderr, "ファイルパスを指定してください。")
+
+	if *fields == "" && *names == "" && *bytesList == "" && *characters == "" {
+		// -b, -c, -f, -Fのいずれも指定されていない場合は1番目のフィールドを取り出す
+		// --describeの場合はすべてのフィールドを集計する
+		*fields = "1"
+		if *describe {
+			*fields = "1-"
+		}
+	}
+	quoteMode, err := cut.ParseQuoteMode(*quote)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
-	fp, err := os.Open(flag.Args()[0])
+	outputFormat, err := cut.ParseFormat(*format)
 	if err != nil {
-		// Openエラー処理
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
-	defer fp.Close()
+	opts := cut.Options{
+		Delimiter:        *delimiter,
+		RegexDelimiter:   cutflag.RegexDelimiter(*regexDelimiter),
+		Whitespace:       *whitespace,
+		Trim:             *trim,
+		OutputDelimiter:  *outputDelimiter,
+		Fields:           cutflag.List("f", *fields),
+		Names:            parseNames(*names),
+		Header:           *header,
+		Bytes:            cutflag.List("b", *bytesList),
+		Characters:       cutflag.List("c", *characters),
+		NoSplitMultibyte: *noSplit,
+		Complement:       *complement,
+		OnlyDelimited:    *onlyDelimited,
+		CSV:              *csvMode,
+		LazyQuotes:       *lazyQuotes,
+		Quote:            quoteMode,
+		Format:           outputFormat,
+		RecordSeparator:  cutflag.RecordSeparator(*zeroTerminated, *recordSeparator),
+		Where:            parseWhere(*where),
+		MaxLineLength:    *maxLineLength,
+		WithFilename:     *withFilename,
+		Jobs:             *jobs,
+		Missing:          parseMissingPolicy(*strict, *lenient, *skip),
+		GroupBy:          *groupBy,
+		Aggregations:     parseAggregations(*agg),
+		MemoryLimit:      cutflag.Size("memory-limit", *memoryLimit),
+		Describe:         *describe,
+		TopN:             *top,
+	}
+
+	if *follow {
+		if flag.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "--followではファイルを1つだけ指定してください")
+			os.Exit(1)
+		}
+		ctx, cancel := interruptContext()
+		err = cut.FollowFile(ctx, flag.Arg(0), os.Stdout, os.Stderr, opts, *pollInterval)
+		// os.Exitではdeferが呼ばれないので先に解放する
+		cancel()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	scanner := bufio.NewScanner(fp)
+	// ファイルを指定しない場合や"-"は標準入力から読み込む
+	err = cut.CutFiles(flag.Args(), os.Stdin, os.Stdout, os.Stderr, opts)
+	if err == cut.ErrInputFailed || errors.Is(err, cut.ErrMissingField) {
+		// 読み込めなかったファイルや--strictのエラーは出力済み
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
 
-	writer := bufio.NewWriter(os.Stdout)
-	for scanner.Scan() {
-		slice := strings.Split(scanner.Text(), *delimiter)
-		for i, str := range slice {
-			if i == (*fields - 1) {
-				str = strings.Trim(str, " ")
-				writer.WriteString(str)
-				writer.WriteRune('\n')
-				break
-			}
+// interruptContext SIGINTかSIGTERMを受け取るとキャンセルされるContextを返す
+// 最初のシグナルを受け取った後はシグナルの監視をやめるので、2回目のCtrl+Cではすぐに終了する
+// 使い終わったら返したcancelを呼び出してシグナルの監視をやめる
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sig:
+		case <-ctx.Done():
 		}
+		signal.Stop(sig)
+		cancel()
+	}()
+	return ctx, cancel
+}
+
+// parseNames -Fで指定されたカラム名をパースする
+func parseNames(s string) []string {
+	if s == "" {
+		return nil
+	}
+	names, err := cut.ParseNames(s)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-F: %v\n", err)
+		os.Exit(1)
+	}
+	return names
+}
+
+// parseAggregations --aggで指定された集計をパースする
+func parseAggregations(s string) []cut.Aggregation {
+	if s == "" {
+		return nil
+	}
+	aggs, err := cut.ParseAggregations(s)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "--agg: %v\n", err)
+		os.Exit(1)
+	}
+	return aggs
+}
+
+// parseMissingPolicy --strict, --lenient, --skipから指定したフィールドがない行の扱いを返す
+func parseMissingPolicy(strict, lenient, skip bool) cut.MissingPolicy {
+	n := 0
+	for _, b := range []bool{strict, lenient, skip} {
+		if b {
+			n++
+		}
+	}
+	if n > 1 {
+		fmt.Fprintln(os.Stderr, "--strict, --lenient, --skipは同時に指定できません")
+		os.Exit(1)
+	}
+	switch {
+	case strict:
+		return cut.MissingStrict
+	case skip:
+		return cut.MissingSkip
+	default:
+		return cut.MissingLenient
+	}
+}
+
+// parseWhere --whereで指定された条件式をパースする
+func parseWhere(s string) *cut.Where {
+	if s == "" {
+		return nil
+	}
+	where, err := cut.ParseWhere(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	writer.Flush()
+	return where
 }
@@ -1,18 +1,21 @@
 package chapter5
 
 import (
-	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
-	"strings"
+
+	// kadai_base.goのcut()と名前が衝突するため別名でimportする
+	gocut "github.com/apbgo/go-study-group/cut"
 )
 
 // kadai_baseのほうで定義されているからコッチではコメントアウトしておく
 // var delimiter = flag.String("d", ",", "区切り文字を指定してください")
 // var fields = flag.Int("f", 1, "フィールドの何番目を取り出すか指定してください")
+var withFilename = flag.Bool("with-filename", false, "出力する各行の先頭にファイル名を付けます")
 
 func Validation(argCount int, fieldNum int) error {
 
@@ -25,24 +28,14 @@ func Validation(argCount int, fieldNum int) error {
 	return nil
 }
 
+// Cut rから読み込んだ各行のfieldNum番目のフィールドをwに書き出す
+// 実際の処理はcutパッケージに任せる
 func Cut(r io.Reader, w io.Writer, delimiterStr string, fieldNum int) error {
-	scanner := bufio.NewScanner(r)
-	writer := bufio.NewWriter(w)
-	for scanner.Scan() {
-		text := scanner.Text()
-		sb := strings.Split(text, delimiterStr)
-		if len(sb) <= fieldNum-1 {
-			return fmt.Errorf("-fの値に該当するデータがありません")
-		}
-		s := sb[fieldNum-1]
-		fmt.Fprintln(writer, s)
-	}
-	writer.Flush()
-	if err := scanner.Err(); err != nil {
-		return err
-	}
-
-	return nil
+	return gocut.Cut(r, w, gocut.Options{
+		Delimiter: delimiterStr,
+		Fields:    gocut.List{{Low: fieldNum, High: fieldNum}},
+		Missing:   gocut.MissingStrict,
+	})
 }
 
 // go-cutコマンドを実装しよう
@@ -53,13 +46,26 @@ func GoCut() {
 		log.Fatal(err)
 	}
 
-	file, err := os.Open(flag.Args()[0])
+	// 複数のファイル、標準入力("-")、gzip/bzip2に対応する
+	// 読み込めないファイルがあっても残りを処理し、GNU cutと同様に終了コード1で終わる
+	err := CutFiles(flag.Args(), os.Stdin, os.Stdout, os.Stderr, *delimiter, *fields, *withFilename)
+	if err == gocut.ErrInputFailed || errors.Is(err, gocut.ErrMissingField) {
+		// エラーはerrWに出力済み
+		os.Exit(1)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer file.Close()
+}
 
-	if err := Cut(file, os.Stdout, *delimiter, *fields); err != nil {
-		log.Fatal(err)
-	}
+// CutFiles namesの各ファイルのfieldNum番目のフィールドをwに書き出す
+// 読み込めなかったファイルのエラーはerrWに書き出し、gocut.ErrInputFailedを返す
+// フィールドが足りない行があった場合はerrWに書き出し、残りのファイルを処理せずにgocut.FieldCountErrorを返す
+func CutFiles(names []string, stdin io.Reader, w, errW io.Writer, delimiterStr string, fieldNum int, withFilename bool) error {
+	return gocut.CutFiles(names, stdin, w, errW, gocut.Options{
+		Delimiter:    delimiterStr,
+		Fields:       gocut.List{{Low: fieldNum, High: fieldNum}},
+		WithFilename: withFilename,
+		Missing:      gocut.MissingStrict,
+	})
 }
@@ -0,0 +1,100 @@
+//go:build go1.18
+// +build go1.18
+
+package chapter5
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzCut go test ./chapter5 -run XXX -fuzz '^FuzzCut$' で実行する
+// -fuzz FuzzCutだとFuzzCutFilesにも一致し、go testが実行を拒否する
+// 通常のgo testではf.Addで加えた値だけを試す
+func FuzzCut(f *testing.F) {
+	f.Add("foo,hogehoge,aaaaa\nfoo2,aabbcc,bbbbb", ",", 2)
+	f.Add("a\tb\r\nc\td\r\n", "\t", 1)
+	f.Add("\n\n,\n,,,\n", ",", 3)
+	f.Add("日本語:テスト\nno delimiter", ":", 1)
+	f.Add("a<>b<>c", "<>", 3)
+
+	f.Fuzz(func(t *testing.T, input, delimiter string, fieldNum int) {
+		if !utf8.ValidString(input) || !validDelimiter(delimiter) || Validation(1, fieldNum) != nil {
+			t.Skip()
+		}
+		stdout := new(bytes.Buffer)
+		if err := Cut(strings.NewReader(input), stdout, delimiter, fieldNum); err != nil {
+			// フィールドが足りない行があればエラーになるが、パニックしなければよい
+			return
+		}
+		checkCutOutput(t, input, delimiter, stdout.String())
+	})
+}
+
+// FuzzCutFiles 標準入力から読み込んだ場合もCutと同じ出力になることを確認する
+// go test ./chapter5 -run XXX -fuzz '^FuzzCutFiles$' で実行する
+func FuzzCutFiles(f *testing.F) {
+	f.Add("foo,hogehoge,aaaaa\nfoo2,aabbcc,bbbbb", ",", 2)
+	f.Add("a,b\n\nc", ",", 1)
+
+	f.Fuzz(func(t *testing.T, input, delimiter string, fieldNum int) {
+		if !utf8.ValidString(input) || !validDelimiter(delimiter) || Validation(1, fieldNum) != nil {
+			t.Skip()
+		}
+		want := new(bytes.Buffer)
+		wantErr := Cut(strings.NewReader(input), want, delimiter, fieldNum)
+
+		stdout := new(bytes.Buffer)
+		err := CutFiles([]string{"-"}, strings.NewReader(input), stdout, new(bytes.Buffer), delimiter, fieldNum, false)
+		if (err != nil) != (wantErr != nil) {
+			t.Fatalf("Cut: %v, CutFiles: %v", wantErr, err)
+		}
+		if err == nil && stdout.String() != want.String() {
+			t.Fatalf("CutFilesの出力がCutと異なります: %q, %q", stdout.String(), want.String())
+		}
+	})
+}
+
+// validDelimiter -dに指定できる区切り文字か
+// 改行を含む区切り文字は行をまたがないので対象外にする
+func validDelimiter(delimiter string) bool {
+	return delimiter != "" && utf8.ValidString(delimiter) && !strings.ContainsAny(delimiter, "\r\n")
+}
+
+// checkCutOutput 正しいUTF-8で、入力と同じ行数があり、各行が入力の行の一部であることを確認する
+func checkCutOutput(t *testing.T, input, delimiter, output string) {
+	t.Helper()
+	if !utf8.ValidString(output) {
+		t.Fatalf("出力が正しいUTF-8ではありません: %q", output)
+	}
+	inLines := splitLines(input)
+	outLines := splitLines(output)
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		t.Fatalf("出力が改行で終わっていません: %q", output)
+	}
+	if len(outLines) != len(inLines) {
+		t.Fatalf("出力の行数が入力と異なります: 入力 %d行, 出力 %d行 (%q)", len(inLines), len(outLines), output)
+	}
+	for i, line := range outLines {
+		if !strings.Contains(inLines[i], line) {
+			t.Fatalf("%d行目: 入力の行に含まれない値を出力しました: %q, %q", i+1, inLines[i], line)
+		}
+		if strings.Contains(line, delimiter) {
+			t.Fatalf("%d行目: 1つのフィールドに区切り文字が含まれています: %q", i+1, line)
+		}
+	}
+}
+
+// splitLines 改行で行に分ける。行末の\rは取り除く
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
@@ -0,0 +1,51 @@
+package chapter5
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	gocut "github.com/apbgo/go-study-group/cut"
+	"github.com/apbgo/go-study-group/tools/prop"
+)
+
+func TestCut_Property(t *testing.T) {
+	for _, delimiter := range []string{",", "\t", "<>"} {
+		delimiter := delimiter
+		t.Run(fmt.Sprintf("%q", delimiter), func(t *testing.T) {
+			t.Parallel()
+			lines := prop.SliceOf(prop.CSVLine(delimiter, 1, 5), 0, 10)
+			prop.Check(t, func(lines []string, fieldNum int) error {
+				var input strings.Builder
+				for _, line := range lines {
+					input.WriteString(line + "\n")
+				}
+				stdout := new(bytes.Buffer)
+				err := Cut(strings.NewReader(input.String()), stdout, delimiter, fieldNum)
+
+				// フィールドが足りない最初の行まではfieldNum番目のフィールドを1行ずつ出力する
+				var want strings.Builder
+				for i, line := range lines {
+					fields := strings.Split(line, delimiter)
+					if len(fields) < fieldNum {
+						var fce *gocut.FieldCountError
+						if !errors.As(err, &fce) || fce.Line != i+1 || fce.Fields != len(fields) || fce.Want != fieldNum {
+							return fmt.Errorf("%d行目のフィールド数のエラーになるはずです: %v", i+1, err)
+						}
+						break
+					}
+					want.WriteString(fields[fieldNum-1] + "\n")
+				}
+				if err == nil && strings.Count(stdout.String(), "\n") != len(lines) {
+					return fmt.Errorf("入力と出力の行数が違います: %d, %d", len(lines), strings.Count(stdout.String(), "\n"))
+				}
+				if stdout.String() != want.String() {
+					return fmt.Errorf("出力が違います: %q, %q", stdout.String(), want.String())
+				}
+				return nil
+			}, lines, prop.Int(1, 5))
+		})
+	}
+}
@@ -2,8 +2,10 @@ package chapter5
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 
+	gocut "github.com/apbgo/go-study-group/cut"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,7 +33,7 @@ func TestCut(t *testing.T) {
 		t.Parallel()
 		stdin := bytes.NewBufferString("foo,hogehoge,aaaaa\nfoo2,aabbcc,bbbbb")
 		stdout := new(bytes.Buffer)
-		err := Cut(stdin, stdout, ",", 1)
+		err := Cut(stdin, stdout, ",", 2)
 		assert.NoError(t, err)
 		expected := "hogehoge\naabbcc\n"
 		assert.Equal(t, expected, string(stdout.Bytes()))
@@ -42,7 +44,30 @@ func TestCut(t *testing.T) {
 		stdin := bytes.NewBufferString("foo,hogehoge,aaaaa\nfoo2,aabbcc,bbbbb")
 		stdout := new(bytes.Buffer)
 		err := Cut(stdin, stdout, ",", 4)
-		assert.EqualError(t, err, "-fの値に該当するデータがありません")
+		assert.EqualError(t, err, "1行目: -fの値に該当するデータがありません (フィールド数: 3, 必要なフィールド数: 4)")
+		assert.True(t, errors.Is(err, gocut.ErrMissingField))
+	})
+}
+
+func TestCutFiles(t *testing.T) {
+	t.Run("正常系", func(t *testing.T) {
+		t.Parallel()
+		stdin := bytes.NewBufferString("foo,hogehoge,aaaaa\nfoo2,aabbcc,bbbbb")
+		stdout := new(bytes.Buffer)
+		err := CutFiles([]string{"-"}, stdin, stdout, new(bytes.Buffer), ",", 2, true)
+		assert.NoError(t, err)
+		assert.Equal(t, "(standard input):hogehoge\n(standard input):aabbcc\n", stdout.String())
+	})
+
+	t.Run("異常系", func(t *testing.T) {
+		t.Parallel()
+		stdin := bytes.NewBufferString("foo,hogehoge,aaaaa")
+		stdout := new(bytes.Buffer)
+		stderr := new(bytes.Buffer)
+		err := CutFiles([]string{"not_found.csv", "-"}, stdin, stdout, stderr, ",", 2, false)
+		assert.EqualError(t, err, "処理できなかった入力があります")
+		assert.Equal(t, "hogehoge\n", stdout.String())
+		assert.Equal(t, "not_found.csv: no such file or directory\n", stderr.String())
 	})
 }
 
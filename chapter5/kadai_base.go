@@ -22,7 +22,7 @@ func cut() {
 		fmt.Fprintln(os.Stderr, "ファイルパスを指定してください。")
 		os.Exit(1)
 	}
-	if *fields < 0 {
+	if *fields < 1 {
 		fmt.Fprintln(os.Stderr, "-f は1以上である必要があります")
 		os.Exit(1)
 	}
@@ -40,11 +40,13 @@ func cut() {
 	// 関数の引数で読み出すio.Readerと、
 	// 書き出すio.Writer (本関数からはos.Stdout, テストからはbyte.Bufferなどへ)を指定できるようにすると良い
 	scanner := bufio.NewScanner(file)
+	lineNo := 0
 	for scanner.Scan() {
+		lineNo++
 		text := scanner.Text()
 		sb := strings.Split(text, *delimiter)
-		if len(sb) < *fields-1 {
-			fmt.Fprintln(os.Stderr, "-fの値に該当するデータがありません")
+		if len(sb) < *fields {
+			fmt.Fprintf(os.Stderr, "%s: %d行目: -fの値に該当するデータがありません (フィールド数: %d, 必要なフィールド数: %d)\n", flag.Args()[0], lineNo, len(sb), *fields)
 			os.Exit(1)
 		}
 		s := sb[*fields-1]
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/apbgo/go-study-group/clock"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -43,8 +44,14 @@ func TestSum(t *testing.T) {
 
 func TestClock2_AddHour(t *testing.T) {
 	now := time.Date(2019, 7, 04, 18, 30, 00, 0, time.Local)
-	clock := Clock2{
-		Now: func() time.Time { return now },
-	}
-	assert.Equal(t, now.Add(time.Hour*3), clock.AddHour(3))
+	// clock.FakeのNowを渡すと、Advanceで時間を進められる
+	fake := clock.NewFake(now)
+	c := Clock2{Now: fake.Now}
+	assert.Equal(t, now.Add(time.Hour*3), c.AddHour(3))
+
+	fake.Advance(time.Minute * 30)
+	assert.Equal(t, now.Add(time.Hour*3+time.Minute*30), c.AddHour(3))
+
+	// 本番ではclock.New()のNowを渡す
+	assert.WithinDuration(t, time.Now().Add(time.Hour), Clock2{Now: clock.New().Now}.AddHour(1), time.Minute)
 }
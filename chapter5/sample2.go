@@ -1,5 +1,7 @@
 package chapter5
 
+import "context"
+
 type UserData struct {
 	Id       int
 	UserName string
@@ -28,11 +30,16 @@ type IFDBService interface {
 	Get(id int) UserData
 }
 
+// User2 IFDBServiceV2を使うので、見つからない場合と名前が空の場合を区別できる
+// 古いIFDBServiceはDBServiceV2で包んで渡す
 type User2 struct {
-	dbService IFDBService
+	dbService IFDBServiceV2
 }
 
-func (u *User2) UserName(id int) string {
-	user := u.dbService.Get(id)
-	return user.UserName
+func (u *User2) UserName(ctx context.Context, id int) (string, error) {
+	user, err := u.dbService.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return user.UserName, nil
 }
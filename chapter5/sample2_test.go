@@ -1,16 +1,37 @@
 package chapter5
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestUser2_UserName(t *testing.T) {
-	user := User2{
-		dbService: TestService{},
-	}
-	assert.Equal(t, "UserA", user.UserName(1))
+	t.Run("正常系", func(t *testing.T) {
+		t.Parallel()
+		user := User2{
+			// 古いIFDBServiceはDBServiceV2で包んで渡す
+			dbService: DBServiceV2{Service: TestService{}},
+		}
+		name, err := user.UserName(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "UserA", name)
+	})
+
+	t.Run("見つからない場合と名前が空の場合を区別できる", func(t *testing.T) {
+		t.Parallel()
+		user := User2{
+			dbService: NewMemoryDBService(UserData{Id: 1, UserName: ""}),
+		}
+		name, err := user.UserName(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "", name)
+
+		_, err = user.UserName(context.Background(), 2)
+		assert.True(t, errors.Is(err, ErrNotFound))
+	})
 }
 
 // 差し替える用のstruct
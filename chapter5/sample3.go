@@ -1,15 +1,23 @@
 package chapter5
 
+import (
+	"context"
+	"fmt"
+)
+
 //go:generate mockgen -package chapter5 -destination sample3_mock.go -self_package=github.com/apbgo/go-study-group/chapter5 github.com/apbgo/go-study-group/chapter5 IFDBService2
 type IFDBService2 interface {
 	Get(id int) UserData
 }
 
 type User3 struct {
-	dbService IFDBService
+	dbService IFDBServiceV2
 }
 
-func (u *User3) UserName(id int) string {
-	user := u.dbService.Get(id)
-	return user.UserName
+func (u *User3) UserName(ctx context.Context, id int) (string, error) {
+	user, err := u.dbService.Get(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("ユーザー名を取得できません: %w", err)
+	}
+	return user.UserName, nil
 }
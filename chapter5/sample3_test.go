@@ -1,6 +1,9 @@
 package chapter5
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -9,17 +12,32 @@ import (
 
 func TestUser3_UserName(t *testing.T) {
 	ctrl := gomock.NewController(t)
-	mockService := NewMockIFDBService2(ctrl)
+	mockService := NewMockIFDBServiceV2(ctrl)
 
 	expect := "User1"
-	mockService.EXPECT().Get(1).Return(UserData{
+	mockService.EXPECT().Get(gomock.Any(), 1).Return(UserData{
 		Id:       1,
 		UserName: expect,
-	})
+	}, nil)
+	user := User3{
+		dbService: mockService,
+	}
+	name, err := user.UserName(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, expect, name)
+}
+
+func TestUser3_UserName_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := NewMockIFDBServiceV2(ctrl)
+
+	mockService.EXPECT().Get(gomock.Any(), 2).Return(UserData{}, fmt.Errorf("id=2: %w", ErrNotFound))
 	user := User3{
 		dbService: mockService,
 	}
-	assert.Equal(t, expect, user.UserName(1))
+	_, err := user.UserName(context.Background(), 2)
+	assert.EqualError(t, err, "ユーザー名を取得できません: id=2: ユーザーが見つかりません")
+	assert.True(t, errors.Is(err, ErrNotFound))
 }
 
 func TestUser3_Mock(t *testing.T) {
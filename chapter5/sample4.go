@@ -1,5 +1,6 @@
 package chapter5
 
+//go:generate go run github.com/apbgo/go-study-group/tools/decorgen/cmd/decorgen -type IFCalcService
 type IFCalcService interface {
 	XXX(x int) int
 	YYY(x, y int) int
@@ -0,0 +1,40 @@
+// Code generated by decorgen. DO NOT EDIT.
+
+package chapter5
+
+import (
+	"github.com/apbgo/go-study-group/tools/decorgen/decor"
+)
+
+// IFCalcServiceDecorator IFCalcServiceの各メソッドの前後でdecor.Hooksを呼び出すデコレータ
+type IFCalcServiceDecorator struct {
+	next  IFCalcService
+	hooks decor.Hooks
+}
+
+// NewIFCalcServiceDecorator nextをhooksで包んだIFCalcServiceDecoratorを作る
+func NewIFCalcServiceDecorator(next IFCalcService, hooks decor.Hooks) *IFCalcServiceDecorator {
+	return &IFCalcServiceDecorator{next: next, hooks: hooks}
+}
+
+// XXX IFCalcService.XXXの前後でフックを呼び出す
+func (d *IFCalcServiceDecorator) XXX(x int) (r0 int) {
+	call := &decor.Call{Interface: "IFCalcService", Method: "XXX", Args: []interface{}{x}, Out: []interface{}{&r0}}
+	d.hooks.Invoke(call, func() error {
+		r0 = d.next.XXX(x)
+		call.Results = []interface{}{r0}
+		return nil
+	})
+	return r0
+}
+
+// YYY IFCalcService.YYYの前後でフックを呼び出す
+func (d *IFCalcServiceDecorator) YYY(x int, y int) (r0 int) {
+	call := &decor.Call{Interface: "IFCalcService", Method: "YYY", Args: []interface{}{x, y}, Out: []interface{}{&r0}}
+	d.hooks.Invoke(call, func() error {
+		r0 = d.next.YYY(x, y)
+		call.Results = []interface{}{r0}
+		return nil
+	})
+	return r0
+}
@@ -3,6 +3,7 @@ package chapter5
 import (
 	"testing"
 
+	"github.com/apbgo/go-study-group/tools/decorgen/decor"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
@@ -21,3 +22,28 @@ func TestSample4_Method(t *testing.T) {
 	}
 	assert.Equal(t, 13, calc.Method(1, 4, 2))
 }
+
+func TestIFCalcServiceDecorator(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := NewMockIFCalcService(ctrl)
+	mockService.EXPECT().XXX(1).Return(4)
+	mockService.EXPECT().YYY(4, 2).Return(6)
+
+	// decorgenで生成したデコレータで、呼び出しを記録する
+	var calls []decor.Call
+	metrics := decor.NewMetrics()
+	service := NewIFCalcServiceDecorator(mockService, decor.Chain(
+		decor.Hooks{After: func(call *decor.Call) { calls = append(calls, *call) }},
+		metrics.Hooks(),
+	))
+	assert.Equal(t, 6, service.YYY(service.XXX(1), 2))
+
+	if assert.Len(t, calls, 2) {
+		assert.Equal(t, "IFCalcService.XXX", calls[0].Name())
+		assert.Equal(t, []interface{}{1}, calls[0].Args)
+		assert.Equal(t, []interface{}{4}, calls[0].Results)
+		assert.Equal(t, []interface{}{4, 2}, calls[1].Args)
+		assert.Equal(t, []interface{}{6}, calls[1].Results)
+	}
+	assert.Len(t, metrics.Stats(), 2)
+}
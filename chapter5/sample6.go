@@ -0,0 +1,108 @@
+package chapter5
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNotFound 指定したidのユーザーが存在しない
+// 名前が空のユーザーと区別できるように、IFDBServiceV2はUserDataと一緒にエラーを返す
+var ErrNotFound = errors.New("ユーザーが見つかりません")
+
+// IFDBServiceV2 contextとエラーを扱えるようにしたIFDBService
+// 見つからない場合はerrors.Is(err, ErrNotFound)がtrueになるエラーを返す
+//
+//go:generate mockgen -package chapter5 -destination sample6_mock.go -self_package=github.com/apbgo/go-study-group/chapter5 github.com/apbgo/go-study-group/chapter5 IFDBServiceV2
+//go:generate go run github.com/apbgo/go-study-group/tools/decorgen/cmd/decorgen -type IFDBServiceV2
+type IFDBServiceV2 interface {
+	Get(ctx context.Context, id int) (UserData, error)
+}
+
+// MemoryDBService メモリ上のユーザーを返すIFDBServiceV2 (テストや開発用)
+type MemoryDBService struct {
+	mu    sync.RWMutex
+	users map[int]UserData
+}
+
+// NewMemoryDBService usersを登録したMemoryDBServiceを作る
+func NewMemoryDBService(users ...UserData) *MemoryDBService {
+	s := &MemoryDBService{users: make(map[int]UserData, len(users))}
+	for _, user := range users {
+		s.users[user.Id] = user
+	}
+	return s
+}
+
+// Put ユーザーを登録する。同じIdのユーザーは置き換える
+func (s *MemoryDBService) Put(user UserData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[user.Id] = user
+}
+
+// Get idのユーザーを返す
+func (s *MemoryDBService) Get(ctx context.Context, id int) (UserData, error) {
+	if err := ctx.Err(); err != nil {
+		return UserData{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, ok := s.users[id]
+	if !ok {
+		return UserData{}, fmt.Errorf("id=%d: %w", id, ErrNotFound)
+	}
+	return user, nil
+}
+
+// SQLDBService chapter6のi_userテーブルからユーザーを取得するIFDBServiceV2
+type SQLDBService struct {
+	db *sql.DB
+}
+
+// NewSQLDBService dbを使うSQLDBServiceを作る
+func NewSQLDBService(db *sql.DB) *SQLDBService {
+	return &SQLDBService{db: db}
+}
+
+// Get i_userからuser_idがidのユーザーを取得する。削除済みのユーザーは見つからない扱いにする
+// nameがNULLの場合はUserNameを空にする
+func (s *SQLDBService) Get(ctx context.Context, id int) (UserData, error) {
+	var name sql.NullString
+	err := s.db.QueryRowContext(ctx, "SELECT name FROM i_user WHERE user_id = ? AND deleted_at IS NULL", id).Scan(&name)
+	if err == sql.ErrNoRows {
+		return UserData{}, fmt.Errorf("id=%d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return UserData{}, fmt.Errorf("i_userの取得に失敗しました: %w", err)
+	}
+	return UserData{Id: id, UserName: name.String}, nil
+}
+
+// LegacyDBService IFDBServiceV2を古いIFDBServiceとして使うためのアダプタ
+// エラーの場合は以前と同じく空のUserDataを返す
+type LegacyDBService struct {
+	Service IFDBServiceV2
+}
+
+// Get context.Background()でServiceから取得する
+func (s LegacyDBService) Get(id int) UserData {
+	user, _ := s.Service.Get(context.Background(), id)
+	return user
+}
+
+// DBServiceV2 古いIFDBServiceをIFDBServiceV2として使うためのアダプタ
+// 古いIFDBServiceは見つからないことを表せないので、エラーはctxのエラーだけになる
+type DBServiceV2 struct {
+	Service IFDBService
+}
+
+// Get ctxがキャンセルされていなければServiceから取得する
+func (s DBServiceV2) Get(ctx context.Context, id int) (UserData, error) {
+	if err := ctx.Err(); err != nil {
+		return UserData{}, err
+	}
+	return s.Service.Get(id), nil
+}
@@ -0,0 +1,31 @@
+// Code generated by decorgen. DO NOT EDIT.
+
+package chapter5
+
+import (
+	"context"
+
+	"github.com/apbgo/go-study-group/tools/decorgen/decor"
+)
+
+// IFDBServiceV2Decorator IFDBServiceV2の各メソッドの前後でdecor.Hooksを呼び出すデコレータ
+type IFDBServiceV2Decorator struct {
+	next  IFDBServiceV2
+	hooks decor.Hooks
+}
+
+// NewIFDBServiceV2Decorator nextをhooksで包んだIFDBServiceV2Decoratorを作る
+func NewIFDBServiceV2Decorator(next IFDBServiceV2, hooks decor.Hooks) *IFDBServiceV2Decorator {
+	return &IFDBServiceV2Decorator{next: next, hooks: hooks}
+}
+
+// Get IFDBServiceV2.Getの前後でフックを呼び出す
+func (d *IFDBServiceV2Decorator) Get(ctx context.Context, id int) (r0 UserData, r1 error) {
+	call := &decor.Call{Context: ctx, Interface: "IFDBServiceV2", Method: "Get", Args: []interface{}{ctx, id}, Out: []interface{}{&r0}}
+	d.hooks.Invoke(call, func() error {
+		r0, r1 = d.next.Get(ctx, id)
+		call.Results = []interface{}{r0}
+		return r1
+	})
+	return r0, call.Err
+}
@@ -0,0 +1,110 @@
+package chapter5
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/apbgo/go-study-group/tools/cassette"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryDBService_Get(t *testing.T) {
+	service := NewMemoryDBService(UserData{Id: 1, UserName: "UserA"})
+	service.Put(UserData{Id: 2, UserName: "UserB"})
+
+	t.Run("正常系", func(t *testing.T) {
+		t.Parallel()
+		user, err := service.Get(context.Background(), 2)
+		assert.NoError(t, err)
+		assert.Equal(t, UserData{Id: 2, UserName: "UserB"}, user)
+	})
+
+	t.Run("見つからない", func(t *testing.T) {
+		t.Parallel()
+		_, err := service.Get(context.Background(), 3)
+		assert.EqualError(t, err, "id=3: ユーザーが見つかりません")
+		assert.True(t, errors.Is(err, ErrNotFound))
+	})
+
+	t.Run("キャンセル済み", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := service.Get(ctx, 1)
+		assert.Equal(t, context.Canceled, err)
+	})
+}
+
+func TestSQLDBService_Get(t *testing.T) {
+	// chapter6のmigraiton.sqlを流したMySQLが必要。起動していなければスキップする
+	db, err := sql.Open("mysql", "root:@tcp(127.0.0.1:5446)/chapter6?parseTime=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Skipf("MySQLに接続できません: %v", err)
+	}
+	service := NewSQLDBService(db)
+
+	user, err := service.Get(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, UserData{Id: 1, UserName: "★キリト★"}, user)
+
+	_, err = service.Get(context.Background(), 0)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = service.Get(ctx, 1)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.False(t, errors.Is(err, ErrNotFound))
+}
+
+func TestLegacyDBService_Get(t *testing.T) {
+	// 古いIFDBServiceを受け取るコードにも渡せる
+	var service IFDBService = LegacyDBService{Service: NewMemoryDBService(UserData{Id: 1, UserName: "UserA"})}
+	assert.Equal(t, UserData{Id: 1, UserName: "UserA"}, service.Get(1))
+	assert.Equal(t, UserData{}, service.Get(2))
+}
+
+func TestDBServiceV2_Get(t *testing.T) {
+	var service IFDBServiceV2 = DBServiceV2{Service: TestService{}}
+	user, err := service.Get(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "UserA", user.UserName)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = service.Get(ctx, 1)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestUser3_UserName_Cassette(t *testing.T) {
+	// カセットがなければMemoryDBServiceの呼び出しを記録し、あれば包んだ実装を呼び出さずに再生する
+	// 記録し直す場合はtestdata/user3.jsonを削除してから実行する
+	c, err := cassette.New(filepath.Join("testdata", "user3.json"), cassette.Auto)
+	if !assert.NoError(t, err) {
+		return
+	}
+	c.RegisterError(ErrNotFound)
+	var backend IFDBServiceV2
+	if c.Mode() == cassette.Record {
+		backend = NewMemoryDBService(UserData{Id: 1, UserName: "UserA"})
+	}
+	user := User3{dbService: NewIFDBServiceV2Decorator(backend, c.Hooks())}
+
+	ctx := context.Background()
+	name, err := user.UserName(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "UserA", name)
+
+	_, err = user.UserName(ctx, 2)
+	assert.EqualError(t, err, "ユーザー名を取得できません: id=2: ユーザーが見つかりません")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.NoError(t, c.Close())
+}
@@ -0,0 +1,198 @@
+package chapter5
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apbgo/go-study-group/clock"
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultCacheSize CachedDBServiceに保持するユーザー数のデフォルト
+const DefaultCacheSize = 1024
+
+// DefaultCacheTTL CachedDBServiceにユーザーを保持する時間のデフォルト
+const DefaultCacheTTL = time.Minute
+
+// CacheOptions CachedDBServiceの設定
+type CacheOptions struct {
+	// Size 保持するユーザー数の上限。超えた場合は最も長く使われていないものから捨てる。0の場合はDefaultCacheSize
+	Size int
+	// TTL 取得したユーザーを保持する時間。0の場合はDefaultCacheTTL
+	TTL time.Duration
+	// NegativeTTL 見つからなかったことを保持する時間。0の場合はTTLと同じ、負の場合は保持しない
+	NegativeTTL time.Duration
+	// Clock 有効期限の判定に使う時計。nilの場合はclock.New()
+	Clock clock.Clock
+}
+
+// CacheStats CachedDBServiceのヒット数とミス数
+type CacheStats struct {
+	// Hits キャッシュから返した回数 (見つからなかったことを返した場合も含む)
+	Hits int64
+	// Misses キャッシュになく、裏のサービスの結果を待った回数
+	Misses int64
+}
+
+// CachedDBService 裏のIFDBServiceV2の結果をキャッシュするデコレータ
+// 同じidを同時に取得した場合は、裏のサービスを1回だけ呼び出して結果を共有する
+// ErrNotFound以外のエラーはキャッシュしない。古いIFDBServiceとして使う場合はLegacyDBServiceで包む
+type CachedDBService struct {
+	// hits, misses 32bit環境でもatomicで扱えるように先頭に置く
+	hits   int64
+	misses int64
+
+	backend IFDBServiceV2
+	opts    CacheOptions
+	group   singleflight.Group
+
+	mu      sync.Mutex
+	entries map[int]*list.Element
+	// lru 先頭ほど最近使われたcacheEntry
+	lru *list.List
+}
+
+type cacheEntry struct {
+	id      int
+	user    UserData
+	err     error
+	expires time.Time
+}
+
+// NewCachedDBService backendの結果をキャッシュするCachedDBServiceを作る
+func NewCachedDBService(backend IFDBServiceV2, opts CacheOptions) *CachedDBService {
+	if opts.Size <= 0 {
+		opts.Size = DefaultCacheSize
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultCacheTTL
+	}
+	if opts.NegativeTTL == 0 {
+		opts.NegativeTTL = opts.TTL
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.New()
+	}
+	return &CachedDBService{
+		backend: backend,
+		opts:    opts,
+		entries: make(map[int]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Get キャッシュにあればそれを返し、なければ裏のサービスから取得してキャッシュする
+// 同じidを同時に取得した場合は裏のサービスを1回だけ呼び出し、それぞれのctxがキャンセルされるまで結果を待つ
+// 裏のサービスはキャンセルされないctxで呼び出すので、最初の呼び出しがキャンセルされても他の呼び出しには影響しない
+func (s *CachedDBService) Get(ctx context.Context, id int) (UserData, error) {
+	if entry, ok := s.lookup(id); ok {
+		atomic.AddInt64(&s.hits, 1)
+		return entry.user, entry.err
+	}
+	atomic.AddInt64(&s.misses, 1)
+
+	backendCtx := detachedContext{ctx}
+	ch := s.group.DoChan(strconv.Itoa(id), func() (interface{}, error) {
+		// 待っている間に別の呼び出しがキャッシュしていれば、それを使う
+		if entry, ok := s.lookup(id); ok {
+			return entry.user, entry.err
+		}
+		user, err := s.backend.Get(backendCtx, id)
+		switch {
+		case err == nil:
+			s.store(&cacheEntry{id: id, user: user}, s.opts.TTL)
+		case errors.Is(err, ErrNotFound) && s.opts.NegativeTTL > 0:
+			s.store(&cacheEntry{id: id, err: err}, s.opts.NegativeTTL)
+		}
+		return user, err
+	})
+	select {
+	case <-ctx.Done():
+		return UserData{}, ctx.Err()
+	case res := <-ch:
+		user, _ := res.Val.(UserData)
+		return user, res.Err
+	}
+}
+
+// detachedContext 親のctxの値は引き継ぎ、キャンセルと期限は引き継がないcontext.Context
+// 複数の呼び出しで共有する処理を、最初の呼び出しのキャンセルで止めないために使う
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// Delete idのキャッシュを捨てる。ユーザーを更新した場合に呼び出す
+func (s *CachedDBService) Delete(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[id]; ok {
+		s.remove(e)
+	}
+}
+
+// Stats これまでのヒット数とミス数を返す
+func (s *CachedDBService) Stats() CacheStats {
+	return CacheStats{
+		Hits:   atomic.LoadInt64(&s.hits),
+		Misses: atomic.LoadInt64(&s.misses),
+	}
+}
+
+// Len キャッシュしているユーザー数を返す (期限切れのものも含む)
+func (s *CachedDBService) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+// lookup 期限内のキャッシュがあれば返す。期限切れのものは捨てる
+func (s *CachedDBService) lookup(id int) (*cacheEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*cacheEntry)
+	if !s.opts.Clock.Now().Before(entry.expires) {
+		s.remove(e)
+		return nil, false
+	}
+	s.lru.MoveToFront(e)
+	return entry, true
+}
+
+// store entryをttlの間キャッシュする。上限を超えた場合は最も長く使われていないものを捨てる
+func (s *CachedDBService) store(entry *cacheEntry, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry.expires = s.opts.Clock.Now().Add(ttl)
+	if e, ok := s.entries[entry.id]; ok {
+		e.Value = entry
+		s.lru.MoveToFront(e)
+		return
+	}
+	s.entries[entry.id] = s.lru.PushFront(entry)
+	for s.lru.Len() > s.opts.Size {
+		s.remove(s.lru.Back())
+	}
+}
+
+// remove eをキャッシュから外す。s.muをロックして呼び出す
+func (s *CachedDBService) remove(e *list.Element) {
+	s.lru.Remove(e)
+	delete(s.entries, e.Value.(*cacheEntry).id)
+}
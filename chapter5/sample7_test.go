@@ -0,0 +1,242 @@
+package chapter5
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apbgo/go-study-group/clock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedDBService_Get(t *testing.T) {
+	ctx := context.Background()
+	userA := UserData{Id: 1, UserName: "UserA"}
+
+	t.Run("2回目からはキャッシュを返す", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		backend := NewMockIFDBServiceV2(ctrl)
+		backend.EXPECT().Get(gomock.Any(), 1).Return(userA, nil).Times(1)
+
+		service := NewCachedDBService(backend, CacheOptions{})
+		for i := 0; i < 3; i++ {
+			user, err := service.Get(ctx, 1)
+			assert.NoError(t, err)
+			assert.Equal(t, userA, user)
+		}
+		assert.Equal(t, CacheStats{Hits: 2, Misses: 1}, service.Stats())
+
+		// User2に渡して使える
+		user2 := User2{dbService: service}
+		name, err := user2.UserName(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "UserA", name)
+	})
+
+	t.Run("TTLが過ぎたら取得し直す", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		backend := NewMockIFDBServiceV2(ctrl)
+		gomock.InOrder(
+			backend.EXPECT().Get(gomock.Any(), 1).Return(userA, nil),
+			backend.EXPECT().Get(gomock.Any(), 1).Return(UserData{Id: 1, UserName: "UserA2"}, nil),
+		)
+
+		fake := clock.NewFake(time.Date(2020, 3, 19, 0, 0, 0, 0, time.UTC))
+		service := NewCachedDBService(backend, CacheOptions{TTL: time.Minute, Clock: fake})
+		_, err := service.Get(ctx, 1)
+		assert.NoError(t, err)
+		fake.Advance(59 * time.Second)
+		user, _ := service.Get(ctx, 1)
+		assert.Equal(t, "UserA", user.UserName)
+		fake.Advance(time.Second)
+		user, _ = service.Get(ctx, 1)
+		assert.Equal(t, "UserA2", user.UserName)
+		assert.Equal(t, CacheStats{Hits: 1, Misses: 2}, service.Stats())
+	})
+
+	t.Run("見つからなかったこともキャッシュする", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		backend := NewMockIFDBServiceV2(ctrl)
+		backend.EXPECT().Get(gomock.Any(), 2).Return(UserData{}, fmt.Errorf("id=2: %w", ErrNotFound)).Times(2)
+
+		fake := clock.NewFake(time.Date(2020, 3, 19, 0, 0, 0, 0, time.UTC))
+		service := NewCachedDBService(backend, CacheOptions{TTL: time.Hour, NegativeTTL: time.Second, Clock: fake})
+		for i := 0; i < 3; i++ {
+			_, err := service.Get(ctx, 2)
+			assert.True(t, errors.Is(err, ErrNotFound))
+		}
+		// NegativeTTLが過ぎたら取得し直す
+		fake.Advance(time.Second)
+		_, err := service.Get(ctx, 2)
+		assert.True(t, errors.Is(err, ErrNotFound))
+		assert.Equal(t, CacheStats{Hits: 2, Misses: 2}, service.Stats())
+	})
+
+	t.Run("NegativeTTLが負の場合は見つからなかったことをキャッシュしない", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		backend := NewMockIFDBServiceV2(ctrl)
+		backend.EXPECT().Get(gomock.Any(), 2).Return(UserData{}, ErrNotFound).Times(2)
+
+		service := NewCachedDBService(backend, CacheOptions{NegativeTTL: -1})
+		for i := 0; i < 2; i++ {
+			_, err := service.Get(ctx, 2)
+			assert.Equal(t, ErrNotFound, err)
+		}
+	})
+
+	t.Run("その他のエラーはキャッシュしない", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		backend := NewMockIFDBServiceV2(ctrl)
+		gomock.InOrder(
+			backend.EXPECT().Get(gomock.Any(), 1).Return(UserData{}, errors.New("connection refused")),
+			backend.EXPECT().Get(gomock.Any(), 1).Return(userA, nil),
+		)
+
+		service := NewCachedDBService(backend, CacheOptions{})
+		_, err := service.Get(ctx, 1)
+		assert.EqualError(t, err, "connection refused")
+		user, err := service.Get(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, userA, user)
+	})
+
+	t.Run("上限を超えたら最も長く使われていないものを捨てる", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		backend := NewMockIFDBServiceV2(ctrl)
+		backend.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id int) (UserData, error) {
+			return UserData{Id: id}, nil
+		}).Times(4)
+
+		service := NewCachedDBService(backend, CacheOptions{Size: 2})
+		for _, id := range []int{1, 2, 1, 3} {
+			_, err := service.Get(ctx, id)
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, 2, service.Len())
+		// 2が捨てられ、1と3は残っている
+		for _, id := range []int{1, 3, 2} {
+			_, err := service.Get(ctx, id)
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, CacheStats{Hits: 3, Misses: 4}, service.Stats())
+	})
+
+	t.Run("Deleteで捨てる", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		backend := NewMockIFDBServiceV2(ctrl)
+		backend.EXPECT().Get(gomock.Any(), 1).Return(userA, nil).Times(2)
+
+		service := NewCachedDBService(backend, CacheOptions{})
+		service.Get(ctx, 1)
+		service.Delete(1)
+		service.Get(ctx, 1)
+		assert.Equal(t, CacheStats{Misses: 2}, service.Stats())
+	})
+}
+
+func TestCachedDBService_Get_Concurrent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	backend := NewMockIFDBServiceV2(ctrl)
+
+	// 裏のサービスの呼び出しを止めておき、その間に同じidを取得する
+	release := make(chan struct{})
+	backend.EXPECT().Get(gomock.Any(), 1).DoAndReturn(func(context.Context, int) (UserData, error) {
+		<-release
+		return UserData{Id: 1, UserName: "UserA"}, nil
+	}).Times(1)
+
+	service := NewCachedDBService(backend, CacheOptions{})
+	const n = 10
+	var wg sync.WaitGroup
+	names := make([]string, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user, err := service.Get(context.Background(), 1)
+			assert.NoError(t, err)
+			names[i] = user.UserName
+		}(i)
+	}
+	// すべての呼び出しがキャッシュにないことを確認してから裏のサービスを返す
+	for service.Stats().Misses < n {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	for _, name := range names {
+		assert.Equal(t, "UserA", name)
+	}
+	assert.Equal(t, CacheStats{Misses: n}, service.Stats())
+}
+
+func TestCachedDBService_Get_Cancel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	backend := NewMockIFDBServiceV2(ctrl)
+
+	type key struct{}
+	called := make(chan struct{})
+	release := make(chan struct{})
+	backend.EXPECT().Get(gomock.Any(), 1).DoAndReturn(func(ctx context.Context, id int) (UserData, error) {
+		close(called)
+		<-release
+		// 最初の呼び出しのctxの値は引き継ぎ、キャンセルは引き継がない
+		assert.Equal(t, "first", ctx.Value(key{}))
+		if err := ctx.Err(); err != nil {
+			return UserData{}, err
+		}
+		return UserData{Id: id, UserName: "UserA"}, nil
+	}).Times(1)
+
+	service := NewCachedDBService(backend, CacheOptions{})
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "first"))
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := service.Get(ctx, 1)
+		firstErr <- err
+	}()
+	<-called
+
+	type result struct {
+		user UserData
+		err  error
+	}
+	second := make(chan result, 1)
+	go func() {
+		user, err := service.Get(context.Background(), 1)
+		second <- result{user: user, err: err}
+	}()
+	for service.Stats().Misses < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 最初の呼び出しをキャンセルしても、裏のサービスの結果を待たずにすぐ返る
+	cancel()
+	assert.Equal(t, context.Canceled, <-firstErr)
+
+	// 2つ目の呼び出しはキャンセルされずにユーザーを受け取る
+	close(release)
+	res := <-second
+	assert.NoError(t, res.err)
+	assert.Equal(t, "UserA", res.user.UserName)
+
+	// 結果はキャッシュされている
+	user, err := service.Get(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "UserA", user.UserName)
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 2}, service.Stats())
+}
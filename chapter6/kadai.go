@@ -1,6 +1,7 @@
 package chapter6
 
 //go:generate mockgen -source=$GOFILE -destination=kadai_mock.go -package=$GOPACKAGE -self_package=github.com/apbgo/go-study-group/$GOPACKAGE
+//go:generate go run github.com/apbgo/go-study-group/tools/decorgen/cmd/decorgen -type IFUserItemService,IFUserItemRepository
 
 import (
 	"context"
@@ -0,0 +1,76 @@
+// Code generated by decorgen. DO NOT EDIT.
+
+package chapter6
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/apbgo/go-study-group/tools/decorgen/decor"
+)
+
+// IFUserItemServiceDecorator IFUserItemServiceの各メソッドの前後でdecor.Hooksを呼び出すデコレータ
+type IFUserItemServiceDecorator struct {
+	next  IFUserItemService
+	hooks decor.Hooks
+}
+
+// NewIFUserItemServiceDecorator nextをhooksで包んだIFUserItemServiceDecoratorを作る
+func NewIFUserItemServiceDecorator(next IFUserItemService, hooks decor.Hooks) *IFUserItemServiceDecorator {
+	return &IFUserItemServiceDecorator{next: next, hooks: hooks}
+}
+
+// Provide IFUserItemService.Provideの前後でフックを呼び出す
+func (d *IFUserItemServiceDecorator) Provide(ctx context.Context, userID int64, rewards ...Reward) (r0 error) {
+	call := &decor.Call{Context: ctx, Interface: "IFUserItemService", Method: "Provide", Args: []interface{}{ctx, userID, rewards}}
+	d.hooks.Invoke(call, func() error {
+		r0 = d.next.Provide(ctx, userID, rewards...)
+		call.Results = []interface{}{}
+		return r0
+	})
+	return call.Err
+}
+
+// IFUserItemRepositoryDecorator IFUserItemRepositoryの各メソッドの前後でdecor.Hooksを呼び出すデコレータ
+type IFUserItemRepositoryDecorator struct {
+	next  IFUserItemRepository
+	hooks decor.Hooks
+}
+
+// NewIFUserItemRepositoryDecorator nextをhooksで包んだIFUserItemRepositoryDecoratorを作る
+func NewIFUserItemRepositoryDecorator(next IFUserItemRepository, hooks decor.Hooks) *IFUserItemRepositoryDecorator {
+	return &IFUserItemRepositoryDecorator{next: next, hooks: hooks}
+}
+
+// FindByUserIdAndItemIDs IFUserItemRepository.FindByUserIdAndItemIDsの前後でフックを呼び出す
+func (d *IFUserItemRepositoryDecorator) FindByUserIdAndItemIDs(ctx context.Context, tx *sql.Tx, userID int64, itemIDs []int64) (r0 []*IUserItem, r1 error) {
+	call := &decor.Call{Context: ctx, Interface: "IFUserItemRepository", Method: "FindByUserIdAndItemIDs", Args: []interface{}{ctx, tx, userID, itemIDs}, Out: []interface{}{&r0}}
+	d.hooks.Invoke(call, func() error {
+		r0, r1 = d.next.FindByUserIdAndItemIDs(ctx, tx, userID, itemIDs)
+		call.Results = []interface{}{r0}
+		return r1
+	})
+	return r0, call.Err
+}
+
+// Insert IFUserItemRepository.Insertの前後でフックを呼び出す
+func (d *IFUserItemRepositoryDecorator) Insert(ctx context.Context, tx *sql.Tx, iUserItem *IUserItem) (r0 error) {
+	call := &decor.Call{Context: ctx, Interface: "IFUserItemRepository", Method: "Insert", Args: []interface{}{ctx, tx, iUserItem}}
+	d.hooks.Invoke(call, func() error {
+		r0 = d.next.Insert(ctx, tx, iUserItem)
+		call.Results = []interface{}{}
+		return r0
+	})
+	return call.Err
+}
+
+// Update IFUserItemRepository.Updateの前後でフックを呼び出す
+func (d *IFUserItemRepositoryDecorator) Update(ctx context.Context, tx *sql.Tx, iUserItem *IUserItem) (r0 bool, r1 error) {
+	call := &decor.Call{Context: ctx, Interface: "IFUserItemRepository", Method: "Update", Args: []interface{}{ctx, tx, iUserItem}, Out: []interface{}{&r0}}
+	d.hooks.Invoke(call, func() error {
+		r0, r1 = d.next.Update(ctx, tx, iUserItem)
+		call.Results = []interface{}{r0}
+		return r1
+	})
+	return r0, call.Err
+}
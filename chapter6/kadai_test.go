@@ -3,10 +3,16 @@ package chapter6
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/apbgo/go-study-group/tools/cassette"
+	"github.com/apbgo/go-study-group/tools/decorgen/decor"
 	gomock "github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
@@ -357,3 +363,108 @@ func TestUserItemService_Provide(t *testing.T) {
 		assert.Error(t, userItemService.Provide(ctx, 1, reward1, reward2))
 	})
 }
+
+// providerFunc 関数をIFUserItemServiceとして使う
+type providerFunc func(ctx context.Context, userID int64, rewards ...Reward) error
+
+func (f providerFunc) Provide(ctx context.Context, userID int64, rewards ...Reward) error {
+	return f(ctx, userID, rewards...)
+}
+
+func TestIFUserItemServiceDecorator(t *testing.T) {
+	rewards := []Reward{{ItemID: 1, Count: 10}, {ItemID: 2, Count: 1}}
+	var provided [][]Reward
+	service := providerFunc(func(ctx context.Context, userID int64, rewards ...Reward) error {
+		provided = append(provided, rewards)
+		if len(provided) < 3 {
+			return fmt.Errorf("deadlock")
+		}
+		return nil
+	})
+
+	// 可変長引数はそのまま渡され、Argsにはスライスとして入る
+	var calls []decor.Call
+	decorated := NewIFUserItemServiceDecorator(service, decor.Chain(
+		decor.Hooks{After: func(call *decor.Call) { calls = append(calls, *call) }},
+		decor.Retry(3, nil),
+	))
+	ctx := context.Background()
+	err := decorated.Provide(ctx, 1, rewards...)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]Reward{rewards, rewards, rewards}, provided)
+	if assert.Len(t, calls, 3) {
+		assert.Equal(t, []interface{}{ctx, int64(1), rewards}, calls[0].Args)
+		assert.Equal(t, ctx, calls[0].Context)
+		assert.EqualError(t, calls[0].Err, "deadlock")
+		assert.Equal(t, 3, calls[2].Attempt)
+		assert.NoError(t, calls[2].Err)
+	}
+
+	// 可変長引数を渡さない場合
+	provided = nil
+	calls = nil
+	assert.NoError(t, decorated.Provide(ctx, 1))
+	assert.Equal(t, []interface{}{ctx, int64(1), []Reward(nil)}, calls[0].Args)
+}
+
+func TestIFUserItemRepository_Cassette(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chapter6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "repository.json")
+	ctx := context.Background()
+	item := &IUserItem{UserID: 1, ItemID: 1, Count: 100}
+
+	// 記録: 本来はDBにつないだUserItemRepositoryを包むが、ここではMockの結果を記録する
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock := NewMockIFUserItemRepository(ctrl)
+	gomock.InOrder(
+		mock.EXPECT().FindByUserIdAndItemIDs(ctx, gomock.Any(), int64(1), []int64{1, 2}).Return([]*IUserItem{item}, nil),
+		mock.EXPECT().Update(ctx, gomock.Any(), gomock.Any()).Return(true, nil),
+		mock.EXPECT().Insert(ctx, gomock.Any(), gomock.Any()).Return(fmt.Errorf("duplicate: %w", sql.ErrNoRows)),
+	)
+	run := func(repo IFUserItemRepository, tx *sql.Tx) {
+		items, err := repo.FindByUserIdAndItemIDs(ctx, tx, 1, []int64{1, 2})
+		assert.NoError(t, err)
+		assert.Equal(t, []*IUserItem{item}, items)
+		ok, err := repo.Update(ctx, tx, &IUserItem{UserID: 1, ItemID: 1, Count: 110})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		err = repo.Insert(ctx, tx, &IUserItem{UserID: 1, ItemID: 2, Count: 1})
+		assert.True(t, errors.Is(err, sql.ErrNoRows))
+	}
+
+	recorder, err := cassette.New(path, cassette.Record)
+	if !assert.NoError(t, err) {
+		return
+	}
+	// *sql.Txは呼び出すたびに違う値になるので比べない
+	recorder.Match(cassette.IgnoreType((*sql.Tx)(nil)))
+	recorder.RegisterError(sql.ErrNoRows)
+	run(NewIFUserItemRepositoryDecorator(mock, recorder.Hooks()), &sql.Tx{})
+	assert.NoError(t, recorder.Close())
+
+	// 再生: 包んだ実装は呼び出さず、記録した結果を返す
+	player, err := cassette.New(path, cassette.Replay)
+	if !assert.NoError(t, err) {
+		return
+	}
+	player.Match(cassette.IgnoreType((*sql.Tx)(nil)))
+	player.RegisterError(sql.ErrNoRows)
+	run(NewIFUserItemRepositoryDecorator(nil, player.Hooks()), nil)
+	assert.NoError(t, player.Close())
+
+	// 引数が記録と違う場合は差分がわかるエラーになる
+	player, err = cassette.New(path, cassette.Replay)
+	if !assert.NoError(t, err) {
+		return
+	}
+	player.Match(cassette.IgnoreType((*sql.Tx)(nil)))
+	repo := NewIFUserItemRepositoryDecorator(nil, player.Hooks())
+	_, err = repo.FindByUserIdAndItemIDs(ctx, nil, 1, []int64{1, 3})
+	assert.True(t, errors.Is(err, cassette.ErrMismatch))
+	assert.Contains(t, err.Error(), "  引数3:\n    - 記録: [1,2]\n    + 実際: [1,3]\n")
+}
@@ -0,0 +1,77 @@
+// Package clock 時刻とタイマーを差し替えられるようにするパッケージ
+//
+// time.Now()やtime.After()を直接呼び出すと、テストで実際に時間が経つのを待つ必要がある。
+// Clockを引数やフィールドで受け取るようにしておけば、本番ではNew()、
+// テストではNewFake()を渡し、Advance()で時間を進めて決まった順番でタイマーを発火させられる。
+package clock
+
+import "time"
+
+// Clock timeパッケージの時刻とタイマーの関数をまとめたインタフェース
+type Clock interface {
+	// Now 現在の時刻を返す
+	Now() time.Time
+	// Since tからの経過時間を返す
+	Since(t time.Time) time.Duration
+	// After dが経過したら現在の時刻を送るチャネルを返す
+	After(d time.Duration) <-chan time.Time
+	// NewTimer dが経過したら1回だけ発火するTimerを作る
+	NewTimer(d time.Duration) Timer
+	// NewTicker dごとに発火するTickerを作る。dが0以下の場合はパニックする
+	NewTicker(d time.Duration) Ticker
+	// Sleep dが経過するまで待つ
+	Sleep(d time.Duration)
+}
+
+// Timer time.Timerと同じ操作ができるタイマー
+type Timer interface {
+	// C 発火した時刻を受け取るチャネル
+	C() <-chan time.Time
+	// Stop タイマーを止める。発火する前に止めた場合はtrueを返す
+	Stop() bool
+	// Reset dが経過したら発火するように設定し直す。発火する前だった場合はtrueを返す
+	Reset(d time.Duration) bool
+}
+
+// Ticker time.Tickerと同じ操作ができるティッカー
+type Ticker interface {
+	// C 発火した時刻を受け取るチャネル
+	C() <-chan time.Time
+	// Stop ティッカーを止める
+	Stop()
+}
+
+// New timeパッケージをそのまま使うClockを返す
+func New() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time        { return t.t.C }
+func (t realTimer) Stop() bool                 { return t.t.Stop() }
+func (t realTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time { return t.t.C }
+func (t realTicker) Stop()               { t.t.Stop() }
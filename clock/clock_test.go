@@ -0,0 +1,38 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	c := New()
+
+	t.Run("Now Since", func(t *testing.T) {
+		t.Parallel()
+		start := c.Now()
+		c.Sleep(time.Millisecond)
+		assert.True(t, c.Since(start) >= time.Millisecond)
+	})
+
+	t.Run("After NewTimer NewTicker", func(t *testing.T) {
+		t.Parallel()
+		select {
+		case <-c.After(time.Millisecond):
+		case <-time.After(5 * time.Second):
+			t.Fatal("Afterが発火しません")
+		}
+
+		timer := c.NewTimer(time.Hour)
+		assert.True(t, timer.Reset(time.Millisecond))
+		<-timer.C()
+		assert.False(t, timer.Stop())
+
+		ticker := c.NewTicker(time.Millisecond)
+		<-ticker.C()
+		<-ticker.C()
+		ticker.Stop()
+	})
+}
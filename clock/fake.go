@@ -0,0 +1,191 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake テスト用のClock
+// 時刻はAdvanceを呼び出したときだけ進み、その間に期限が来たタイマーとティッカーを期限の順に発火させる
+// Sleepやタイマーを待つゴルーチンとテストの間で待ち合わせるにはBlockUntilを使う
+type Fake struct {
+	mu sync.Mutex
+	// cond 待っているタイマーの数が変わったことをBlockUntilに知らせる
+	cond *sync.Cond
+	now  time.Time
+	// timers 発火を待っているタイマーとティッカー
+	timers []*fakeTimer
+	// seq 期限が同じタイマーを作った順に発火させるための通し番号
+	seq int64
+}
+
+// NewFake 時刻がnowのFakeを作る
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now 現在の時刻を返す
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since tからの経過時間を返す
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// After dが経過したら現在の時刻を送るチャネルを返す
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// Sleep Advanceでdが経過するまで待つ
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+// NewTimer dが経過したら1回だけ発火するTimerを作る。dが0以下の場合はすぐに発火する
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{fake: f, c: make(chan time.Time, 1)}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.schedule(t, d)
+	return t
+}
+
+// NewTicker dごとに発火するTickerを作る
+// time.Tickerと同様に、受け取られていない時刻があれば次の時刻は捨てる
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: NewTickerには0より大きい間隔を指定してください")
+	}
+	t := &fakeTimer{fake: f, c: make(chan time.Time, 1), period: d}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.schedule(t, d)
+	return fakeTicker{t}
+}
+
+// Advance 時刻をdだけ進める
+// 間に期限が来たタイマーは期限の早い順に、期限が同じであれば作った順に発火させる
+// 発火させる時点の時刻はそのタイマーの期限になるので、ティッカーは進めた間の回数だけ発火する
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	end := f.now.Add(d)
+	for {
+		t := f.next(end)
+		if t == nil {
+			break
+		}
+		if t.deadline.After(f.now) {
+			f.now = t.deadline
+		}
+		if t.period > 0 {
+			f.seq++
+			t.deadline = t.deadline.Add(t.period)
+			t.seq = f.seq
+		} else {
+			f.remove(t)
+		}
+		t.fire(f.now)
+	}
+	f.now = end
+}
+
+// BlockUntil 発火を待っているタイマーとティッカーがn個以上になるまで待つ
+// 別のゴルーチンがSleepやAfterで待ち始めてからAdvanceするために使う
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.timers) < n {
+		f.cond.Wait()
+	}
+}
+
+// schedule tの期限を今からdにして待ちに加える。f.muをロックして呼び出す
+func (f *Fake) schedule(t *fakeTimer, d time.Duration) {
+	if d <= 0 && t.period == 0 {
+		t.fire(f.now)
+		return
+	}
+	f.seq++
+	t.deadline = f.now.Add(d)
+	t.seq = f.seq
+	f.timers = append(f.timers, t)
+	f.cond.Broadcast()
+}
+
+// next end以前に期限が来るタイマーのうち、最初に発火させるものを返す
+func (f *Fake) next(end time.Time) *fakeTimer {
+	var next *fakeTimer
+	for _, t := range f.timers {
+		if t.deadline.After(end) {
+			continue
+		}
+		if next == nil || t.deadline.Before(next.deadline) || t.deadline.Equal(next.deadline) && t.seq < next.seq {
+			next = t
+		}
+	}
+	return next
+}
+
+// remove tを待ちから外す。待っていた場合はtrueを返す
+func (f *Fake) remove(t *fakeTimer) bool {
+	for i, timer := range f.timers {
+		if timer == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			f.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+// fakeTimer FakeのTimerとTicker (periodが0より大きい場合はTicker)
+type fakeTimer struct {
+	fake     *Fake
+	c        chan time.Time
+	deadline time.Time
+	period   time.Duration
+	seq      int64
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) fire(now time.Time) {
+	select {
+	case t.c <- now:
+	default:
+	}
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.fake.mu.Lock()
+	defer t.fake.mu.Unlock()
+	return t.fake.remove(t)
+}
+
+// Reset Tickerのインタフェースには含まれないので、Timerの場合だけ呼び出される
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.fake.mu.Lock()
+	defer t.fake.mu.Unlock()
+	active := t.fake.remove(t)
+	t.fake.schedule(t, d)
+	return active
+}
+
+// fakeTicker Tickerとして使うfakeTimer
+type fakeTicker struct {
+	*fakeTimer
+}
+
+func (t fakeTicker) Stop() {
+	t.fakeTimer.Stop()
+}
@@ -0,0 +1,154 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2019, 7, 4, 18, 30, 0, 0, time.UTC)
+
+// received chにすでに送られている時刻を返す。送られていなければfalseを返す
+func received(ch <-chan time.Time) (time.Time, bool) {
+	select {
+	case now := <-ch:
+		return now, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestFake(t *testing.T) {
+	t.Run("Now Since", func(t *testing.T) {
+		t.Parallel()
+		c := NewFake(start)
+		assert.Equal(t, start, c.Now())
+		c.Advance(90 * time.Minute)
+		assert.Equal(t, start.Add(90*time.Minute), c.Now())
+		assert.Equal(t, 90*time.Minute, c.Since(start))
+	})
+
+	t.Run("Advanceで期限が来たタイマーだけ発火する", func(t *testing.T) {
+		t.Parallel()
+		c := NewFake(start)
+		after := c.After(time.Second)
+		timer := c.NewTimer(3 * time.Second)
+
+		c.Advance(999 * time.Millisecond)
+		_, ok := received(after)
+		assert.False(t, ok)
+
+		c.Advance(time.Millisecond)
+		now, ok := received(after)
+		assert.True(t, ok)
+		assert.Equal(t, start.Add(time.Second), now)
+		_, ok = received(timer.C())
+		assert.False(t, ok)
+
+		// 期限を過ぎて進めた場合は、期限の時刻が送られる
+		c.Advance(time.Hour)
+		now, ok = received(timer.C())
+		assert.True(t, ok)
+		assert.Equal(t, start.Add(3*time.Second), now)
+		assert.False(t, timer.Stop())
+	})
+
+	t.Run("まとめて進めても期限の順に期限の時刻で発火する", func(t *testing.T) {
+		t.Parallel()
+		c := NewFake(start)
+		timers := map[time.Duration]Timer{}
+		for _, d := range []time.Duration{3 * time.Second, time.Second, 2 * time.Second} {
+			timers[d] = c.NewTimer(d)
+		}
+		ticker := c.NewTicker(1500 * time.Millisecond)
+		c.Advance(time.Hour)
+		for d, timer := range timers {
+			now, ok := received(timer.C())
+			assert.True(t, ok)
+			assert.Equal(t, start.Add(d), now)
+		}
+		// ティッカーは最初の時刻だけがチャネルに残る
+		now, ok := received(ticker.C())
+		assert.True(t, ok)
+		assert.Equal(t, start.Add(1500*time.Millisecond), now)
+		assert.Equal(t, start.Add(time.Hour), c.Now())
+	})
+
+	t.Run("ティッカーは進めた間の回数だけ発火する", func(t *testing.T) {
+		t.Parallel()
+		c := NewFake(start)
+		ticker := c.NewTicker(time.Second)
+		var ticks []time.Time
+		for i := 0; i < 3; i++ {
+			c.Advance(time.Second)
+			now, ok := received(ticker.C())
+			assert.True(t, ok)
+			ticks = append(ticks, now)
+		}
+		assert.Equal(t, []time.Time{start.Add(time.Second), start.Add(2 * time.Second), start.Add(3 * time.Second)}, ticks)
+
+		// 受け取られていない時刻があれば次の時刻は捨てる
+		c.Advance(5 * time.Second)
+		now, ok := received(ticker.C())
+		assert.True(t, ok)
+		assert.Equal(t, start.Add(4*time.Second), now)
+		_, ok = received(ticker.C())
+		assert.False(t, ok)
+
+		ticker.Stop()
+		c.Advance(time.Hour)
+		_, ok = received(ticker.C())
+		assert.False(t, ok)
+
+		assert.Panics(t, func() { c.NewTicker(0) })
+	})
+
+	t.Run("Stop Reset", func(t *testing.T) {
+		t.Parallel()
+		c := NewFake(start)
+		timer := c.NewTimer(time.Second)
+		assert.True(t, timer.Stop())
+		assert.False(t, timer.Stop())
+		c.Advance(time.Second)
+		_, ok := received(timer.C())
+		assert.False(t, ok)
+
+		assert.False(t, timer.Reset(2*time.Second))
+		assert.True(t, timer.Reset(3*time.Second))
+		c.Advance(2 * time.Second)
+		_, ok = received(timer.C())
+		assert.False(t, ok)
+		c.Advance(time.Second)
+		now, ok := received(timer.C())
+		assert.True(t, ok)
+		assert.Equal(t, start.Add(4*time.Second), now)
+
+		// 0以下はすぐに発火する
+		_, ok = received(c.After(0))
+		assert.True(t, ok)
+	})
+
+	t.Run("SleepはAdvanceするまで待つ", func(t *testing.T) {
+		t.Parallel()
+		c := NewFake(start)
+		done := make(chan time.Time)
+		go func() {
+			c.Sleep(time.Minute)
+			done <- c.Now()
+		}()
+		c.BlockUntil(1)
+		select {
+		case <-done:
+			t.Fatal("Advanceする前にSleepが終わりました")
+		default:
+		}
+		c.Advance(time.Minute)
+		select {
+		case now := <-done:
+			assert.Equal(t, start.Add(time.Minute), now)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Sleepが終わりません")
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package cut
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/txtar"
+)
+
+// update trueの場合、testdata/conformanceの期待する出力をGNU cutの出力で書き換える
+// go test ./cut -run TestConformance -update
+var update = flag.Bool("update", false, "testdata/conformanceの期待する出力をGNU cutで作り直す")
+
+// TestConformance testdata/conformanceの各ファイルについて、GNU cutと同じ出力になるかを確認する
+// ファイルはtxtar形式で、コメントにcutの引数を空白区切りで書き、inputとoutputのファイルを置く
+func TestConformance(t *testing.T) {
+	files, err := filepath.Glob("testdata/conformance/*.txtar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("testdata/conformanceにファイルがありません")
+	}
+	for _, file := range files {
+		file := file
+		name := strings.TrimSuffix(filepath.Base(file), ".txtar")
+		t.Run(name, func(t *testing.T) {
+			ar, err := txtar.ParseFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			args := strings.Fields(string(ar.Comment))
+			input, ok := archiveFile(ar, "input")
+			if !ok {
+				t.Fatalf("%s: inputがありません", file)
+			}
+			if *update {
+				updateConformance(t, file, ar, args, input)
+				return
+			}
+			want, ok := archiveFile(ar, "output")
+			if !ok {
+				t.Fatalf("%s: outputがありません", file)
+			}
+
+			opts, err := parseCutArgs(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stdout := new(bytes.Buffer)
+			err = Cut(bytes.NewReader(input), stdout, opts)
+			assert.NoError(t, err)
+			assert.Equal(t, string(want), stdout.String(), "cut %s", strings.Join(args, " "))
+		})
+	}
+}
+
+func archiveFile(ar *txtar.Archive, name string) ([]byte, bool) {
+	for _, f := range ar.Files {
+		if f.Name == name {
+			return f.Data, true
+		}
+	}
+	return nil, false
+}
+
+// updateConformance GNU cutを実行してoutputを書き換える
+func updateConformance(t *testing.T, file string, ar *txtar.Archive, args []string, input []byte) {
+	t.Helper()
+	cmd := exec.Command("cut", args...)
+	cmd.Stdin = bytes.NewReader(input)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("cut %s: %v", strings.Join(args, " "), err)
+	}
+	files := []txtar.File{{Name: "input", Data: input}, {Name: "output", Data: out}}
+	ar.Files = files
+	if err := ioutil.WriteFile(file, txtar.Format(ar), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// parseCutArgs GNU cutの引数をOptionsに変換する
+// 区切り文字はGNU cutと同じくデフォルトをタブにする
+func parseCutArgs(args []string) (Options, error) {
+	opts := Options{Delimiter: "\t"}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var value string
+		switch arg {
+		case "-d", "-f", "-b", "-c", "--output-delimiter":
+			if i+1 == len(args) {
+				return opts, fmt.Errorf("%sの値がありません", arg)
+			}
+			i++
+			value = args[i]
+		}
+		var err error
+		switch arg {
+		case "-d":
+			opts.Delimiter = value
+		case "-f":
+			opts.Fields, err = ParseList(value)
+		case "-b":
+			opts.Bytes, err = ParseList(value)
+		case "-c":
+			opts.Characters, err = ParseList(value)
+		case "--output-delimiter":
+			opts.OutputDelimiter = value
+		case "-s":
+			opts.OnlyDelimited = true
+		case "--complement":
+			opts.Complement = true
+		default:
+			return opts, fmt.Errorf("対応していない引数です: %s", arg)
+		}
+		if err != nil {
+			return opts, err
+		}
+	}
+	return opts, opts.Validate()
+}
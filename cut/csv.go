@@ -0,0 +1,170 @@
+package cut
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// QuoteMode CSVモードで出力する際のクォート方法
+type QuoteMode int
+
+const (
+	// QuoteMinimal 区切り文字、ダブルクォート、改行などを含むフィールドのみクォートする
+	QuoteMinimal QuoteMode = iota
+	// QuoteAll すべてのフィールドをクォートする
+	QuoteAll
+	// QuoteNone クォートせずにそのまま出力する
+	QuoteNone
+)
+
+// ParseQuoteMode --quoteで指定された文字列をQuoteModeに変換する
+func ParseQuoteMode(s string) (QuoteMode, error) {
+	switch s {
+	case "", "minimal":
+		return QuoteMinimal, nil
+	case "all":
+		return QuoteAll, nil
+	case "none":
+		return QuoteNone, nil
+	}
+	return 0, fmt.Errorf("不正なクォート方法です: %q (minimal, all, noneのいずれかを指定してください)", s)
+}
+
+// csvSplitter CSVのレコードをencoding/csvでフィールドに分割する
+// クォートされたフィールド内の改行で複数行にまたがるレコードを扱うため、
+// 物理行を受け取ってレコードの終わりを判定してからcsv.Readerに渡す
+type csvSplitter struct {
+	comma  rune
+	lazy   bool
+	src    *recordSource
+	reader *csv.Reader
+	// fed csv.Readerに渡した物理行数 (ParseErrorの行番号の補正に使う)
+	fed int
+
+	// レコードの終わりを判定するための状態
+	inQuote    bool
+	fieldStart bool
+}
+
+func newCSVSplitter(comma rune, lazy bool) *csvSplitter {
+	src := &recordSource{}
+	reader := csv.NewReader(src)
+	reader.Comma = comma
+	reader.LazyQuotes = lazy
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	return &csvSplitter{
+		comma:      comma,
+		lazy:       lazy,
+		src:        src,
+		reader:     reader,
+		fieldStart: true,
+	}
+}
+
+// scanLine 物理行を1行読み進め、レコードが完結したかを返す
+// クォートの中で行が終わった場合は次の行もレコードの一部になる
+func (s *csvSplitter) scanLine(line string) bool {
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		next := line[i+size:]
+		i += size
+
+		if !s.inQuote {
+			if r == '"' && s.fieldStart {
+				s.inQuote = true
+			}
+			s.fieldStart = r == s.comma
+			continue
+		}
+
+		if r != '"' {
+			continue
+		}
+		if strings.HasPrefix(next, `"`) {
+			// エスケープされたダブルクォート
+			i++
+			continue
+		}
+		if s.lazy && next != "" && !strings.HasPrefix(next, string(s.comma)) {
+			// LazyQuotesの場合、区切り文字の前以外のクォートは文字として扱われる
+			continue
+		}
+		s.inQuote = false
+	}
+
+	if s.inQuote {
+		return false
+	}
+	s.fieldStart = true
+	return true
+}
+
+// split 1レコード分のテキストをフィールドに分割する
+// startLineはレコードが始まる行番号、linesはレコードの物理行数
+func (s *csvSplitter) split(text string, startLine, lines int) ([]string, error) {
+	// 次のレコードのために状態を戻しておく
+	s.inQuote = false
+	s.fieldStart = true
+
+	offset := startLine - s.fed - 1
+	s.src.reset(text + "\n")
+	s.fed += lines
+
+	fields, err := s.reader.Read()
+	if err != nil {
+		if pe, ok := err.(*csv.ParseError); ok {
+			// csv.Readerは渡された行しか知らないので、入力全体での行番号に補正する
+			pe.StartLine += offset
+			pe.Line += offset
+			return nil, pe
+		}
+		return nil, err
+	}
+	return fields, nil
+}
+
+// recordSource csv.Readerに1レコードずつ渡すためのio.Reader
+type recordSource struct {
+	s string
+}
+
+func (r *recordSource) reset(s string) {
+	r.s = s
+}
+
+func (r *recordSource) Read(p []byte) (int, error) {
+	if r.s == "" {
+		return 0, io.EOF
+	}
+	n := copy(p, r.s)
+	r.s = r.s[n:]
+	return n, nil
+}
+
+// writeCSVField CSVのフィールドをクォート方法に従って書き出す
+func writeCSVField(w *bufio.Writer, field, delimiter string, mode QuoteMode) {
+	if mode == QuoteNone || (mode == QuoteMinimal && !needsQuote(field, delimiter)) {
+		w.WriteString(field)
+		return
+	}
+	w.WriteByte('"')
+	w.WriteString(strings.Replace(field, `"`, `""`, -1))
+	w.WriteByte('"')
+}
+
+// needsQuote encoding/csvのWriterと同じ条件でクォートが必要かを判定する
+func needsQuote(field, delimiter string) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` || (delimiter != "" && strings.Contains(field, delimiter)) || strings.ContainsAny(field, "\"\r\n") {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(field)
+	return r == ' ' || r == '\t'
+}
@@ -0,0 +1,108 @@
+package cut
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCut_CSV(t *testing.T) {
+	const input = `1,"Hello, Gopher",TRUE
+2,"multi
+line",FALSE
+3,"say ""hi""",TRUE
+
+4,plain,FALSE
+`
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "クォート内の区切り文字と改行",
+			opts: Options{Delimiter: ",", Fields: mustParseList(t, "2"), CSV: true, Quote: QuoteNone},
+			want: "Hello, Gopher\nmulti\nline\nsay \"hi\"\n\nplain\n",
+		},
+		{
+			name: "必要な場合のみ再クォート",
+			opts: Options{Delimiter: ",", Fields: mustParseList(t, "1-2"), CSV: true},
+			want: "1,\"Hello, Gopher\"\n2,\"multi\nline\"\n3,\"say \"\"hi\"\"\"\n\n4,plain\n",
+		},
+		{
+			name: "出力の区切り文字が変われば不要なクォートは外れる",
+			opts: Options{Delimiter: ",", OutputDelimiter: "\t", Fields: mustParseList(t, "2-3"), CSV: true},
+			want: "Hello, Gopher\tTRUE\n\"multi\nline\"\tFALSE\n\"say \"\"hi\"\"\"\tTRUE\n\nplain\tFALSE\n",
+		},
+		{
+			name: "すべてクォート",
+			opts: Options{Delimiter: ",", Fields: mustParseList(t, "1,3"), CSV: true, Quote: QuoteAll, OnlyDelimited: true},
+			want: "\"1\",\"TRUE\"\n\"2\",\"FALSE\"\n\"3\",\"TRUE\"\n\"4\",\"FALSE\"\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			stdout := new(bytes.Buffer)
+			err := Cut(bytes.NewBufferString(input), stdout, tt.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+
+	t.Run("LazyQuotes", func(t *testing.T) {
+		t.Parallel()
+		input := "1,a \"quoted\" word,x\n2,\"b \"c\" d\",y\n"
+		opts := Options{Delimiter: ",", Fields: mustParseList(t, "2-3"), CSV: true, LazyQuotes: true, Quote: QuoteNone}
+
+		stdout := new(bytes.Buffer)
+		err := Cut(bytes.NewBufferString(input), stdout, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, "a \"quoted\" word,x\nb \"c\" d,y\n", stdout.String())
+	})
+
+	t.Run("複数行のレコードの後でも行番号がずれない", func(t *testing.T) {
+		t.Parallel()
+		input := "1,\"a\nb\nc\",x\n2,\"bad\"quote,y\n"
+		opts := Options{Delimiter: ",", Fields: mustParseList(t, "1"), CSV: true}
+
+		err := Cut(bytes.NewBufferString(input), new(bytes.Buffer), opts)
+		pe, ok := err.(*csv.ParseError)
+		if assert.True(t, ok, "%v", err) {
+			assert.Equal(t, 4, pe.StartLine)
+			assert.Equal(t, 4, pe.Line)
+			assert.Equal(t, csv.ErrQuote, pe.Err)
+		}
+	})
+
+	t.Run("閉じられないクォート", func(t *testing.T) {
+		t.Parallel()
+		input := "1,x\n2,\"open\nnever closed\n"
+		opts := Options{Delimiter: ",", Fields: mustParseList(t, "1"), CSV: true}
+
+		stdout := new(bytes.Buffer)
+		err := Cut(bytes.NewBufferString(input), stdout, opts)
+		pe, ok := err.(*csv.ParseError)
+		if assert.True(t, ok, "%v", err) {
+			assert.Equal(t, 2, pe.StartLine)
+		}
+		assert.Equal(t, "1\n", stdout.String())
+	})
+
+	t.Run("異常系", func(t *testing.T) {
+		t.Parallel()
+		list := mustParseList(t, "1")
+		for _, opts := range []Options{
+			{Delimiter: "::", Fields: list, CSV: true},
+			{Delimiter: "\"", Fields: list, CSV: true},
+			{Delimiter: ",", Fields: list, LazyQuotes: true},
+		} {
+			err := Cut(bytes.NewBufferString(input), new(bytes.Buffer), opts)
+			assert.Error(t, err)
+		}
+	})
+}
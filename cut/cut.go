@@ -0,0 +1,647 @@
+package cut
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrMissingField 指定したフィールドが存在しない行があった場合のエラー
+var ErrMissingField = errors.New("-fの値に該当するデータがありません")
+
+// Options go-cutの動作を指定するオプション
+// Fields, Names, Bytes, Charactersのいずれか1つを指定する
+type Options struct {
+	// Delimiter 入力の区切り文字 (-d)
+	Delimiter string
+	// RegexDelimiter 入力の区切り文字を正規表現で指定する (--regex-delimiter)
+	// 指定した場合はDelimiterを使わない
+	RegexDelimiter *regexp.Regexp
+	// Whitespace awkと同様に、連続する空白とタブを1つの区切りとして扱う (--whitespace)
+	// 行頭と行末の空白は無視する。指定した場合はDelimiterを使わない
+	Whitespace bool
+	// Trim 各フィールドの前後の空白を取り除く (--trim)
+	// --whereの比較やヘッダのカラム名にも取り除いた値を使う
+	Trim bool
+	// OutputDelimiter 出力の区切り文字 (--output-delimiter)
+	// Fieldsの場合、空であればDelimiterを使う
+	// RegexDelimiter, Whitespaceの場合は空白1文字を使う
+	// Bytes, Charactersの場合は連続しない範囲の間に出力する
+	OutputDelimiter string
+	// Fields 取り出すフィールドのリスト (-f)
+	Fields List
+	// Names 取り出すカラム名のリスト (-F)
+	// Headerと一緒に指定し、'created_*'のようなglobも使える
+	// 出力は指定した順番になる
+	Names []string
+	// Header 1行目をカラム名のヘッダとして扱う (--header)
+	Header bool
+	// Bytes 取り出すバイト位置のリスト (-b)
+	Bytes List
+	// Characters 取り出す文字位置のリスト (-c)
+	// UTF-8のルーン単位で数えるため、マルチバイト文字が途中で分割されることはない
+	Characters List
+	// NoSplitMultibyte Bytesでマルチバイト文字を分割しない (-n)
+	// 文字の最後のバイトが選択されている場合のみ、その文字全体を出力する
+	NoSplitMultibyte bool
+	// Complement 指定した位置以外を取り出す (--complement)
+	Complement bool
+	// OnlyDelimited 区切り文字を含まない行を出力しない (-s)
+	OnlyDelimited bool
+	// CSV 入力をRFC 4180形式のCSVとして読み込む (--csv)
+	// クォートされたフィールド内の区切り文字や改行はフィールドの一部として扱う
+	CSV bool
+	// LazyQuotes CSVで不正なクォートを許容する (--lazy-quotes)
+	LazyQuotes bool
+	// Quote CSVで出力する際のクォート方法 (--quote)
+	Quote QuoteMode
+	// Format 出力形式 (--format)
+	// FormatText以外は-fまたは-Fと一緒に指定する
+	Format Format
+	// RecordSeparator 入力と出力の行の区切り (-z, --record-separator)
+	// 空の場合は改行で区切り、行末の\rは取り除く。出力の改行コードは1行目に合わせて\nか\r\nにする
+	RecordSeparator string
+	// Where 出力する行を絞り込む条件 (--where)
+	// 条件を満たさない行は出力しない。ヘッダの行は常に出力する
+	Where *Where
+	// MaxLineLength 1行の最大バイト数
+	// 0の場合は上限なし。超えた場合はErrLineTooLongを返す
+	MaxLineLength int
+	// WithFilename CutFilesで出力する各行の先頭にファイル名を付ける (--with-filename)
+	// 標準入力は"(standard input)"になる。JSONとMarkdownの出力形式では使えない
+	WithFilename bool
+	// Jobs 並列に処理する数 (-j)
+	// 2以上の場合、入力がシーク可能なファイルであれば行単位のチャンクに分けて並列に処理する
+	// 出力は逐次処理と同じ順番になる。標準入力などシークできない入力は逐次処理する
+	Jobs int
+	// Missing 指定したフィールドが存在しない行の扱い (--lenient, --strict, --skip)
+	Missing MissingPolicy
+	// GroupBy 切り出したフィールドをキーにしてグループごとに集計する (--group-by)
+	// 出力はキーのフィールドにAggregationsの結果を続けたもので、キーの昇順に並ぶ
+	// CutFilesで複数の入力を指定した場合はすべての入力をまとめて集計する
+	GroupBy bool
+	// Aggregations グループごとに求める集計 (--agg)
+	// 空の場合はcountだけを求める。空の値は行数を数えるcount以外の集計では無視する
+	Aggregations []Aggregation
+	// MemoryLimit 集計に使うメモリの目安 (バイト, --memory-limit)
+	// グループが多く超えそうな場合は一時ファイルに書き出す。0の場合は64MiB
+	MemoryLimit int
+	// TempDir 集計の一時ファイルを作るディレクトリ。空の場合はos.TempDir()
+	TempDir string
+	// Describe 切り出したフィールドごとに型、空の値の数、異なる値の数、最小値と最大値、上位の値を集計する (--describe)
+	// 入力は1回だけ読み込み、使うメモリはカラムの数に比例する。出力形式はtext, json, ndjsonのいずれか
+	Describe bool
+	// TopN Describeで出力する上位の値の数 (--top)。0の場合はDefaultTopN
+	TopN int
+}
+
+// Validate オプションの組み合わせが正しいかをチェックする
+func (o Options) Validate() error {
+	var lists int
+	for _, specified := range []bool{len(o.Fields) > 0, len(o.Names) > 0, len(o.Bytes) > 0, len(o.Characters) > 0} {
+		if specified {
+			lists++
+		}
+	}
+	switch {
+	case lists == 0:
+		return fmt.Errorf("-b, -c, -f, -Fのいずれかを指定してください")
+	case lists > 1:
+		return fmt.Errorf("-b, -c, -f, -Fは同時に指定できません")
+	}
+
+	if o.WithFilename {
+		switch o.Format {
+		case FormatJSON, FormatNDJSON, FormatMarkdown:
+			return fmt.Errorf("--with-filenameはjson, ndjson, markdownの出力形式と一緒に指定できません")
+		}
+	}
+	if o.Jobs < 0 {
+		return fmt.Errorf("並列数には0以上を指定してください")
+	}
+	if o.MaxLineLength < 0 {
+		return fmt.Errorf("行の最大長には0以上を指定してください")
+	}
+	if o.MemoryLimit < 0 {
+		return fmt.Errorf("集計のメモリの上限には0以上を指定してください")
+	}
+	if len(o.Aggregations) > 0 && !o.GroupBy {
+		return fmt.Errorf("--aggは--group-byと一緒に指定してください")
+	}
+	if o.GroupBy && o.WithFilename {
+		return fmt.Errorf("--group-byと--with-filenameは同時に指定できません")
+	}
+	if o.TopN < 0 {
+		return fmt.Errorf("--topには0以上を指定してください")
+	}
+	if o.Describe {
+		switch {
+		case o.GroupBy:
+			return fmt.Errorf("--describeと--group-byは同時に指定できません")
+		case o.WithFilename:
+			return fmt.Errorf("--describeと--with-filenameは同時に指定できません")
+		}
+		switch o.Format {
+		case FormatText, FormatJSON, FormatNDJSON:
+		default:
+			return fmt.Errorf("--describeの出力形式にはtext, json, ndjsonのいずれかを指定してください")
+		}
+	}
+	if o.NoSplitMultibyte && len(o.Bytes) == 0 {
+		return fmt.Errorf("-nは-bと一緒に指定してください")
+	}
+	if !o.fieldMode() {
+		if o.OnlyDelimited {
+			return fmt.Errorf("-sは-fと一緒に指定してください")
+		}
+		if o.Header {
+			return fmt.Errorf("--headerは-fまたは-Fと一緒に指定してください")
+		}
+		if o.Format != FormatText {
+			return fmt.Errorf("--formatは-fまたは-Fと一緒に指定してください")
+		}
+		if o.Where != nil {
+			return fmt.Errorf("--whereは-fまたは-Fと一緒に指定してください")
+		}
+		if o.GroupBy {
+			return fmt.Errorf("--group-byは-fまたは-Fと一緒に指定してください")
+		}
+		if o.Describe {
+			return fmt.Errorf("--describeは-fまたは-Fと一緒に指定してください")
+		}
+		if o.RegexDelimiter != nil || o.Whitespace || o.Trim {
+			return fmt.Errorf("--regex-delimiter, --whitespace, --trimは-fまたは-Fと一緒に指定してください")
+		}
+		return nil
+	}
+	if len(o.Names) > 0 && !o.Header {
+		return fmt.Errorf("-Fは--headerと一緒に指定してください")
+	}
+	if o.Where != nil && len(o.Where.Names()) > 0 && !o.Header {
+		return fmt.Errorf("--whereでカラム名を使う場合は--headerと一緒に指定してください")
+	}
+	for _, agg := range o.Aggregations {
+		if _, ok := agg.number(); !ok && agg.Column != "" && !o.Header {
+			return fmt.Errorf("--aggでカラム名を使う場合は--headerと一緒に指定してください")
+		}
+	}
+	for _, name := range o.Names {
+		if _, err := path.Match(name, ""); err != nil {
+			return fmt.Errorf("不正なカラム名のパターンです: %q", name)
+		}
+	}
+	switch {
+	case o.RegexDelimiter != nil && o.Whitespace:
+		return fmt.Errorf("--regex-delimiterと--whitespaceは同時に指定できません")
+	case o.RegexDelimiter != nil || o.Whitespace:
+		if o.CSV {
+			return fmt.Errorf("--csvは--regex-delimiter, --whitespaceと一緒に指定できません")
+		}
+	case o.Delimiter == "":
+		return fmt.Errorf("区切り文字を指定してください")
+	}
+	if o.CSV && o.RecordSeparator != "" {
+		return fmt.Errorf("--csvは-z, --record-separatorと一緒に指定できません")
+	}
+	if o.CSV {
+		if utf8.RuneCountInString(o.Delimiter) != 1 || strings.ContainsAny(o.Delimiter, "\"\r\n") {
+			return fmt.Errorf("--csvの区切り文字は改行とダブルクォート以外の1文字を指定してください")
+		}
+	} else if o.LazyQuotes {
+		return fmt.Errorf("--lazy-quotesは--csvと一緒に指定してください")
+	}
+	return nil
+}
+
+// fieldMode 区切り文字でフィールドを切り出すモードか
+func (o Options) fieldMode() bool {
+	return len(o.Fields) > 0 || len(o.Names) > 0
+}
+
+func (o Options) outputDelimiter() string {
+	if o.OutputDelimiter != "" || !o.fieldMode() {
+		return o.OutputDelimiter
+	}
+	if o.RegexDelimiter != nil || o.Whitespace {
+		// 入力の区切り文字が一定でないので、awkと同様に空白1文字で区切る
+		return " "
+	}
+	return o.Delimiter
+}
+
+// Cut rから1行ずつ読み込み、optsに従って切り出した結果をwに書き出す
+func Cut(r io.Reader, w io.Writer, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	c := newCutter(opts)
+	if c.summary = newSummarizer(opts); c.summary != nil {
+		defer c.summary.close()
+	}
+	if err := c.cut(r, w); err != nil {
+		return err
+	}
+	if c.summary != nil {
+		return c.summary.write(w)
+	}
+	return nil
+}
+
+// cut rから最後まで読み込み、切り出した結果をwに書き出す
+// optsはValidateでチェック済みであること
+func (c *cutter) cut(r io.Reader, w io.Writer) error {
+	opts := c.opts
+	if opts.Jobs > 1 && opts.parallelizable() {
+		if ra, start, end, ok := seekableRange(r); ok {
+			return c.cutParallel(ra, start, end, w, opts.Jobs, parallelChunkSize)
+		}
+	}
+
+	writer := bufio.NewWriter(w)
+	if err := c.run(newLineReader(r, opts.MaxLineLength, opts.RecordSeparator), writer); err != nil {
+		writer.Flush()
+		return err
+	}
+	if c.summary == nil && !c.sharedOut {
+		// 集計結果はすべての入力を読み込んでからsummarizer.writeで書き出す
+		// 複数の入力で共有するoutはCutFilesが最後に閉じる
+		c.out.close(writer)
+	}
+	return writer.Flush()
+}
+
+// run linesから最後まで読み込み、切り出した結果をwに書き出す
+func (c *cutter) run(lines *lineReader, w *bufio.Writer) error {
+	return c.scan(lines, func(record string, lineNo int) error {
+		return c.cutLine(w, record, lineNo)
+	})
+}
+
+// scan linesから最後まで読み込み、1レコードずつfnに渡す
+// CSVで複数行にまたがるレコードは改行で連結し、lineNoはレコードが始まる行番号になる
+// 1行目を読み込んだ時点で出力の改行コードを決める
+func (c *cutter) scan(lines *lineReader, fn func(record string, lineNo int) error) error {
+	var (
+		startLine int
+		pending   []string // CSVで複数行にまたがるレコードの行
+	)
+	for {
+		line, err := lines.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if !c.eolFixed {
+			// Windowsで作られたファイルは出力も\r\nで終わるようにする
+			c.eolFixed = true
+			if lines.crlf {
+				c.eol = "\r\n"
+			}
+		}
+
+		if c.csv == nil {
+			err = fn(line, lines.lineNo)
+		} else {
+			if len(pending) == 0 {
+				startLine = lines.lineNo
+			}
+			pending = append(pending, line)
+			if !c.csv.scanLine(line) {
+				continue
+			}
+			err = fn(joinLines(pending), startLine)
+			pending = pending[:0]
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(pending) > 0 {
+		// クォートが閉じられないまま終わった場合はcsv.Readerにエラーを報告させる
+		return fn(joinLines(pending), startLine)
+	}
+	return nil
+}
+
+// summarizer 1行ずつ出力せず、すべての入力を読み込んでから結果を書き出すモード (--group-by, --describe)
+type summarizer interface {
+	// add 切り出したレコードを加える。c.keys, c.valuesに選択されたフィールドが入っている
+	add(c *cutter, fields []string, lineNo int) error
+	// write 結果をwに書き出す
+	write(w io.Writer) error
+	// close 一時ファイルなどを片付ける
+	close()
+}
+
+// newSummarizer optsがsummarizerを使うモードであれば作る。そうでなければnilを返す
+func newSummarizer(opts Options) summarizer {
+	switch {
+	case opts.GroupBy:
+		return newGrouper(opts)
+	case opts.Describe:
+		return newProfiler(opts)
+	}
+	return nil
+}
+
+// cutter 1行ごとの切り出しを行う
+type cutter struct {
+	opts         Options
+	outDelimiter string
+	minFields    int
+	// file 読み込んでいるファイル名 (CutFilesの場合のみ)
+	file string
+	// prefix 出力する各行の先頭に付ける文字列 (--with-filename)
+	prefix string
+	// skipped --skipで読み飛ばした行
+	skipped skipSummary
+	// eol 出力する行末
+	eol string
+	// eolFixed eolが決まっているか (改行で区切る場合は1行目を読むまで決まらない)
+	eolFixed bool
+	csv      *csvSplitter
+	out      recordWriter
+	// sharedOut outを複数の入力で共有しているか (CutFilesでJSONやMarkdownを出力する場合)
+	sharedOut bool
+	// fields, keys, values 1レコード分の分割と出力に使い回すバッファ
+	fields  []string
+	keys    []string
+	values  []string
+	indexes []int
+	// needKeys 出力にフィールドの名前を使うか (JSONやMarkdownの場合のみ)
+	needKeys bool
+
+	// header --headerで読み込んだカラム名
+	header []string
+	// columns -Fで指定されたカラムのインデックス (指定した順番)
+	columns []int
+	// selected columnsに含まれるインデックス (--complementで使う)
+	selected map[int]bool
+	// whereColumns --whereのカラム名のインデックス
+	whereColumns []int
+
+	// summary --group-by, --describeで集計するsummarizer (複数の入力で共有する)
+	summary summarizer
+	// aggColumns 集計するフィールドのインデックス (行数を数えるcountは-1)
+	aggColumns []int
+}
+
+func newCutter(opts Options) *cutter {
+	c := &cutter{
+		opts:         opts,
+		outDelimiter: opts.outputDelimiter(),
+		minFields:    opts.Fields.MinFields(),
+		eol:          "\n",
+	}
+	if opts.RecordSeparator != "" {
+		c.eol = opts.RecordSeparator
+		c.eolFixed = true
+	}
+	if opts.CSV {
+		comma, _ := utf8.DecodeRuneInString(opts.Delimiter)
+		c.csv = newCSVSplitter(comma, opts.LazyQuotes)
+	}
+	c.out = newRecordWriter(c)
+	switch opts.Format {
+	case FormatJSON, FormatNDJSON, FormatMarkdown:
+		c.needKeys = true
+	}
+	if opts.GroupBy {
+		// 集計結果の見出しにキーの名前を使う
+		c.needKeys = true
+		// カラム名で指定したものはヘッダを読み込んでから変換するので、ここではエラーにならない
+		c.bindAggregations(nil)
+	}
+	if opts.Describe {
+		// 集計結果の見出しにカラムの名前を使う
+		c.needKeys = true
+	}
+	return c
+}
+
+// joinLines CSVの複数行にまたがるレコードの行を連結する
+func joinLines(lines []string) string {
+	if len(lines) == 1 {
+		return lines[0]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cutLine 1レコードを切り出す
+// lineNoはレコードが始まる行番号 (1始まり)
+func (c *cutter) cutLine(w *bufio.Writer, line string, lineNo int) error {
+	switch {
+	case len(c.opts.Bytes) > 0:
+		c.writeBytes(w, line)
+		return nil
+	case len(c.opts.Characters) > 0:
+		c.writeCharacters(w, line)
+		return nil
+	}
+
+	fields, err := c.split(line, lineNo)
+	if err != nil {
+		return err
+	}
+	if c.opts.Header && c.header == nil {
+		if err := c.readHeader(fields); err != nil {
+			return err
+		}
+		if c.summary != nil {
+			// 集計結果の見出しは最後にまとめて書き出す
+			return nil
+		}
+		if c.opts.Format != FormatText {
+			// テキスト以外の形式ではヘッダはキーや見出しとして使う
+			c.selectFields(fields)
+			w.WriteString(c.prefix)
+			c.out.writeHeader(w, c.values)
+			return nil
+		}
+	} else if c.opts.Where != nil && !c.opts.Where.match(fields, c.whereColumns) {
+		return nil
+	}
+	return c.writeFields(w, line, fields, lineNo)
+}
+
+// split レコードをフィールドに分割する
+// 区切り文字を含まない場合は1要素のスライスを返す
+func (c *cutter) split(line string, lineNo int) ([]string, error) {
+	fields, err := c.splitFields(line, lineNo)
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.Trim {
+		for i, field := range fields {
+			fields[i] = strings.TrimSpace(field)
+		}
+	}
+	return fields, nil
+}
+
+func (c *cutter) splitFields(line string, lineNo int) ([]string, error) {
+	switch {
+	case c.csv != nil:
+		if line == "" {
+			return []string{""}, nil
+		}
+		return c.csv.split(line, lineNo, strings.Count(line, "\n")+1)
+	case c.opts.RegexDelimiter != nil:
+		return c.opts.RegexDelimiter.Split(line, -1), nil
+	case c.opts.Whitespace:
+		c.fields = splitWhitespace(c.fields[:0], line)
+		return c.fields, nil
+	}
+	// 行ごとにstrings.Splitでスライスを確保しないようバッファを使い回す
+	c.fields = splitInto(c.fields[:0], line, c.opts.Delimiter)
+	return c.fields, nil
+}
+
+// splitInto sをsepで分割してdstに追加する
+// strings.Splitと同じ結果になるが、dstの容量が足りていればメモリを確保しない
+func splitInto(dst []string, s, sep string) []string {
+	for {
+		i := strings.Index(s, sep)
+		if i < 0 {
+			return append(dst, s)
+		}
+		dst = append(dst, s[:i])
+		s = s[i+len(sep):]
+	}
+}
+
+// splitWhitespace sを連続する空白とタブで分割してdstに追加する
+// 行頭と行末の空白は無視し、空白だけの行は空文字列1つのフィールドになる
+func splitWhitespace(dst []string, s string) []string {
+	start := len(dst)
+	for i := 0; i < len(s); {
+		if s[i] == ' ' || s[i] == '\t' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(s) && s[j] != ' ' && s[j] != '\t' {
+			j++
+		}
+		dst = append(dst, s[i:j])
+		i = j
+	}
+	if len(dst) == start {
+		dst = append(dst, "")
+	}
+	return dst
+}
+
+func (c *cutter) writeFields(w *bufio.Writer, line string, fields []string, lineNo int) error {
+	opts := c.opts
+	if len(fields) == 1 {
+		if opts.OnlyDelimited {
+			return nil
+		}
+		if opts.Missing == MissingLenient && opts.Format == FormatText && c.summary == nil {
+			// 区切り文字を含まない行はGNU cutと同様にそのまま出力する
+			// テキスト以外の形式では1フィールドのレコードとして扱う
+			if opts.Trim {
+				line = strings.TrimSpace(line)
+			}
+			w.WriteString(c.prefix)
+			w.WriteString(line)
+			w.WriteString(c.eol)
+			return nil
+		}
+	}
+
+	if !opts.Complement && len(fields) < c.minFields {
+		switch opts.Missing {
+		case MissingStrict:
+			return &FieldCountError{File: c.file, Line: lineNo, Fields: len(fields), Want: c.minFields}
+		case MissingSkip:
+			c.skipped.add(lineNo)
+			return nil
+		}
+	}
+
+	c.selectFields(fields)
+	if c.summary != nil {
+		return c.summary.add(c, fields, lineNo)
+	}
+	w.WriteString(c.prefix)
+	c.out.writeRecord(w, c.keys, c.values)
+	return nil
+}
+
+// selectFields 出力するフィールドとその名前をc.keys, c.valuesに詰める
+func (c *cutter) selectFields(fields []string) {
+	c.keys = c.keys[:0]
+	c.values = c.values[:0]
+	c.indexes = c.appendIndexes(c.indexes[:0], len(fields))
+	for _, index := range c.indexes {
+		if c.needKeys {
+			c.keys = append(c.keys, c.key(index))
+		}
+		c.values = append(c.values, fields[index])
+	}
+}
+
+// readHeader ヘッダのカラム名を保持し、-Fのカラム名をインデックスに変換する
+func (c *cutter) readHeader(fields []string) error {
+	c.header = append(make([]string, 0, len(fields)), fields...)
+	if c.opts.Where != nil {
+		columns, err := c.opts.Where.bind(c.header)
+		if err != nil {
+			return err
+		}
+		c.whereColumns = columns
+	}
+	if c.opts.GroupBy {
+		if err := c.bindAggregations(c.header); err != nil {
+			return err
+		}
+	}
+	if len(c.opts.Names) == 0 {
+		return nil
+	}
+
+	columns, err := resolveNames(c.header, c.opts.Names)
+	if err != nil {
+		return err
+	}
+	c.columns = columns
+	c.selected = make(map[int]bool, len(columns))
+	for _, column := range columns {
+		c.selected[column] = true
+		if column+1 > c.minFields {
+			c.minFields = column + 1
+		}
+	}
+	return nil
+}
+
+// appendIndexes n個のフィールドから出力するフィールドのインデックスを出力順にindexesに追加する
+func (c *cutter) appendIndexes(indexes []int, n int) []int {
+	if len(c.opts.Names) == 0 {
+		return c.opts.Fields.appendIndexes(indexes, n, c.opts.Complement)
+	}
+
+	if c.opts.Complement {
+		for i := 0; i < n; i++ {
+			if !c.selected[i] {
+				indexes = append(indexes, i)
+			}
+		}
+		return indexes
+	}
+	for _, column := range c.columns {
+		if column < n {
+			indexes = append(indexes, column)
+		}
+	}
+	return indexes
+}
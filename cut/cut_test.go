@@ -0,0 +1,188 @@
+package cut
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParseList(t *testing.T, s string) List {
+	t.Helper()
+	list, err := ParseList(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func TestCut(t *testing.T) {
+	const input = "1,GoodAfternoon ,Illustrator,FALSE\n2,Hi,Gopher,TRUE\nno delimiter\n"
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "単体",
+			opts: Options{Delimiter: ",", Fields: mustParseList(t, "2")},
+			want: "GoodAfternoon \nHi\nno delimiter\n",
+		},
+		{
+			name: "カンマ区切りと範囲",
+			opts: Options{Delimiter: ",", Fields: mustParseList(t, "3-,1")},
+			want: "1,Illustrator,FALSE\n2,Gopher,TRUE\nno delimiter\n",
+		},
+		{
+			name: "complement",
+			opts: Options{Delimiter: ",", Fields: mustParseList(t, "2"), Complement: true},
+			want: "1,Illustrator,FALSE\n2,Gopher,TRUE\nno delimiter\n",
+		},
+		{
+			name: "output-delimiter",
+			opts: Options{Delimiter: ",", OutputDelimiter: "\t", Fields: mustParseList(t, "-2")},
+			want: "1\tGoodAfternoon \n2\tHi\nno delimiter\n",
+		},
+		{
+			name: "区切り文字を含む行のみ",
+			opts: Options{Delimiter: ",", Fields: mustParseList(t, "1"), OnlyDelimited: true},
+			want: "1\n2\n",
+		},
+		{
+			name: "存在しないフィールド",
+			opts: Options{Delimiter: ",", Fields: mustParseList(t, "5")},
+			want: "\n\nno delimiter\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			stdout := new(bytes.Buffer)
+			err := Cut(bytes.NewBufferString(input), stdout, tt.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+
+	t.Run("Strict", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		opts := Options{Delimiter: ",", Fields: mustParseList(t, "4"), Missing: MissingStrict}
+		err := Cut(bytes.NewBufferString(input), stdout, opts)
+		assert.True(t, errors.Is(err, ErrMissingField))
+		assert.Equal(t, &FieldCountError{Line: 3, Fields: 1, Want: 4}, err)
+		assert.EqualError(t, err, "3行目: -fの値に該当するデータがありません (フィールド数: 1, 必要なフィールド数: 4)")
+		assert.Equal(t, "FALSE\nTRUE\n", stdout.String())
+	})
+
+	t.Run("Skip", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		c := newCutter(Options{Delimiter: ",", Fields: mustParseList(t, "1,4"), Missing: MissingSkip})
+		err := c.cut(bytes.NewBufferString(input+"3,x\n4,a,b,c\n"), stdout)
+		assert.NoError(t, err)
+		assert.Equal(t, "1,FALSE\n2,TRUE\n4,c\n", stdout.String())
+		assert.Equal(t, "2行 (3, 4行目)", c.skipped.String())
+	})
+
+	t.Run("Lenient", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		opts := Options{Delimiter: ",", Fields: mustParseList(t, "4"), Missing: MissingLenient}
+		err := Cut(bytes.NewBufferString(input+"3,x\n"), stdout, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, "FALSE\nTRUE\nno delimiter\n\n", stdout.String())
+	})
+
+	t.Run("異常系", func(t *testing.T) {
+		t.Parallel()
+		err := Cut(bytes.NewBufferString(input), new(bytes.Buffer), Options{Delimiter: ","})
+		assert.Error(t, err)
+	})
+}
+
+func TestCut_Delimiter(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  Options
+		want  string
+	}{
+		{
+			name:  "正規表現",
+			input: "a, b;c\nd;;e\nno delimiter\n",
+			opts:  Options{Fields: mustParseList(t, "1-2"), RegexDelimiter: regexp.MustCompile(`\s*[,;]\s*`)},
+			want:  "a b\nd \nno delimiter\n",
+		},
+		{
+			name:  "正規表現と出力の区切り文字",
+			input: "2020-05-14T10:00:00\n",
+			opts:  Options{Fields: mustParseList(t, "1,4"), RegexDelimiter: regexp.MustCompile(`[-T:]`), OutputDelimiter: "/"},
+			want:  "2020/10\n",
+		},
+		{
+			name:  "空白",
+			input: "  PID TTY\t\tTIME CMD\n    1 ?    00:00:01   init\n\n   \nsingle  \n",
+			opts:  Options{Fields: mustParseList(t, "1,4"), Whitespace: true},
+			want:  "PID CMD\n1 init\n\n   \nsingle  \n",
+		},
+		{
+			name:  "空白 区切り文字を含む行のみ",
+			input: "a b\nsingle\n",
+			opts:  Options{Fields: mustParseList(t, "2"), Whitespace: true, OnlyDelimited: true},
+			want:  "b\n",
+		},
+		{
+			name:  "trim",
+			input: "1,GoodAfternoon ,Illustrator\n5,GoodEvening , Singer\n  no delimiter \n",
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "2-"), Trim: true},
+			want:  "GoodAfternoon,Illustrator\nGoodEvening,Singer\nno delimiter\n",
+		},
+		{
+			name:  "trim ヘッダとwhere",
+			input: " id , job \n1, Gopher\n2,Singer \n",
+			opts:  Options{Delimiter: ",", Header: true, Names: []string{"job"}, Trim: true, Where: mustParseWhere(t, `$job == "Singer"`)},
+			want:  "job\nSinger\n",
+		},
+		{
+			name:  "trim csv",
+			input: "\" a \", b\n",
+			opts:  Options{Delimiter: ",", CSV: true, Fields: mustParseList(t, "1-"), Trim: true},
+			want:  "a,b\n",
+		},
+		{
+			name:  "trim 全角空白",
+			input: "　太郎　,花子\n",
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "1"), Trim: true},
+			want:  "太郎\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			stdout := new(bytes.Buffer)
+			err := Cut(bytes.NewBufferString(tt.input), stdout, tt.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+
+	t.Run("異常系", func(t *testing.T) {
+		t.Parallel()
+		re := regexp.MustCompile(`,`)
+		for _, opts := range []Options{
+			{Fields: mustParseList(t, "1"), RegexDelimiter: re, Whitespace: true},
+			{Delimiter: ",", Fields: mustParseList(t, "1"), Whitespace: true, CSV: true},
+			{Characters: mustParseList(t, "1"), Trim: true},
+			{Bytes: mustParseList(t, "1"), Whitespace: true},
+		} {
+			err := Cut(bytes.NewBufferString("a,b\n"), new(bytes.Buffer), opts)
+			assert.Error(t, err)
+		}
+	})
+}
@@ -0,0 +1,428 @@
+package cut
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTopN --describeで出力する上位の値の数のデフォルト
+const DefaultTopN = 5
+
+// maxTrackedValues --describeで1つのカラムについて出現回数を数える値の最大数
+// これより多くの異なる値がある場合、上位の値と異なる値の数は推定値になる
+const maxTrackedValues = 1000
+
+// hllPrecision 異なる値の数を推定するHyperLogLogのレジスタ数 (2^hllPrecision)
+const hllPrecision = 12
+
+// nullValues 空の値と同じく値がないものとして数える値
+var nullValues = map[string]bool{"NULL": true, "null": true, `\N`: true}
+
+// dateLayouts dateとして判定する日付の形式
+var dateLayouts = []string{"2006-01-02", "2006/01/02", "2006-01-02 15:04:05", "2006/01/02 15:04:05", time.RFC3339}
+
+// profiler --describeで各カラムを1回の走査で集計する
+// 異なる値の数はHyperLogLog、上位の値はSpace-Savingで数えるので、使うメモリはカラムの数に比例する
+type profiler struct {
+	opts    Options
+	topN    int
+	rows    int64
+	columns []*columnProfile
+	// byIndex フィールドのインデックスからcolumnsへの対応
+	byIndex map[int]*columnProfile
+	eol     string
+}
+
+func newProfiler(opts Options) *profiler {
+	topN := opts.TopN
+	if topN == 0 {
+		topN = DefaultTopN
+	}
+	return &profiler{opts: opts, topN: topN, byIndex: make(map[int]*columnProfile), eol: "\n"}
+}
+
+func (p *profiler) add(c *cutter, fields []string, lineNo int) error {
+	p.eol = c.eol
+	p.rows++
+	for i, index := range c.indexes {
+		col, ok := p.byIndex[index]
+		if !ok {
+			col = newColumnProfile(c.keys[i])
+			// 前の行にこのカラムがなかった分は値がないものとして数える
+			col.rows = p.rows - 1
+			col.nulls = p.rows - 1
+			p.byIndex[index] = col
+			p.columns = append(p.columns, col)
+		}
+		col.add(c.values[i])
+	}
+	// この行にないカラムは値がないものとして数える
+	for _, col := range p.columns {
+		if col.rows < p.rows {
+			col.rows = p.rows
+			col.nulls++
+		}
+	}
+	return nil
+}
+
+func (p *profiler) close() {}
+
+// write 集計した結果を--formatに従ってtextかjsonで書き出す
+func (p *profiler) write(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	reports := make([]columnReport, len(p.columns))
+	for i, col := range p.columns {
+		reports[i] = col.report(p.rows, p.topN)
+	}
+	switch p.opts.Format {
+	case FormatJSON, FormatNDJSON:
+		lines := p.opts.Format == FormatNDJSON
+		if !lines {
+			writer.WriteByte('[')
+		}
+		for i, report := range reports {
+			if !lines {
+				if i > 0 {
+					writer.WriteByte(',')
+				}
+				writer.WriteString(p.eol)
+			}
+			b, err := json.Marshal(report)
+			if err != nil {
+				return err
+			}
+			writer.Write(b)
+			if lines {
+				writer.WriteString(p.eol)
+			}
+		}
+		if !lines {
+			if len(reports) > 0 {
+				writer.WriteString(p.eol)
+			}
+			writer.WriteByte(']')
+			writer.WriteString(p.eol)
+		}
+	default:
+		for i, report := range reports {
+			if i > 0 {
+				writer.WriteString(p.eol)
+			}
+			report.writeText(writer, p.eol)
+		}
+	}
+	return writer.Flush()
+}
+
+// columnReport 1つのカラムの集計結果
+type columnReport struct {
+	Column string `json:"column"`
+	Type   string `json:"type"`
+	Count  int64  `json:"count"`
+	Nulls  int64  `json:"nulls"`
+	// Distinct 空の値を除いた異なる値の数
+	Distinct int64 `json:"distinct"`
+	// Estimated DistinctとTopが推定値か
+	Estimated bool         `json:"estimated"`
+	Min       *string      `json:"min"`
+	Max       *string      `json:"max"`
+	Top       []valueCount `json:"top"`
+}
+
+// valueCount 値とその出現回数
+type valueCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+func (r *columnReport) writeText(w *bufio.Writer, eol string) {
+	approx := ""
+	if r.Estimated {
+		approx = "約"
+	}
+	fmt.Fprintf(w, "[%s]%s", r.Column, eol)
+	fmt.Fprintf(w, "  型: %s%s", r.Type, eol)
+	fmt.Fprintf(w, "  行数: %d%s", r.Count, eol)
+	fmt.Fprintf(w, "  空・NULL: %d%s", r.Nulls, eol)
+	fmt.Fprintf(w, "  異なる値: %s%d%s", approx, r.Distinct, eol)
+	if r.Min != nil {
+		fmt.Fprintf(w, "  最小値: %s%s", *r.Min, eol)
+		fmt.Fprintf(w, "  最大値: %s%s", *r.Max, eol)
+	}
+	if len(r.Top) > 0 {
+		top := make([]string, len(r.Top))
+		for i, v := range r.Top {
+			top[i] = fmt.Sprintf("%q (%s%d)", v.Value, approx, v.Count)
+		}
+		fmt.Fprintf(w, "  上位: %s%s", strings.Join(top, ", "), eol)
+	}
+}
+
+// columnProfile 1つのカラムの集計途中の値
+type columnProfile struct {
+	name  string
+	rows  int64
+	nulls int64
+	// ints, floats, bools, dates 空の値を除いて、それぞれの型として読めた値の数
+	ints, floats, bools, dates int64
+
+	// 型ごとの最小値と最大値 (元の文字列)
+	minStr, maxStr         string
+	minNum, maxNum         float64
+	minNumStr, maxNumStr   string
+	minDate, maxDate       time.Time
+	minDateStr, maxDateStr string
+
+	hll     hyperLogLog
+	counter spaceSaving
+}
+
+func newColumnProfile(name string) *columnProfile {
+	return &columnProfile{name: name, hll: newHyperLogLog(), counter: newSpaceSaving(maxTrackedValues)}
+}
+
+func (c *columnProfile) add(value string) {
+	first := c.values() == 0
+	c.rows++
+	if value == "" || nullValues[value] {
+		c.nulls++
+		return
+	}
+	if first || value < c.minStr {
+		c.minStr = value
+	}
+	if first || value > c.maxStr {
+		c.maxStr = value
+	}
+
+	trimmed := strings.TrimSpace(value)
+	if _, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+		c.ints++
+	}
+	if f, err := strconv.ParseFloat(trimmed, 64); err == nil && !math.IsNaN(f) {
+		if c.floats == 0 || f < c.minNum {
+			c.minNum, c.minNumStr = f, value
+		}
+		if c.floats == 0 || f > c.maxNum {
+			c.maxNum, c.maxNumStr = f, value
+		}
+		c.floats++
+	}
+	if strings.EqualFold(trimmed, "true") || strings.EqualFold(trimmed, "false") {
+		c.bools++
+	}
+	if t, ok := parseDate(trimmed); ok {
+		if c.dates == 0 || t.Before(c.minDate) {
+			c.minDate, c.minDateStr = t, value
+		}
+		if c.dates == 0 || t.After(c.maxDate) {
+			c.maxDate, c.maxDateStr = t, value
+		}
+		c.dates++
+	}
+
+	c.hll.add(value)
+	c.counter.add(value)
+}
+
+// values 空の値を除いた値の数
+func (c *columnProfile) values() int64 {
+	return c.rows - c.nulls
+}
+
+func parseDate(s string) (time.Time, bool) {
+	if len(s) < len("2006-01-02") || s[0] < '0' || s[0] > '9' {
+		return time.Time{}, false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// typ すべての値が読める型のうち、最も狭いものを返す
+func (c *columnProfile) typ() string {
+	n := c.values()
+	switch {
+	case n == 0:
+		return "string"
+	case c.ints == n:
+		return "int"
+	case c.floats == n:
+		return "float"
+	case c.bools == n:
+		return "bool"
+	case c.dates == n:
+		return "date"
+	}
+	return "string"
+}
+
+func (c *columnProfile) report(rows int64, topN int) columnReport {
+	r := columnReport{
+		Column:    c.name,
+		Type:      c.typ(),
+		Count:     rows,
+		Nulls:     c.nulls,
+		Estimated: c.counter.evicted,
+		Top:       c.counter.top(topN),
+	}
+	if c.counter.evicted {
+		r.Distinct = c.hll.estimate()
+	} else {
+		r.Distinct = int64(len(c.counter.items))
+	}
+	if c.values() > 0 {
+		min, max := c.minStr, c.maxStr
+		switch r.Type {
+		case "int", "float":
+			min, max = c.minNumStr, c.maxNumStr
+		case "date":
+			min, max = c.minDateStr, c.maxDateStr
+		}
+		r.Min, r.Max = &min, &max
+	}
+	return r
+}
+
+// hyperLogLog 異なる値の数を一定のメモリで推定する
+type hyperLogLog struct {
+	registers []uint8
+}
+
+func newHyperLogLog() hyperLogLog {
+	return hyperLogLog{registers: make([]uint8, 1<<hllPrecision)}
+}
+
+func (h *hyperLogLog) add(value string) {
+	f := fnv.New64a()
+	f.Write([]byte(value))
+	x := mix64(f.Sum64())
+	index := x >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+// mix64 FNVのハッシュ値の偏りをなくす (splitmix64の最後の処理)
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+func (h *hyperLogLog) estimate() int64 {
+	m := float64(len(h.registers))
+	var sum float64
+	zeros := 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		// 値が少ない場合はLinear Countingで補正する
+		e = m * math.Log(m/float64(zeros))
+	}
+	return int64(e + 0.5)
+}
+
+// spaceSaving 出現回数の多い値をSpace-Savingアルゴリズムで数える
+// capacity個までの値は正確に数え、それを超えたら最も少ない値を置き換える (evictedがtrueになる)
+type spaceSaving struct {
+	capacity int
+	items    map[string]*countItem
+	heap     countHeap
+	evicted  bool
+}
+
+type countItem struct {
+	value string
+	count int64
+	index int
+}
+
+func newSpaceSaving(capacity int) spaceSaving {
+	return spaceSaving{capacity: capacity, items: make(map[string]*countItem)}
+}
+
+func (s *spaceSaving) add(value string) {
+	if item, ok := s.items[value]; ok {
+		item.count++
+		heap.Fix(&s.heap, item.index)
+		return
+	}
+	if len(s.items) < s.capacity {
+		item := &countItem{value: string([]byte(value)), count: 1}
+		s.items[item.value] = item
+		heap.Push(&s.heap, item)
+		return
+	}
+	// 最も少ない値を置き換え、その回数を引き継ぐ
+	s.evicted = true
+	item := s.heap[0]
+	delete(s.items, item.value)
+	item.value = string([]byte(value))
+	item.count++
+	s.items[item.value] = item
+	heap.Fix(&s.heap, 0)
+}
+
+// top 出現回数の多い順にn個の値を返す。回数が同じ場合は値の順番にする
+func (s *spaceSaving) top(n int) []valueCount {
+	counts := make([]valueCount, 0, len(s.items))
+	for _, item := range s.items {
+		counts = append(counts, valueCount{Value: item.value, Count: item.count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// countHeap 回数が最も少ない値を先頭にするヒープ
+type countHeap []*countItem
+
+func (h countHeap) Len() int           { return len(h) }
+func (h countHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+func (h countHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *countHeap) Push(x interface{}) {
+	item := x.(*countItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+func (h *countHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,130 @@
+package cut
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCut_Describe(t *testing.T) {
+	const input = "id,price,active,date,name\n" +
+		"1,9.5,true,2020-01-02,gopher\n" +
+		"2,10,false,2020-01-10,gopher\n" +
+		"3,,TRUE,2019-12-31,doctor\n" +
+		"10,NULL,false,2020-02-01,\n"
+
+	t.Run("text", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		opts := Options{Delimiter: ",", Fields: mustParseList(t, "1-"), Header: true, Describe: true, TopN: 2}
+		err := Cut(strings.NewReader(input), stdout, opts)
+		assert.NoError(t, err)
+		want := "[id]\n  型: int\n  行数: 4\n  空・NULL: 0\n  異なる値: 4\n  最小値: 1\n  最大値: 10\n  上位: \"1\" (1), \"10\" (1)\n" +
+			"\n[price]\n  型: float\n  行数: 4\n  空・NULL: 2\n  異なる値: 2\n  最小値: 9.5\n  最大値: 10\n  上位: \"10\" (1), \"9.5\" (1)\n" +
+			"\n[active]\n  型: bool\n  行数: 4\n  空・NULL: 0\n  異なる値: 3\n  最小値: TRUE\n  最大値: true\n  上位: \"false\" (2), \"TRUE\" (1)\n" +
+			"\n[date]\n  型: date\n  行数: 4\n  空・NULL: 0\n  異なる値: 4\n  最小値: 2019-12-31\n  最大値: 2020-02-01\n  上位: \"2019-12-31\" (1), \"2020-01-02\" (1)\n" +
+			"\n[name]\n  型: string\n  行数: 4\n  空・NULL: 1\n  異なる値: 2\n  最小値: doctor\n  最大値: gopher\n  上位: \"gopher\" (2), \"doctor\" (1)\n"
+		assert.Equal(t, want, stdout.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		opts := Options{Delimiter: ",", Names: []string{"name", "id"}, Header: true, Describe: true, TopN: 1, Format: FormatJSON}
+		err := Cut(strings.NewReader(input), stdout, opts)
+		assert.NoError(t, err)
+		want := "[\n" +
+			`{"column":"name","type":"string","count":4,"nulls":1,"distinct":2,"estimated":false,"min":"doctor","max":"gopher","top":[{"value":"gopher","count":2}]},` + "\n" +
+			`{"column":"id","type":"int","count":4,"nulls":0,"distinct":4,"estimated":false,"min":"1","max":"10","top":[{"value":"1","count":1}]}` + "\n" +
+			"]\n"
+		assert.Equal(t, want, stdout.String())
+	})
+
+	t.Run("ndjson ヘッダなし 値がないカラム", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		opts := Options{Delimiter: ",", Fields: mustParseList(t, "2"), Describe: true, Format: FormatNDJSON}
+		err := Cut(strings.NewReader("a,\nb,\\N\n"), stdout, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"column":"2","type":"string","count":2,"nulls":2,"distinct":0,"estimated":false,"min":null,"max":null,"top":[]}`+"\n", stdout.String())
+	})
+
+	t.Run("フィールドが足りない行は値がないものとして数える", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		opts := Options{Delimiter: ",", Fields: mustParseList(t, "1-"), Describe: true, Format: FormatNDJSON, Missing: MissingLenient}
+		err := Cut(strings.NewReader("1\n2,x\n3\n"), stdout, opts)
+		assert.NoError(t, err)
+		lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+		if assert.Len(t, lines, 2) {
+			assert.Contains(t, lines[1], `"column":"2","type":"string","count":3,"nulls":2,"distinct":1`)
+		}
+	})
+
+	t.Run("入力が空", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		err := Cut(strings.NewReader(""), stdout, Options{Delimiter: ",", Fields: mustParseList(t, "1"), Describe: true, Format: FormatJSON})
+		assert.NoError(t, err)
+		assert.Equal(t, "[]\n", stdout.String())
+	})
+
+	t.Run("異常系", func(t *testing.T) {
+		t.Parallel()
+		tests := []Options{
+			{Bytes: mustParseList(t, "1"), Describe: true},
+			{Delimiter: ",", Fields: mustParseList(t, "1"), Describe: true, GroupBy: true},
+			{Delimiter: ",", Fields: mustParseList(t, "1"), Describe: true, WithFilename: true},
+			{Delimiter: ",", Fields: mustParseList(t, "1"), Describe: true, Format: FormatCSV},
+			{Delimiter: ",", Fields: mustParseList(t, "1"), Describe: true, TopN: -1},
+		}
+		for _, opts := range tests {
+			assert.Error(t, opts.Validate())
+		}
+	})
+}
+
+func TestCut_DescribeEstimate(t *testing.T) {
+	// 数える値の上限を超えると、異なる値の数と上位の値は推定値になる
+	var input strings.Builder
+	const distinct = 20000
+	for i := 0; i < distinct; i++ {
+		fmt.Fprintf(&input, "v%d\n", i)
+		if i%100 == 0 {
+			input.WriteString("hot\n")
+		}
+	}
+	stdout := new(bytes.Buffer)
+	opts := Options{Delimiter: ",", Fields: mustParseList(t, "1"), Describe: true, TopN: 1, Format: FormatNDJSON}
+	err := Cut(strings.NewReader(input.String()), stdout, opts)
+	assert.NoError(t, err)
+
+	var report columnReport
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	assert.True(t, report.Estimated)
+	assert.Equal(t, int64(distinct+distinct/100), report.Count)
+	assert.InEpsilon(t, distinct+1, report.Distinct, 0.05)
+	if assert.Len(t, report.Top, 1) {
+		assert.Equal(t, "hot", report.Top[0].Value)
+	}
+}
+
+func TestHyperLogLog(t *testing.T) {
+	for _, n := range []int{0, 1, 100, 1000, 100000} {
+		h := newHyperLogLog()
+		for i := 0; i < n; i++ {
+			h.add(fmt.Sprint(i))
+			// 同じ値は何度加えても数が変わらない
+			h.add(fmt.Sprint(i))
+		}
+		if n == 0 {
+			assert.Equal(t, int64(0), h.estimate())
+			continue
+		}
+		assert.InEpsilon(t, n, h.estimate(), 0.05, "n=%d", n)
+	}
+}
@@ -0,0 +1,153 @@
+package cut
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/apbgo/go-study-group/clock"
+)
+
+// DefaultPollInterval FollowFileで追記を確認する間隔のデフォルト
+const DefaultPollInterval = time.Second
+
+// FollowFile tail -fと同様に、nameのファイルの終わりに達しても追記されたデータを読み込み続ける (--follow)
+// intervalごとにファイルを確認し、0の場合はDefaultPollIntervalにする
+// ファイルが切り詰められた場合は先頭から、ログのローテーションで別のファイルに置き換えられた場合は
+// 新しいファイルを先頭から読み込む。ctxがキャンセルされると、読み込んだ分を書き出してnilを返す
+func FollowFile(ctx context.Context, name string, w, errW io.Writer, opts Options, interval time.Duration) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	return followFile(ctx, name, w, errW, opts, interval, clock.New())
+}
+
+func followFile(ctx context.Context, name string, w, errW io.Writer, opts Options, interval time.Duration, clk clock.Clock) error {
+	if name == StdinName {
+		return fmt.Errorf("--followには標準入力を指定できません")
+	}
+	if opts.GroupBy {
+		return fmt.Errorf("--followと--group-byは同時に指定できません")
+	}
+	if opts.Describe {
+		return fmt.Errorf("--followと--describeは同時に指定できません")
+	}
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, unwrapPathError(err))
+	}
+	r := &followReader{ctx: ctx, name: name, f: f, interval: interval, clock: clk}
+	defer func() { r.f.Close() }()
+
+	c := newCutter(opts)
+	c.file = name
+	if opts.WithFilename {
+		c.prefix = name + ":"
+	}
+	writer := bufio.NewWriter(w)
+	// 追記を待つ前に、それまでの出力を書き出しておく
+	r.idle = writer.Flush
+	err = c.run(newLineReader(r, opts.MaxLineLength, opts.RecordSeparator), writer)
+	if c.skipped.count > 0 {
+		fmt.Fprintf(errW, "%s: フィールドが足りない%sをスキップしました\n", name, c.skipped)
+	}
+	if err != nil {
+		writer.Flush()
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	c.out.close(writer)
+	return writer.Flush()
+}
+
+// followReader ファイルの終わりでio.EOFを返さずに追記を待つReader
+// ctxがキャンセルされた場合にだけio.EOFを返す
+type followReader struct {
+	ctx      context.Context
+	name     string
+	f        *os.File
+	offset   int64
+	interval time.Duration
+	// clock 追記を待つためのタイマー。テストでは時間を進めずに待ちを解除できるclock.Fakeにする
+	clock clock.Clock
+	// idle 追記を待つ前に呼び出す
+	idle func() error
+}
+
+func (r *followReader) Read(p []byte) (int, error) {
+	for {
+		if r.ctx.Err() != nil {
+			return 0, io.EOF
+		}
+		n, err := r.f.Read(p)
+		r.offset += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		reopened, err := r.reopen()
+		if err != nil {
+			return 0, err
+		}
+		if reopened {
+			continue
+		}
+		if r.idle != nil {
+			if err := r.idle(); err != nil {
+				return 0, err
+			}
+		}
+		select {
+		case <-r.ctx.Done():
+			return 0, io.EOF
+		case <-r.clock.After(r.interval):
+		}
+	}
+}
+
+// reopen ファイルの終わりで、切り詰められたりローテーションされたりしていないかを確認する
+// 先頭から読み直す場合はtrueを返す
+func (r *followReader) reopen() (bool, error) {
+	current, err := r.f.Stat()
+	if err != nil {
+		return false, err
+	}
+	if current.Size() < r.offset {
+		// copytruncateなどでファイルが切り詰められた
+		if _, err := r.f.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		r.offset = 0
+		return true, nil
+	}
+	if current.Size() > r.offset {
+		// 読み込んだ後に追記された
+		return true, nil
+	}
+
+	latest, err := os.Stat(r.name)
+	if err != nil {
+		// ローテーションで移動され、新しいファイルがまだ作られていない
+		return false, nil
+	}
+	if os.SameFile(current, latest) {
+		return false, nil
+	}
+	// 別のファイルに置き換えられた。古いファイルは最後まで読み込んでいる
+	f, err := os.Open(r.name)
+	if err != nil {
+		return false, nil
+	}
+	r.f.Close()
+	r.f = f
+	r.offset = 0
+	return true, nil
+}
@@ -0,0 +1,153 @@
+package cut
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apbgo/go-study-group/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockUntilWaiting followReaderが追記を待ち始めるまで待つ
+func blockUntilWaiting(t *testing.T, clk *clock.Fake) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		clk.BlockUntil(1)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("追記を待っていません")
+	}
+}
+
+// syncBuffer 別のゴルーチンから書き込まれる出力
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func appendFile(t *testing.T, name, s string) {
+	t.Helper()
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFollowFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cut")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.log")
+	appendFile(t, name, "1,start\n2,run")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clk := clock.NewFake(time.Time{})
+	stdout := new(syncBuffer)
+	stderr := new(syncBuffer)
+	done := make(chan error, 1)
+	go func() {
+		opts := Options{Delimiter: ",", Fields: mustParseList(t, "2")}
+		done <- followFile(ctx, name, stdout, stderr, opts, time.Second, clk)
+	}()
+
+	// 改行で終わっていない行は追記を待つ
+	blockUntilWaiting(t, clk)
+	assert.Equal(t, "start\n", stdout.String())
+
+	appendFile(t, name, "ning\n3,append\n")
+	clk.Advance(time.Second)
+	blockUntilWaiting(t, clk)
+	assert.Equal(t, "start\nrunning\nappend\n", stdout.String())
+
+	// 切り詰められた場合は先頭から読み直す
+	if err := os.Truncate(name, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, name, "4,trunc\n")
+	clk.Advance(time.Second)
+	blockUntilWaiting(t, clk)
+	assert.Equal(t, "start\nrunning\nappend\ntrunc\n", stdout.String())
+
+	// ローテーションで移動された場合は、古いファイルの残りを読んでから新しいファイルを読む
+	appendFile(t, name, "5,last\n")
+	if err := os.Rename(name, name+".1"); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(time.Second)
+	blockUntilWaiting(t, clk)
+	assert.Equal(t, "start\nrunning\nappend\ntrunc\nlast\n", stdout.String())
+
+	appendFile(t, name, "6,rotated\n")
+	clk.Advance(time.Second)
+	blockUntilWaiting(t, clk)
+	assert.Equal(t, "start\nrunning\nappend\ntrunc\nlast\nrotated\n", stdout.String())
+
+	// キャンセルすると改行で終わっていない行も書き出して終わる
+	appendFile(t, name, "7,partial")
+	clk.Advance(time.Second)
+	blockUntilWaiting(t, clk)
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("キャンセルしても終わりません")
+	}
+	assert.Equal(t, "start\nrunning\nappend\ntrunc\nlast\nrotated\npartial\n", stdout.String())
+	assert.Empty(t, stderr.String())
+}
+
+func TestFollowFile_Error(t *testing.T) {
+	opts := Options{Delimiter: ",", Fields: mustParseList(t, "1")}
+	ctx := context.Background()
+
+	err := FollowFile(ctx, "-", new(bytes.Buffer), new(bytes.Buffer), opts, 0)
+	assert.EqualError(t, err, "--followには標準入力を指定できません")
+
+	err = FollowFile(ctx, "testdata/missing.csv", new(bytes.Buffer), new(bytes.Buffer), opts, 0)
+	assert.EqualError(t, err, "testdata/missing.csv: no such file or directory")
+
+	opts.GroupBy = true
+	err = FollowFile(ctx, "testdata/sample.csv", new(bytes.Buffer), new(bytes.Buffer), opts, 0)
+	assert.EqualError(t, err, "--followと--group-byは同時に指定できません")
+
+	opts.GroupBy = false
+	opts.Describe = true
+	err = FollowFile(ctx, "testdata/sample.csv", new(bytes.Buffer), new(bytes.Buffer), opts, 0)
+	assert.EqualError(t, err, "--followと--describeは同時に指定できません")
+
+	// キャンセル済みであれば何も読み込まずに終わる
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = FollowFile(canceled, "testdata/sample.csv", new(bytes.Buffer), new(bytes.Buffer), Options{Delimiter: ",", Fields: mustParseList(t, "1")}, 0)
+	assert.NoError(t, err)
+}
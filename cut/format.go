@@ -0,0 +1,244 @@
+package cut
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Format 切り出したフィールドの出力形式
+type Format int
+
+const (
+	// FormatText 出力の区切り文字で連結して出力する (デフォルト)
+	FormatText Format = iota
+	// FormatCSV RFC 4180形式のCSVで出力する
+	FormatCSV
+	// FormatTSV タブ区切りで出力する。タブや改行は\t, \nにエスケープする
+	FormatTSV
+	// FormatJSON カラム名をキーにしたオブジェクトの配列で出力する
+	FormatJSON
+	// FormatNDJSON 1行に1つのJSONオブジェクトを出力する
+	FormatNDJSON
+	// FormatMarkdown Markdownのテーブルで出力する
+	FormatMarkdown
+)
+
+// ParseFormat --formatで指定された文字列をFormatに変換する
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "", "text":
+		return FormatText, nil
+	case "csv":
+		return FormatCSV, nil
+	case "tsv":
+		return FormatTSV, nil
+	case "json":
+		return FormatJSON, nil
+	case "ndjson":
+		return FormatNDJSON, nil
+	case "markdown":
+		return FormatMarkdown, nil
+	}
+	return 0, fmt.Errorf("不正な出力形式です: %q (text, csv, tsv, json, ndjson, markdownのいずれかを指定してください)", s)
+}
+
+// statefulOutput 出力形式がレコードの間で状態を持つか (JSONの配列とMarkdownのテーブル)
+func (o Options) statefulOutput() bool {
+	return o.Format == FormatJSON || o.Format == FormatMarkdown
+}
+
+// recordWriter 切り出したフィールドを出力形式に従って書き出す
+// 1レコードずつ書き出すので、入力全体をバッファリングすることはない
+type recordWriter interface {
+	// writeHeader --headerで読み込んだカラム名のうち選択されたものを書き出す
+	writeHeader(w *bufio.Writer, names []string)
+	// writeRecord 1レコードを書き出す
+	// keysはJSONのキーやテーブルの見出しに使う選択されたフィールドの名前
+	writeRecord(w *bufio.Writer, keys, values []string)
+	// close すべてのレコードを書き出した後の後処理を行う
+	close(w *bufio.Writer)
+}
+
+// newRecordWriter cの出力形式に従ったrecordWriterを作る
+// 行末は入力の改行コードを判定してから決まるので、c.eolを参照して書き出す
+func newRecordWriter(c *cutter) recordWriter {
+	switch c.opts.Format {
+	case FormatCSV:
+		delimiter := c.opts.OutputDelimiter
+		if delimiter == "" {
+			delimiter = ","
+		}
+		return &delimitedWriter{eol: &c.eol, delimiter: delimiter, quote: true, mode: c.opts.Quote}
+	case FormatTSV:
+		return &tsvWriter{eol: &c.eol}
+	case FormatJSON:
+		return &jsonWriter{eol: &c.eol}
+	case FormatNDJSON:
+		return &jsonWriter{eol: &c.eol, lines: true}
+	case FormatMarkdown:
+		return &markdownWriter{eol: &c.eol}
+	}
+	// --csvで読み込んだ場合は出力時にも必要に応じてクォートし直す
+	return &delimitedWriter{eol: &c.eol, delimiter: c.outDelimiter, quote: c.csv != nil, mode: c.opts.Quote}
+}
+
+// delimitedWriter 区切り文字で連結して書き出す
+type delimitedWriter struct {
+	eol       *string
+	delimiter string
+	quote     bool
+	mode      QuoteMode
+}
+
+func (d *delimitedWriter) writeHeader(w *bufio.Writer, names []string) {
+	d.writeRecord(w, nil, names)
+}
+
+func (d *delimitedWriter) writeRecord(w *bufio.Writer, _, values []string) {
+	for i, value := range values {
+		if i > 0 {
+			w.WriteString(d.delimiter)
+		}
+		if d.quote {
+			writeCSVField(w, value, d.delimiter, d.mode)
+		} else {
+			w.WriteString(value)
+		}
+	}
+	w.WriteString(*d.eol)
+}
+
+func (d *delimitedWriter) close(w *bufio.Writer) {}
+
+// tsvWriter タブ区切りで書き出す
+type tsvWriter struct {
+	eol *string
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func (t *tsvWriter) writeHeader(w *bufio.Writer, names []string) {
+	t.writeRecord(w, nil, names)
+}
+
+func (t *tsvWriter) writeRecord(w *bufio.Writer, _, values []string) {
+	for i, value := range values {
+		if i > 0 {
+			w.WriteByte('\t')
+		}
+		tsvEscaper.WriteString(w, value)
+	}
+	w.WriteString(*t.eol)
+}
+
+func (t *tsvWriter) close(w *bufio.Writer) {}
+
+// jsonWriter JSONの配列またはNDJSONで書き出す
+// キーの順番を選択したフィールドの順番にするため、オブジェクトは自前で組み立てる
+type jsonWriter struct {
+	eol     *string
+	lines   bool
+	records int
+}
+
+func (j *jsonWriter) writeHeader(w *bufio.Writer, names []string) {}
+
+func (j *jsonWriter) writeRecord(w *bufio.Writer, keys, values []string) {
+	if !j.lines {
+		if j.records == 0 {
+			w.WriteByte('[')
+		} else {
+			w.WriteByte(',')
+		}
+		w.WriteString(*j.eol)
+	}
+	j.records++
+
+	w.WriteByte('{')
+	for i := range values {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		writeJSONString(w, keys[i])
+		w.WriteByte(':')
+		writeJSONString(w, values[i])
+	}
+	w.WriteByte('}')
+	if j.lines {
+		w.WriteString(*j.eol)
+	}
+}
+
+func (j *jsonWriter) close(w *bufio.Writer) {
+	if j.lines {
+		return
+	}
+	if j.records == 0 {
+		w.WriteString("[]")
+		w.WriteString(*j.eol)
+		return
+	}
+	w.WriteString(*j.eol)
+	w.WriteByte(']')
+	w.WriteString(*j.eol)
+}
+
+func writeJSONString(w *bufio.Writer, s string) {
+	// stringのMarshalは失敗しない
+	b, _ := json.Marshal(s)
+	w.Write(b)
+}
+
+// markdownWriter Markdownのテーブルで書き出す
+// --headerがない場合は最初のレコードのフィールド番号を見出しにする
+type markdownWriter struct {
+	eol           *string
+	headerWritten bool
+}
+
+var markdownEscaper = strings.NewReplacer(`|`, `\|`, "\r\n", "<br>", "\n", "<br>")
+
+func (m *markdownWriter) writeHeader(w *bufio.Writer, names []string) {
+	if m.headerWritten {
+		// 複数の入力で共有した場合は最初の入力の見出しだけを書き出す
+		return
+	}
+	m.writeRow(w, names)
+	w.WriteByte('|')
+	for range names {
+		w.WriteString(" --- |")
+	}
+	w.WriteString(*m.eol)
+	m.headerWritten = true
+}
+
+func (m *markdownWriter) writeRecord(w *bufio.Writer, keys, values []string) {
+	if !m.headerWritten {
+		m.writeHeader(w, keys)
+	}
+	m.writeRow(w, values)
+}
+
+func (m *markdownWriter) writeRow(w *bufio.Writer, values []string) {
+	w.WriteByte('|')
+	for _, value := range values {
+		w.WriteByte(' ')
+		markdownEscaper.WriteString(w, value)
+		w.WriteString(" |")
+	}
+	w.WriteString(*m.eol)
+}
+
+func (m *markdownWriter) close(w *bufio.Writer) {}
+
+// key index番目(0始まり)のフィールドの名前
+// ヘッダがあればカラム名、なければ1始まりのフィールド番号を返す
+func (c *cutter) key(index int) string {
+	if index < len(c.header) {
+		return c.header[index]
+	}
+	return strconv.Itoa(index + 1)
+}
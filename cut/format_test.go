@@ -0,0 +1,96 @@
+package cut
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCut_Format(t *testing.T) {
+	const input = "id,name,memo\n1,Gopher,\"Hello, Gopher\"\n2,Pipe,a|b\n"
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "json ヘッダをキーにする",
+			opts: Options{Delimiter: ",", CSV: true, Header: true, Names: []string{"name", "id"}, Format: FormatJSON},
+			want: "[\n{\"name\":\"Gopher\",\"id\":\"1\"},\n{\"name\":\"Pipe\",\"id\":\"2\"}\n]\n",
+		},
+		{
+			name: "ndjson ヘッダがなければフィールド番号をキーにする",
+			opts: Options{Delimiter: ",", CSV: true, Fields: mustParseList(t, "1,3"), Format: FormatNDJSON},
+			want: "{\"1\":\"id\",\"3\":\"memo\"}\n{\"1\":\"1\",\"3\":\"Hello, Gopher\"}\n{\"1\":\"2\",\"3\":\"a|b\"}\n",
+		},
+		{
+			name: "csv",
+			opts: Options{Delimiter: ",", CSV: true, Header: true, Fields: mustParseList(t, "2-3"), Format: FormatCSV},
+			want: "name,memo\nGopher,\"Hello, Gopher\"\nPipe,a|b\n",
+		},
+		{
+			name: "csv クォートしない入力でも出力はクォートする",
+			opts: Options{Delimiter: "|", Fields: mustParseList(t, "1-"), Format: FormatCSV},
+			want: "\"id,name,memo\"\n\"1,Gopher,\"\"Hello, Gopher\"\"\"\n\"2,Pipe,a\",b\n",
+		},
+		{
+			name: "tsv",
+			opts: Options{Delimiter: "|", Fields: mustParseList(t, "1-"), Format: FormatTSV},
+			want: "id,name,memo\n1,Gopher,\"Hello, Gopher\"\n2,Pipe,a\tb\n",
+		},
+		{
+			name: "markdown",
+			opts: Options{Delimiter: ",", CSV: true, Header: true, Names: []string{"id", "memo"}, Format: FormatMarkdown},
+			want: "| id | memo |\n| --- | --- |\n| 1 | Hello, Gopher |\n| 2 | a\\|b |\n",
+		},
+		{
+			name: "markdown ヘッダなし",
+			opts: Options{Delimiter: ",", CSV: true, Fields: mustParseList(t, "2"), Format: FormatMarkdown},
+			want: "| 2 |\n| --- |\n| name |\n| Gopher |\n| Pipe |\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			stdout := new(bytes.Buffer)
+			err := Cut(bytes.NewBufferString(input), stdout, tt.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+
+	t.Run("jsonとして読み込める", func(t *testing.T) {
+		t.Parallel()
+		input := "key,value\n\"quo\"\"te\",\"multi\nline\"\n"
+		opts := Options{Delimiter: ",", CSV: true, Header: true, Fields: mustParseList(t, "1-"), Format: FormatJSON}
+
+		stdout := new(bytes.Buffer)
+		err := Cut(bytes.NewBufferString(input), stdout, opts)
+		assert.NoError(t, err)
+
+		var got []map[string]string
+		assert.NoError(t, json.Unmarshal(stdout.Bytes(), &got))
+		assert.Equal(t, []map[string]string{{"key": "quo\"te", "value": "multi\nline"}}, got)
+	})
+
+	t.Run("空の入力", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		err := Cut(bytes.NewBufferString(""), stdout, Options{Delimiter: ",", Fields: mustParseList(t, "1"), Format: FormatJSON})
+		assert.NoError(t, err)
+		assert.Equal(t, "[]\n", stdout.String())
+	})
+
+	t.Run("異常系", func(t *testing.T) {
+		t.Parallel()
+		err := Cut(bytes.NewBufferString(input), new(bytes.Buffer), Options{Characters: mustParseList(t, "1"), Format: FormatJSON})
+		assert.Error(t, err)
+
+		_, err = ParseFormat("yaml")
+		assert.Error(t, err)
+	})
+}
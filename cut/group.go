@@ -0,0 +1,660 @@
+package cut
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AggFunc --aggで指定する集計関数
+type AggFunc int
+
+const (
+	// AggCount 行数を数える。カラムを指定した場合は空でない値の数
+	AggCount AggFunc = iota
+	// AggSum 数値の合計
+	AggSum
+	// AggMin 最小値。数値として読める値同士は数値として、それ以外は文字列として比較する
+	AggMin
+	// AggMax 最大値。比較の方法はAggMinと同じ
+	AggMax
+	// AggAvg 数値の平均
+	AggAvg
+	// AggDistinct 異なる値の数
+	AggDistinct
+)
+
+var aggFuncNames = map[string]AggFunc{
+	"count":    AggCount,
+	"sum":      AggSum,
+	"min":      AggMin,
+	"max":      AggMax,
+	"avg":      AggAvg,
+	"distinct": AggDistinct,
+}
+
+func (f AggFunc) String() string {
+	for name, fn := range aggFuncNames {
+		if fn == f {
+			return name
+		}
+	}
+	return "AggFunc(" + strconv.Itoa(int(f)) + ")"
+}
+
+// Aggregation --group-byでグループごとに求める集計
+type Aggregation struct {
+	Func AggFunc
+	// Column 集計するフィールド。1始まりのフィールド番号か、--headerのカラム名
+	// AggCountでは空にでき、その場合は行数を数える
+	Column string
+}
+
+// String "sum(price)"の形式で返す
+func (a Aggregation) String() string {
+	if a.Column == "" {
+		return a.Func.String()
+	}
+	return a.Func.String() + "(" + a.Column + ")"
+}
+
+// number Columnがフィールド番号の場合はその番号を返す
+func (a Aggregation) number() (int, bool) {
+	if !isDigits(a.Column) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(a.Column)
+	return n, err == nil
+}
+
+var aggregationPattern = regexp.MustCompile(`^([a-z]+)(?:\((.*)\))?$`)
+
+// ParseAggregations --aggで指定されたカンマ区切りの集計をパースする
+// count, sum(3), avg(price) のように関数名と括弧で囲んだフィールド番号かカラム名で指定する
+func ParseAggregations(s string) ([]Aggregation, error) {
+	if s == "" {
+		return nil, fmt.Errorf("集計が空です")
+	}
+	var aggs []Aggregation
+	for _, part := range strings.Split(s, ",") {
+		m := aggregationPattern.FindStringSubmatch(strings.TrimSpace(part))
+		if m == nil {
+			return nil, fmt.Errorf("不正な集計です: %q (count, sum(3), avg(price)のように指定してください)", part)
+		}
+		fn, ok := aggFuncNames[m[1]]
+		if !ok {
+			return nil, fmt.Errorf("不明な集計関数です: %s (count, sum, min, max, avg, distinctが使えます)", m[1])
+		}
+		agg := Aggregation{Func: fn, Column: strings.TrimSpace(m[2])}
+		if agg.Column == "" && fn != AggCount {
+			return nil, fmt.Errorf("%sには集計するフィールドを指定してください: %q", m[1], part)
+		}
+		if n, ok := agg.number(); ok && n < 1 {
+			return nil, fmt.Errorf("フィールド番号は1から始まります: %q", part)
+		}
+		aggs = append(aggs, agg)
+	}
+	return aggs, nil
+}
+
+// ParseSize 512K, 64M, 1Gのように単位を付けて指定されたバイト数をパースする
+// 単位がなければバイト数として扱う
+func ParseSize(s string) (int, error) {
+	unit := 1
+	digits := s
+	if s != "" {
+		switch strings.ToUpper(s[len(s)-1:]) {
+		case "K":
+			unit = 1 << 10
+		case "M":
+			unit = 1 << 20
+		case "G":
+			unit = 1 << 30
+		}
+		if unit > 1 {
+			digits = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("不正なサイズです: %q (512K, 64M, 1Gのように指定してください)", s)
+	}
+	return n * unit, nil
+}
+
+// defaultMemoryLimit Options.MemoryLimitが0の場合に集計で使うメモリの目安
+const defaultMemoryLimit = 64 << 20
+
+// maxMergeRuns 一度にマージする一時ファイルの最大数
+// これより多い場合は何回かに分けてマージし、同時に開くファイルの数を抑える
+const maxMergeRuns = 64
+
+// メモリ使用量の見積もりに使う、グループやマップの要素ごとのおおよそのオーバーヘッド
+const (
+	groupOverhead = 96
+	keyOverhead   = 16
+	aggOverhead   = 64
+	entryOverhead = 48
+)
+
+// group 1つのグループの集計途中の値
+// 一時ファイルにgobで書き出すのでフィールドはエクスポートしている
+type group struct {
+	Keys  []string
+	Count int64
+	Aggs  []aggState
+}
+
+// aggState 1つの集計の途中の値
+type aggState struct {
+	// N 集計した値の数 (空の値を除く)
+	N        int64
+	Sum      float64
+	Min, Max string
+	Distinct map[string]bool
+}
+
+// grouper --group-byでキーごとに集計する
+// グループはハッシュマップで集計し、見積もったメモリ使用量がlimitを超えたら
+// キーでソートして一時ファイルに書き出す。最後に一時ファイルをマージしながら出力するので、
+// 結果はキーの昇順になる
+type grouper struct {
+	opts   Options
+	aggs   []Aggregation
+	limit  int
+	groups map[string]*group
+	// size groupsのおおよそのメモリ使用量 (バイト)
+	size int
+	// runs 書き出した一時ファイル (それぞれキーの昇順)
+	runs []string
+	// buf キーを連結するバッファ (使い回す)
+	buf []byte
+
+	// names キーのフィールドの名前, labels 集計の名前 (出力の見出しやJSONのキーに使う)
+	names  []string
+	labels []string
+	// eol 出力する行末 (最後に読み込んだ入力に合わせる)
+	eol string
+}
+
+func newGrouper(opts Options) *grouper {
+	limit := opts.MemoryLimit
+	if limit == 0 {
+		limit = defaultMemoryLimit
+	}
+	return &grouper{
+		opts:   opts,
+		aggs:   opts.aggregations(),
+		limit:  limit,
+		groups: make(map[string]*group),
+		eol:    "\n",
+	}
+}
+
+// aggregations 集計の指定がなければcountだけを求める
+func (o Options) aggregations() []Aggregation {
+	if len(o.Aggregations) == 0 {
+		return []Aggregation{{Func: AggCount}}
+	}
+	return o.Aggregations
+}
+
+// add 切り出したレコードをグループに加える
+// キーはc.values、集計する値はfieldsのc.aggColumnsの位置から取り出す
+func (g *grouper) add(c *cutter, fields []string, lineNo int) error {
+	g.eol = c.eol
+	if len(c.keys) > len(g.names) {
+		for _, key := range c.keys[len(g.names):] {
+			g.names = append(g.names, key)
+		}
+	}
+	if g.labels == nil {
+		g.labels = c.aggLabels()
+	}
+
+	g.buf = g.buf[:0]
+	for _, value := range c.values {
+		g.buf = strconv.AppendInt(g.buf, int64(len(value)), 10)
+		g.buf = append(g.buf, ':')
+		g.buf = append(g.buf, value...)
+	}
+	gr, ok := g.groups[string(g.buf)]
+	if !ok {
+		// 入力の行を保持し続けないように、キーはコピーする
+		gr = &group{Keys: make([]string, len(c.values)), Aggs: make([]aggState, len(c.aggColumns))}
+		g.size += groupOverhead + len(g.buf) + aggOverhead*len(gr.Aggs)
+		for i, value := range c.values {
+			gr.Keys[i] = string([]byte(value))
+			g.size += keyOverhead + len(value)
+		}
+		g.groups[string(g.buf)] = gr
+	}
+
+	gr.Count++
+	for i, column := range c.aggColumns {
+		if column < 0 || column >= len(fields) || fields[column] == "" {
+			continue
+		}
+		growth, err := gr.Aggs[i].add(g.aggs[i].Func, fields[column])
+		if err != nil {
+			return fmt.Errorf("%d行目: %sの値が数値ではありません: %q", lineNo, c.aggLabels()[i], fields[column])
+		}
+		g.size += growth
+	}
+
+	if g.size > g.limit {
+		return g.spill()
+	}
+	return nil
+}
+
+// add 値を1つ集計し、増えたおおよそのメモリ使用量を返す
+func (a *aggState) add(fn AggFunc, value string) (int, error) {
+	switch fn {
+	case AggSum, AggAvg:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return 0, err
+		}
+		a.Sum += f
+	case AggMin, AggMax:
+		before := len(a.Min) + len(a.Max)
+		if a.N == 0 || compareValues(value, a.Min) < 0 {
+			a.Min = string([]byte(value))
+		}
+		if a.N == 0 || compareValues(value, a.Max) > 0 {
+			a.Max = string([]byte(value))
+		}
+		a.N++
+		return len(a.Min) + len(a.Max) - before, nil
+	case AggDistinct:
+		if a.Distinct == nil {
+			a.Distinct = make(map[string]bool)
+		}
+		if !a.Distinct[value] {
+			a.Distinct[string([]byte(value))] = true
+			a.N++
+			return entryOverhead + len(value), nil
+		}
+		return 0, nil
+	}
+	a.N++
+	return 0, nil
+}
+
+// merge 別に集計した途中の値をまとめる
+func (a *aggState) merge(fn AggFunc, other *aggState) {
+	switch fn {
+	case AggMin, AggMax:
+		if other.N > 0 {
+			if a.N == 0 || compareValues(other.Min, a.Min) < 0 {
+				a.Min = string([]byte(other.Min))
+			}
+			if a.N == 0 || compareValues(other.Max, a.Max) > 0 {
+				a.Max = string([]byte(other.Max))
+			}
+		}
+	case AggDistinct:
+		if a.Distinct == nil {
+			a.Distinct = make(map[string]bool, len(other.Distinct))
+		}
+		for value := range other.Distinct {
+			a.Distinct[value] = true
+		}
+		a.N = int64(len(a.Distinct))
+		return
+	}
+	a.Sum += other.Sum
+	a.N += other.N
+}
+
+// compareValues 両方が数値として読めれば数値として、それ以外は文字列として比較する
+func compareValues(l, r string) int {
+	if lf, rf, ok := numbers(l, r); ok {
+		switch {
+		case lf < rf:
+			return -1
+		case lf > rf:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(l, r)
+}
+
+// result 集計した結果を出力する文字列にする
+// countはグループの行数。値が1つもない場合、sumは0、avg, min, maxは空になる
+func (a *aggState) result(agg Aggregation, count int64) string {
+	switch agg.Func {
+	case AggCount:
+		if agg.Column == "" {
+			return strconv.FormatInt(count, 10)
+		}
+		return strconv.FormatInt(a.N, 10)
+	case AggSum:
+		return strconv.FormatFloat(a.Sum, 'f', -1, 64)
+	case AggAvg:
+		if a.N == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(a.Sum/float64(a.N), 'f', -1, 64)
+	case AggMin:
+		return a.Min
+	case AggMax:
+		return a.Max
+	}
+	return strconv.Itoa(len(a.Distinct))
+}
+
+// merge 同じキーのグループをまとめる
+func (g *grouper) merge(dst, src *group) {
+	dst.Count += src.Count
+	for i, agg := range g.aggs {
+		dst.Aggs[i].merge(agg.Func, &src.Aggs[i])
+	}
+}
+
+// sorted メモリ上のグループをキーの昇順に並べて返す
+func (g *grouper) sorted() []*group {
+	groups := make([]*group, 0, len(g.groups))
+	for _, gr := range g.groups {
+		groups = append(groups, gr)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return compareKeys(groups[i].Keys, groups[j].Keys) < 0
+	})
+	return groups
+}
+
+// compareKeys キーをフィールドごとに文字列として比較する
+func compareKeys(l, r []string) int {
+	for i := 0; i < len(l) && i < len(r); i++ {
+		if cmp := strings.Compare(l[i], r[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	return len(l) - len(r)
+}
+
+// spill メモリ上のグループをキーの昇順で一時ファイルに書き出し、メモリを空ける
+func (g *grouper) spill() error {
+	groups := g.sorted()
+	err := g.writeRun(func(emit func(*group) error) error {
+		for _, gr := range groups {
+			if err := emit(gr); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	g.groups = make(map[string]*group)
+	g.size = 0
+	return nil
+}
+
+// writeRun produceが渡すグループを新しい一時ファイルに書き出し、g.runsに加える
+func (g *grouper) writeRun(produce func(emit func(*group) error) error) error {
+	f, err := ioutil.TempFile(g.opts.TempDir, "go-cut-group-")
+	if err != nil {
+		return fmt.Errorf("集計の一時ファイルを作成できません: %w", err)
+	}
+	g.runs = append(g.runs, f.Name())
+
+	w := bufio.NewWriter(f)
+	enc := gob.NewEncoder(w)
+	err = produce(func(gr *group) error {
+		return enc.Encode(gr)
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("集計の一時ファイルに書き込めません: %w", err)
+	}
+	return nil
+}
+
+// close 一時ファイルを削除する
+func (g *grouper) close() {
+	for _, name := range g.runs {
+		os.Remove(name)
+	}
+	g.runs = nil
+}
+
+// write 集計した結果をキーの昇順にwに書き出す
+func (g *grouper) write(w io.Writer) error {
+	if g.labels == nil {
+		// 1行も集計していない場合
+		g.labels = newCutter(g.opts).aggLabels()
+	}
+
+	// 出力形式やエスケープは通常の切り出しと同じものを使う
+	c := newCutter(g.opts)
+	c.eol = g.eol
+	writer := bufio.NewWriter(w)
+	if g.opts.Header {
+		c.out.writeHeader(writer, g.columns(len(g.names)))
+	}
+
+	emit := func(gr *group) error {
+		keys := g.columns(len(gr.Keys))
+		values := append(make([]string, 0, len(keys)), gr.Keys...)
+		for i, agg := range g.aggs {
+			values = append(values, gr.Aggs[i].result(agg, gr.Count))
+		}
+		c.out.writeRecord(writer, keys, values)
+		return nil
+	}
+
+	var err error
+	if len(g.runs) == 0 {
+		for _, gr := range g.sorted() {
+			emit(gr)
+		}
+	} else {
+		err = g.mergeRuns(emit)
+	}
+	if err != nil {
+		writer.Flush()
+		return err
+	}
+	c.out.close(writer)
+	return writer.Flush()
+}
+
+// columns n個のキーと集計の名前
+func (g *grouper) columns(n int) []string {
+	names := make([]string, 0, n+len(g.labels))
+	for i := 0; i < n; i++ {
+		if i < len(g.names) {
+			names = append(names, g.names[i])
+		} else {
+			names = append(names, strconv.Itoa(i+1))
+		}
+	}
+	return append(names, g.labels...)
+}
+
+// mergeRuns 一時ファイルとメモリ上のグループをマージし、キーごとにまとめてemitに渡す
+func (g *grouper) mergeRuns(emit func(*group) error) error {
+	if len(g.groups) > 0 {
+		if err := g.spill(); err != nil {
+			return err
+		}
+	}
+	// 一度に開くファイルの数を抑えるため、多すぎる場合は先にいくつかずつマージしておく
+	for len(g.runs) > maxMergeRuns {
+		runs := g.runs[:maxMergeRuns]
+		g.runs = g.runs[maxMergeRuns:]
+		err := g.writeRun(func(emit func(*group) error) error {
+			return g.mergeFiles(runs, emit)
+		})
+		for _, name := range runs {
+			os.Remove(name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return g.mergeFiles(g.runs, emit)
+}
+
+// mergeFiles キーの昇順に並んだ一時ファイルをマージし、同じキーのグループをまとめてemitに渡す
+func (g *grouper) mergeFiles(names []string, emit func(*group) error) error {
+	var h runHeap
+	defer func() {
+		for _, r := range h {
+			r.f.Close()
+		}
+	}()
+	for _, name := range names {
+		r, err := openRun(name)
+		if err != nil {
+			return err
+		}
+		ok, err := r.next()
+		if err != nil {
+			r.f.Close()
+			return err
+		}
+		if ok {
+			h = append(h, r)
+		} else {
+			r.f.Close()
+		}
+	}
+	heap.Init(&h)
+
+	var current *group
+	for len(h) > 0 {
+		r := h[0]
+		gr := r.group
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+			r.f.Close()
+		}
+
+		if current != nil && compareKeys(current.Keys, gr.Keys) == 0 {
+			g.merge(current, gr)
+			continue
+		}
+		if current != nil {
+			if err := emit(current); err != nil {
+				return err
+			}
+		}
+		current = gr
+	}
+	if current != nil {
+		return emit(current)
+	}
+	return nil
+}
+
+// run 一時ファイルから順番にグループを読み込む
+type run struct {
+	f     *os.File
+	dec   *gob.Decoder
+	group *group
+}
+
+func openRun(name string) (*run, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("集計の一時ファイルを読み込めません: %w", err)
+	}
+	return &run{f: f, dec: gob.NewDecoder(bufio.NewReader(f))}, nil
+}
+
+// next 次のグループをr.groupに読み込む。ファイルの終わりではfalseを返す
+func (r *run) next() (bool, error) {
+	gr := new(group)
+	if err := r.dec.Decode(gr); err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, fmt.Errorf("集計の一時ファイルを読み込めません: %w", err)
+	}
+	r.group = gr
+	return true, nil
+}
+
+// runHeap 先頭のグループのキーが小さい順に一時ファイルを取り出すヒープ
+type runHeap []*run
+
+func (h runHeap) Len() int            { return len(h) }
+func (h runHeap) Less(i, j int) bool  { return compareKeys(h[i].group.Keys, h[j].group.Keys) < 0 }
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*run)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// bindAggregations 集計するフィールドのインデックスをc.aggColumnsに設定する
+// カラム名で指定したものはヘッダを読み込むまで-1のままにしておく
+func (c *cutter) bindAggregations(header []string) error {
+	aggs := c.opts.aggregations()
+	if c.aggColumns == nil {
+		c.aggColumns = make([]int, len(aggs))
+	}
+	for i, agg := range aggs {
+		c.aggColumns[i] = -1
+		if agg.Column == "" {
+			continue
+		}
+		if n, ok := agg.number(); ok {
+			c.aggColumns[i] = n - 1
+		} else if header != nil {
+			for j, name := range header {
+				if name == agg.Column {
+					c.aggColumns[i] = j
+					break
+				}
+			}
+			if c.aggColumns[i] < 0 {
+				return &ColumnNotFoundError{Name: agg.Column, Available: header}
+			}
+		}
+		if c.aggColumns[i]+1 > c.minFields {
+			c.minFields = c.aggColumns[i] + 1
+		}
+	}
+	return nil
+}
+
+// aggLabels 集計の名前を返す。フィールド番号で指定した場合も、ヘッダがあればカラム名にする
+func (c *cutter) aggLabels() []string {
+	aggs := c.opts.aggregations()
+	labels := make([]string, len(aggs))
+	for i, agg := range aggs {
+		if c.aggColumns != nil && c.aggColumns[i] >= 0 {
+			agg.Column = c.key(c.aggColumns[i])
+		}
+		labels[i] = agg.String()
+	}
+	return labels
+}
@@ -0,0 +1,269 @@
+package cut
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParseAggregations(t *testing.T, s string) []Aggregation {
+	t.Helper()
+	aggs, err := ParseAggregations(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aggs
+}
+
+func TestParseAggregations(t *testing.T) {
+	t.Run("正常系", func(t *testing.T) {
+		t.Parallel()
+		aggs, err := ParseAggregations("count, sum(3),avg(price),count(2),distinct(created at)")
+		assert.NoError(t, err)
+		assert.Equal(t, []Aggregation{
+			{Func: AggCount},
+			{Func: AggSum, Column: "3"},
+			{Func: AggAvg, Column: "price"},
+			{Func: AggCount, Column: "2"},
+			{Func: AggDistinct, Column: "created at"},
+		}, aggs)
+		assert.Equal(t, "avg(price)", aggs[2].String())
+	})
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "", want: "集計が空です"},
+		{input: "median(1)", want: "不明な集計関数です: median (count, sum, min, max, avg, distinctが使えます)"},
+		{input: "sum", want: `sumには集計するフィールドを指定してください: "sum"`},
+		{input: "max(0)", want: `フィールド番号は1から始まります: "max(0)"`},
+		{input: "count,", want: `不正な集計です: "" (count, sum(3), avg(price)のように指定してください)`},
+		{input: "sum(1", want: `不正な集計です: "sum(1" (count, sum(3), avg(price)のように指定してください)`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run("異常系 "+tt.input, func(t *testing.T) {
+			t.Parallel()
+			_, err := ParseAggregations(tt.input)
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
+
+func TestCut_GroupBy(t *testing.T) {
+	const header = "id,job,score,active\n"
+	const input = "1,Gopher,10,TRUE\n2,Doctor,7.5,FALSE\n3,Gopher,,TRUE\n4,Singer,3,TRUE\n5,Gopher,25,FALSE\n"
+
+	tests := []struct {
+		name  string
+		input string
+		opts  Options
+		want  string
+	}{
+		{
+			name:  "countだけ",
+			input: input,
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "2"), GroupBy: true},
+			want:  "Doctor,1\nGopher,3\nSinger,1\n",
+		},
+		{
+			name:  "複数のキーと集計",
+			input: input,
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "2,4"), GroupBy: true, Aggregations: mustParseAggregations(t, "count,count(3),sum(3),avg(3),min(3),max(3),distinct(1)")},
+			want:  "Doctor,FALSE,1,1,7.5,7.5,7.5,7.5,1\nGopher,FALSE,1,1,25,25,25,25,1\nGopher,TRUE,2,1,10,10,10,10,2\nSinger,TRUE,1,1,3,3,3,3,1\n",
+		},
+		{
+			name:  "値がない場合",
+			input: "a,\nb,1\nb\n",
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "1"), GroupBy: true, Aggregations: mustParseAggregations(t, "count,count(2),sum(2),avg(2),min(2),distinct(2)")},
+			want:  "a,1,0,0,,,0\nb,2,1,1,1,1,1\n",
+		},
+		{
+			name:  "minとmaxは数値として比較する",
+			input: "a,9\na,10\na,x\nb,9\nb,10\n",
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "1"), GroupBy: true, Aggregations: mustParseAggregations(t, "min(2),max(2)")},
+			want:  "a,9,x\nb,9,10\n",
+		},
+		{
+			name:  "ヘッダ カラム名で指定する",
+			input: header + input,
+			opts:  Options{Delimiter: ",", Header: true, Names: []string{"active"}, GroupBy: true, Aggregations: mustParseAggregations(t, "count,sum(score),max(1)")},
+			want:  "active,count,sum(score),max(id)\nFALSE,2,32.5,5\nTRUE,3,13,4\n",
+		},
+		{
+			name:  "where",
+			input: header + input,
+			opts:  Options{Delimiter: ",", Header: true, Names: []string{"job"}, GroupBy: true, Where: mustParseWhere(t, `$active == "TRUE"`)},
+			want:  "job,count\nGopher,2\nSinger,1\n",
+		},
+		{
+			name:  "json",
+			input: header + input,
+			opts:  Options{Delimiter: ",", Header: true, Names: []string{"active"}, GroupBy: true, Aggregations: mustParseAggregations(t, "count,avg(score)"), Format: FormatJSON},
+			want:  "[\n{\"active\":\"FALSE\",\"count\":\"2\",\"avg(score)\":\"16.25\"},\n{\"active\":\"TRUE\",\"count\":\"3\",\"avg(score)\":\"6.5\"}\n]\n",
+		},
+		{
+			name:  "ndjson ヘッダがなければフィールド番号をキーにする",
+			input: input,
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "4"), GroupBy: true, Aggregations: mustParseAggregations(t, "sum(3)"), Format: FormatNDJSON},
+			want:  "{\"4\":\"FALSE\",\"sum(3)\":\"32.5\"}\n{\"4\":\"TRUE\",\"sum(3)\":\"13\"}\n",
+		},
+		{
+			name:  "markdown",
+			input: input,
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "4"), GroupBy: true, Format: FormatMarkdown},
+			want:  "| 4 | count |\n| --- | --- |\n| FALSE | 2 |\n| TRUE | 3 |\n",
+		},
+		{
+			name:  "tsv",
+			input: "a\tb,1\na\tb,2\n",
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "1"), GroupBy: true, Aggregations: mustParseAggregations(t, "sum(2)"), Format: FormatTSV},
+			want:  "a\\tb\t3\n",
+		},
+		{
+			name:  "csv 改行コードは入力に合わせる",
+			input: "\"x,y\",1\r\n\"x,y\",2\r\n",
+			opts:  Options{Delimiter: ",", CSV: true, Fields: mustParseList(t, "1"), GroupBy: true, Format: FormatCSV},
+			want:  "\"x,y\",2\r\n",
+		},
+		{
+			name:  "区切り文字を含まない行も1つのキーとして集計する",
+			input: "a\na\nb,1\n",
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "1"), GroupBy: true},
+			want:  "a,2\nb,1\n",
+		},
+		{
+			name:  "skip",
+			input: "a,1\nb\na,2\n",
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "1"), GroupBy: true, Aggregations: mustParseAggregations(t, "sum(2)"), Missing: MissingSkip},
+			want:  "a,3\n",
+		},
+		{
+			name:  "入力が空",
+			input: "",
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "1"), GroupBy: true, Format: FormatJSON},
+			want:  "[]\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			stdout := new(bytes.Buffer)
+			err := Cut(strings.NewReader(tt.input), stdout, tt.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+
+	t.Run("異常系", func(t *testing.T) {
+		t.Parallel()
+		opts := Options{Delimiter: ",", Fields: mustParseList(t, "2"), GroupBy: true, Aggregations: mustParseAggregations(t, "sum(3)")}
+		err := Cut(strings.NewReader("1,a,1\n2,a,x\n"), new(bytes.Buffer), opts)
+		assert.EqualError(t, err, `2行目: sum(3)の値が数値ではありません: "x"`)
+
+		opts = Options{Delimiter: ",", Header: true, Fields: mustParseList(t, "2"), GroupBy: true, Aggregations: mustParseAggregations(t, "sum(missing)")}
+		err = Cut(strings.NewReader(header+input), new(bytes.Buffer), opts)
+		assert.EqualError(t, err, "カラムが見つかりません: \"missing\" (指定できるカラム: id, job, score, active)")
+
+		opts = Options{Delimiter: ",", Fields: mustParseList(t, "2"), GroupBy: true, Aggregations: mustParseAggregations(t, "sum(3)"), Missing: MissingStrict}
+		err = Cut(strings.NewReader("1,a,1\n2,a\n"), new(bytes.Buffer), opts)
+		assert.EqualError(t, err, "2行目: -fの値に該当するデータがありません (フィールド数: 2, 必要なフィールド数: 3)")
+	})
+
+	t.Run("オプションの組み合わせ", func(t *testing.T) {
+		t.Parallel()
+		tests := []Options{
+			{Delimiter: ",", Fields: mustParseList(t, "1"), Aggregations: mustParseAggregations(t, "count")},
+			{Delimiter: ",", Fields: mustParseList(t, "1"), GroupBy: true, Aggregations: mustParseAggregations(t, "sum(score)")},
+			{Delimiter: ",", Fields: mustParseList(t, "1"), GroupBy: true, WithFilename: true},
+			{Delimiter: ",", Fields: mustParseList(t, "1"), GroupBy: true, MemoryLimit: -1},
+			{Characters: mustParseList(t, "1"), GroupBy: true},
+		}
+		for _, opts := range tests {
+			assert.Error(t, opts.Validate())
+		}
+	})
+}
+
+func TestCut_GroupBySpill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cut")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 200個のグループを逆順に3回ずつ出現させる
+	var sb strings.Builder
+	for round := 0; round < 3; round++ {
+		for i := 200; i > 0; i-- {
+			fmt.Fprintf(&sb, "key%03d,%d,v%d\n", i, i*round, round)
+		}
+	}
+	input := sb.String()
+
+	base := Options{Delimiter: ",", Fields: mustParseList(t, "1"), GroupBy: true, Aggregations: mustParseAggregations(t, "count,sum(2),min(2),max(2),distinct(3)"), TempDir: dir}
+	want := new(bytes.Buffer)
+	err = Cut(strings.NewReader(input), want, base)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(want.String(), "key001,3,3,0,2,3\nkey002,3,6,0,4,3\n"))
+
+	// 上限を小さくすると何度も一時ファイルに書き出すが、結果は同じになる
+	// 1バイトの場合はグループごとに書き出すので、一時ファイルの数がmaxMergeRunsを超える
+	for _, limit := range []int{1, 4 << 10, 64 << 10} {
+		opts := base
+		opts.MemoryLimit = limit
+		c := newCutter(opts)
+		g := newGrouper(opts)
+		c.summary = g
+		err := c.cut(strings.NewReader(input), ioutil.Discard)
+		assert.NoError(t, err)
+		spilled := len(g.runs)
+
+		got := new(bytes.Buffer)
+		assert.NoError(t, g.write(got))
+		g.close()
+		assert.Equal(t, want.String(), got.String(), "limit=%d", limit)
+		if limit == 1 {
+			assert.True(t, spilled > maxMergeRuns, "spilled=%d", spilled)
+		} else {
+			assert.True(t, spilled > 0, "limit=%d", limit)
+		}
+	}
+
+	// 一時ファイルは残らない
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestCutFiles_GroupBy(t *testing.T) {
+	t.Run("すべての入力をまとめて集計する", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		stderr := new(bytes.Buffer)
+		opts := Options{Delimiter: ",", Header: true, Names: []string{"name"}, GroupBy: true, Aggregations: mustParseAggregations(t, "count,sum(id)")}
+		err := CutFiles([]string{"testdata/sample.csv", "-", "testdata/missing.csv"}, strings.NewReader("name,id\nGopher,3\n"), stdout, stderr, opts)
+		assert.Equal(t, ErrInputFailed, err)
+		assert.Equal(t, "name,count,sum(id)\nDoctor,1,2\nGopher,2,4\n", stdout.String())
+		assert.Equal(t, "testdata/missing.csv: no such file or directory\n", stderr.String())
+	})
+}
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int{"0": 0, "100": 100, "512K": 512 << 10, "64m": 64 << 20, "1G": 1 << 30} {
+		got, err := ParseSize(s)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, s)
+	}
+	for _, s := range []string{"", "M", "1.5M", "-1", "1T"} {
+		_, err := ParseSize(s)
+		assert.Error(t, err, s)
+	}
+}
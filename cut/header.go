@@ -0,0 +1,56 @@
+package cut
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ColumnNotFoundError -Fで指定したカラム名がヘッダに存在しない場合のエラー
+type ColumnNotFoundError struct {
+	// Name 見つからなかったカラム名 (パターン)
+	Name string
+	// Available ヘッダに存在するカラム名
+	Available []string
+}
+
+func (e *ColumnNotFoundError) Error() string {
+	return fmt.Sprintf("カラムが見つかりません: %q (指定できるカラム: %s)", e.Name, strings.Join(e.Available, ", "))
+}
+
+// ParseNames -Fで指定されたカンマ区切りのカラム名をパースする
+func ParseNames(s string) ([]string, error) {
+	if s == "" {
+		return nil, fmt.Errorf("カラム名が空です")
+	}
+	names := strings.Split(s, ",")
+	for _, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("空のカラム名が含まれています: %q", s)
+		}
+	}
+	return names, nil
+}
+
+// resolveNames カラム名(glob)をヘッダのインデックスに変換する
+// 戻り値はnamesで指定した順番になり、1つのglobに複数のカラムが一致した場合はヘッダの順番になる
+func resolveNames(header, names []string) ([]int, error) {
+	var columns []int
+	for _, name := range names {
+		matched := false
+		for i, column := range header {
+			ok, err := path.Match(name, column)
+			if err != nil {
+				return nil, fmt.Errorf("不正なカラム名のパターンです: %q", name)
+			}
+			if ok {
+				columns = append(columns, i)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, &ColumnNotFoundError{Name: name, Available: header}
+		}
+	}
+	return columns, nil
+}
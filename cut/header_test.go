@@ -0,0 +1,94 @@
+package cut
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCut_Header(t *testing.T) {
+	const input = "id,name,count,created_at,created_by\n1,Gopher,10,2020-03-05,hoge\n2,Doctor,20,2020-03-19,fuga\n"
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "カラム名で指定した順番に出力",
+			opts: Options{Delimiter: ",", Header: true, Names: []string{"count", "name"}},
+			want: "count,name\n10,Gopher\n20,Doctor\n",
+		},
+		{
+			name: "glob",
+			opts: Options{Delimiter: ",", Header: true, Names: []string{"id", "created_*"}},
+			want: "id,created_at,created_by\n1,2020-03-05,hoge\n2,2020-03-19,fuga\n",
+		},
+		{
+			name: "complement",
+			opts: Options{Delimiter: ",", Header: true, Names: []string{"created_*"}, Complement: true},
+			want: "id,name,count\n1,Gopher,10\n2,Doctor,20\n",
+		},
+		{
+			name: "フィールド番号との併用",
+			opts: Options{Delimiter: ",", Header: true, Fields: mustParseList(t, "2")},
+			want: "name\nGopher\nDoctor\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			stdout := new(bytes.Buffer)
+			err := Cut(bytes.NewBufferString(input), stdout, tt.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+
+	t.Run("CSVのヘッダ", func(t *testing.T) {
+		t.Parallel()
+		input := "\"user, id\",name\n1,\"Gopher, Jr.\"\n"
+		opts := Options{Delimiter: ",", Header: true, Names: []string{"name", "user, id"}, CSV: true}
+
+		stdout := new(bytes.Buffer)
+		err := Cut(bytes.NewBufferString(input), stdout, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, "name,\"user, id\"\n\"Gopher, Jr.\",1\n", stdout.String())
+	})
+
+	t.Run("存在しないカラム名", func(t *testing.T) {
+		t.Parallel()
+		opts := Options{Delimiter: ",", Header: true, Names: []string{"name", "updated_*"}}
+
+		err := Cut(bytes.NewBufferString(input), new(bytes.Buffer), opts)
+		assert.EqualError(t, err, `カラムが見つかりません: "updated_*" (指定できるカラム: id, name, count, created_at, created_by)`)
+		_, ok := err.(*ColumnNotFoundError)
+		assert.True(t, ok)
+	})
+
+	t.Run("異常系", func(t *testing.T) {
+		t.Parallel()
+		for _, opts := range []Options{
+			{Delimiter: ",", Names: []string{"name"}},
+			{Delimiter: ",", Header: true, Names: []string{"[name"}},
+			{Delimiter: ",", Header: true, Names: []string{"name"}, Fields: mustParseList(t, "1")},
+			{Header: true, Characters: mustParseList(t, "1")},
+		} {
+			err := Cut(bytes.NewBufferString(input), new(bytes.Buffer), opts)
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestParseNames(t *testing.T) {
+	names, err := ParseNames("name,created_*")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name", "created_*"}, names)
+
+	_, err = ParseNames("")
+	assert.Error(t, err)
+	_, err = ParseNames("name,,count")
+	assert.Error(t, err)
+}
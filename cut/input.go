@@ -0,0 +1,210 @@
+package cut
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// ErrInputFailed CutFilesで処理できなかった入力があった場合のエラー
+// 個々のエラーはその都度errWに書き出している
+var ErrInputFailed = errors.New("処理できなかった入力があります")
+
+// StdinName 標準入力を表すファイル名
+const StdinName = "-"
+
+// stdinLabel --with-filenameで標準入力の行の先頭に付ける名前
+const stdinLabel = "(standard input)"
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	// bzip2Magic "BZh"と圧縮レベル('1'から'9')の後にブロックのマジックナンバーが続く
+	// 空のファイルを圧縮した場合はブロックの代わりにストリームの終わりのマジックナンバーが続く
+	// "BZh"だけで判定すると、それで始まる普通のファイルを展開しようとしてしまう
+	bzip2Magic      = []byte("BZh")
+	bzip2BlockMagic = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	bzip2EOSMagic   = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+)
+
+// magicLen 圧縮形式を判定するために先頭から読み込むバイト数
+const magicLen = 10
+
+// CutFiles namesのファイルを順番に読み込み、optsに従って切り出した結果をwに書き出す
+// "-"はstdinから読み込み、namesが空の場合もstdinを読み込む
+// gzip, bzip2で圧縮されたファイルは先頭のマジックナンバーから判定して展開する
+//
+// GNU cutと同様に、読み込めないファイルがあってもエラーをerrWに書き出して残りのファイルを処理し、
+// 最後にErrInputFailedを返す。オプションが不正な場合はどのファイルも読み込まずにそのエラーを返す
+// --strictでフィールドが足りない行があった場合は、errWに書き出した後に残りのファイルを処理せずFieldCountErrorを返す
+// --skipで読み飛ばした行があれば、ファイルごとにその行数と行番号をerrWに書き出す
+// --group-byではすべての入力をまとめて集計し、最後に結果を書き出す
+// JSONとMarkdownではすべての入力を1つの配列やテーブルにまとめて書き出す
+func CutFiles(names []string, stdin io.Reader, w, errW io.Writer, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		names = []string{StdinName}
+	}
+
+	s := newSummarizer(opts)
+	if s != nil {
+		defer s.close()
+	}
+
+	var (
+		failed bool
+		// out 複数の入力で共有するrecordWriter (JSONとMarkdownの場合のみ)
+		out recordWriter
+	)
+	for _, name := range names {
+		if err := cutFile(name, stdin, w, errW, opts, s, &out); err != nil {
+			failed = true
+			fmt.Fprintf(errW, "%s: %v\n", name, unwrapPathError(err))
+			var fieldErr *FieldCountError
+			if opts.Missing == MissingStrict && errors.As(err, &fieldErr) {
+				// --strictでは残りの入力を処理せずに止める
+				return fieldErr
+			}
+		}
+	}
+	if s != nil {
+		// 読み込めなかった入力があっても、読み込めた分の集計結果は書き出す
+		if err := s.write(w); err != nil {
+			return err
+		}
+	} else if out != nil {
+		writer := bufio.NewWriter(w)
+		out.close(writer)
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+	if failed {
+		return ErrInputFailed
+	}
+	return nil
+}
+
+// cutFile nameのファイルを切り出してwに書き出す
+// 出力形式がレコードの間で状態を持つ場合は、最初のファイルのrecordWriterをoutに入れて次のファイルでも使う
+func cutFile(name string, stdin io.Reader, w, errW io.Writer, opts Options, s summarizer, out *recordWriter) error {
+	r, err := openInput(name, stdin)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	c := newCutter(opts)
+	c.file = name
+	c.summary = s
+	if opts.statefulOutput() {
+		if *out == nil {
+			*out = c.out
+		}
+		c.out = *out
+		c.sharedOut = true
+	}
+	if opts.WithFilename {
+		c.prefix = name + ":"
+		if name == StdinName {
+			c.prefix = stdinLabel + ":"
+		}
+	}
+	err = c.cut(r, w)
+	if c.skipped.count > 0 {
+		fmt.Fprintf(errW, "%s: フィールドが足りない%sをスキップしました\n", name, c.skipped)
+	}
+	return err
+}
+
+// openInput nameのファイルを開き、圧縮されていれば展開するReaderを返す
+// 圧縮されていないファイルはシーク可能なまま返すので、-jで並列に処理できる
+func openInput(name string, stdin io.Reader) (io.ReadCloser, error) {
+	var r io.Reader
+	var closer io.Closer
+	if name == StdinName {
+		// 標準入力は複数回指定されることがあるので閉じない
+		r, closer = stdin, ioutil.NopCloser(stdin)
+	} else {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		r, closer = f, f
+	}
+
+	magic := make([]byte, magicLen)
+	n, err := io.ReadFull(r, magic)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		closer.Close()
+		return nil, err
+	}
+	magic = magic[:n]
+	if seeker, ok := r.(io.Seeker); !ok || !seekBack(seeker, n) {
+		// 読み込んだ位置を戻せない場合は読み込んだ分を先頭に付け直す
+		r = io.MultiReader(bytes.NewReader(magic), r)
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(bufio.NewReader(r))
+		if err != nil {
+			closer.Close()
+			return nil, err
+		}
+		return &input{Reader: zr, closers: []io.Closer{zr, closer}}, nil
+	case isBzip2(magic):
+		return &input{Reader: bzip2.NewReader(bufio.NewReader(r)), closers: []io.Closer{closer}}, nil
+	}
+	if f, ok := r.(*os.File); ok && name != StdinName {
+		return f, nil
+	}
+	return &input{Reader: r, closers: []io.Closer{closer}}, nil
+}
+
+// isBzip2 magicがbzip2のヘッダか
+func isBzip2(magic []byte) bool {
+	return len(magic) == magicLen &&
+		bytes.HasPrefix(magic, bzip2Magic) &&
+		'1' <= magic[3] && magic[3] <= '9' &&
+		(bytes.Equal(magic[4:], bzip2BlockMagic) || bytes.Equal(magic[4:], bzip2EOSMagic))
+}
+
+// seekBack nバイト読み込んだ位置を戻す
+// パイプなどシークできない場合はfalseを返す
+func seekBack(s io.Seeker, n int) bool {
+	_, err := s.Seek(int64(-n), io.SeekCurrent)
+	return err == nil
+}
+
+// input 展開用のReaderと元のファイルをまとめて閉じる
+type input struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (i *input) Close() error {
+	var err error
+	for _, c := range i.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// unwrapPathError os.PathErrorのファイル名を取り除く
+// エラーを書き出すときに先頭にファイル名を付けるので、重複しないようにする
+func unwrapPathError(err error) error {
+	if pe, ok := err.(*os.PathError); ok {
+		return pe.Err
+	}
+	return err
+}
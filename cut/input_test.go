@@ -0,0 +1,230 @@
+package cut
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCutFiles(t *testing.T) {
+	const (
+		plain = "testdata/sample.csv"
+		gz    = "testdata/sample.csv.gz"
+		bz2   = "testdata/sample.csv.bz2"
+	)
+	opts := Options{Delimiter: ",", Fields: mustParseList(t, "2")}
+
+	tests := []struct {
+		name       string
+		names      []string
+		stdin      io.Reader
+		opts       Options
+		want       string
+		wantStderr string
+		wantErr    error
+	}{
+		{
+			name:  "複数のファイル",
+			names: []string{plain, plain},
+			opts:  opts,
+			want:  "name\nGopher\nDoctor\nname\nGopher\nDoctor\n",
+		},
+		{
+			name:  "gzipとbzip2を展開する",
+			names: []string{gz, bz2},
+			opts:  opts,
+			want:  "name\nGopher\nDoctor\nname\nGopher\nDoctor\n",
+		},
+		{
+			name:  "-は標準入力",
+			names: []string{"-", plain},
+			stdin: strings.NewReader("a,b\n"),
+			opts:  opts,
+			want:  "b\nname\nGopher\nDoctor\n",
+		},
+		{
+			name:  "ファイルを指定しなければ標準入力",
+			stdin: strings.NewReader("a,b\nc,d"),
+			opts:  opts,
+			want:  "b\nd\n",
+		},
+		{
+			name:  "シークできない標準入力のgzip",
+			names: []string{"-"},
+			stdin: pipeReader{r: gzipReader(t, "a,b\n")},
+			opts:  opts,
+			want:  "b\n",
+		},
+		{
+			name:  "空のbzip2",
+			names: []string{"-"},
+			stdin: bytes.NewReader([]byte("BZh9\x17\x72\x45\x38\x50\x90\x00\x00\x00\x00")),
+			opts:  opts,
+			want:  "",
+		},
+		{
+			name:  "with-filename",
+			names: []string{plain, "-"},
+			stdin: strings.NewReader("a,b\n"),
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "2"), WithFilename: true},
+			want:  plain + ":name\n" + plain + ":Gopher\n" + plain + ":Doctor\n(standard input):b\n",
+		},
+		{
+			name:  "with-filename ヘッダとCSV出力",
+			names: []string{gz},
+			opts:  Options{Delimiter: ",", Header: true, Names: []string{"id"}, Format: FormatCSV, WithFilename: true},
+			want:  gz + ":id\n" + gz + ":1\n" + gz + ":2\n",
+		},
+		{
+			name:  "JSONは複数のファイルを1つの配列にまとめる",
+			names: []string{plain, "testdata/missing.csv", gz},
+			opts:  Options{Delimiter: ",", Header: true, Fields: mustParseList(t, "1,2"), Format: FormatJSON},
+			want: "[\n" +
+				`{"id":"1","name":"Gopher"},` + "\n" +
+				`{"id":"2","name":"Doctor"},` + "\n" +
+				`{"id":"1","name":"Gopher"},` + "\n" +
+				`{"id":"2","name":"Doctor"}` + "\n]\n",
+			wantStderr: "testdata/missing.csv: no such file or directory\n",
+			wantErr:    ErrInputFailed,
+		},
+		{
+			name:  "Markdownは複数のファイルを1つのテーブルにまとめる",
+			names: []string{plain, bz2},
+			opts:  Options{Delimiter: ",", Header: true, Fields: mustParseList(t, "2"), Format: FormatMarkdown},
+			want:  "| name |\n| --- |\n| Gopher |\n| Doctor |\n| Gopher |\n| Doctor |\n",
+		},
+		{
+			name:       "読み込めないファイルがあっても残りを処理する",
+			names:      []string{"testdata/missing.csv", plain, "testdata"},
+			opts:       opts,
+			want:       "name\nGopher\nDoctor\n",
+			wantStderr: "testdata/missing.csv: no such file or directory\ntestdata: is a directory\n",
+			wantErr:    ErrInputFailed,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+			err := CutFiles(tt.names, tt.stdin, stdout, stderr, tt.opts)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, stdout.String())
+			assert.Equal(t, tt.wantStderr, stderr.String())
+		})
+	}
+
+	t.Run("壊れたgzip", func(t *testing.T) {
+		t.Parallel()
+		dir, err := ioutil.TempDir("", "cut")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		broken := filepath.Join(dir, "broken.gz")
+		if err := ioutil.WriteFile(broken, []byte{0x1f, 0x8b, 0x00}, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		stdout := new(bytes.Buffer)
+		stderr := new(bytes.Buffer)
+		err = CutFiles([]string{broken, plain}, nil, stdout, stderr, opts)
+		assert.Equal(t, ErrInputFailed, err)
+		assert.Equal(t, "name\nGopher\nDoctor\n", stdout.String())
+		assert.True(t, strings.HasPrefix(stderr.String(), broken+": "))
+	})
+
+	t.Run("BZhで始まる圧縮されていないファイル", func(t *testing.T) {
+		t.Parallel()
+		dir, err := ioutil.TempDir("", "cut")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		name := filepath.Join(dir, "bzh.csv")
+		if err := ioutil.WriteFile(name, []byte("BZh,1\nfoo,2\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		stdout := new(bytes.Buffer)
+		stderr := new(bytes.Buffer)
+		err = CutFiles([]string{name, "-"}, pipeReader{r: strings.NewReader("BZh9,3\n")}, stdout, stderr, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, "1\n2\n3\n", stdout.String())
+		assert.Empty(t, stderr.String())
+	})
+
+	t.Run("並列処理", func(t *testing.T) {
+		t.Parallel()
+		opts := opts
+		opts.Jobs = 4
+		opts.WithFilename = true
+		stdout := new(bytes.Buffer)
+		err := CutFiles([]string{plain}, nil, stdout, new(bytes.Buffer), opts)
+		assert.NoError(t, err)
+		assert.Equal(t, plain+":name\n"+plain+":Gopher\n"+plain+":Doctor\n", stdout.String())
+	})
+
+	t.Run("不正なオプションはファイルを読み込まない", func(t *testing.T) {
+		t.Parallel()
+		stderr := new(bytes.Buffer)
+		err := CutFiles([]string{"testdata/missing.csv"}, nil, new(bytes.Buffer), stderr, Options{})
+		assert.Error(t, err)
+		assert.NotEqual(t, ErrInputFailed, err)
+		assert.Empty(t, stderr.String())
+
+		err = CutFiles(nil, nil, new(bytes.Buffer), stderr, Options{Delimiter: ",", Fields: mustParseList(t, "1"), Format: FormatJSON, WithFilename: true})
+		assert.Error(t, err)
+	})
+}
+
+func TestOpenInput(t *testing.T) {
+	t.Run("圧縮されていないファイルはシーク可能なまま返す", func(t *testing.T) {
+		t.Parallel()
+		r, err := openInput("testdata/sample.csv", nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer r.Close()
+		_, ok := r.(*os.File)
+		assert.True(t, ok)
+
+		b, err := ioutil.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "id,name\n1,Gopher\n2,Doctor\n", string(b))
+	})
+
+	t.Run("短い入力", func(t *testing.T) {
+		t.Parallel()
+		for _, s := range []string{"", "a", "\x1f"} {
+			r, err := openInput("-", pipeReader{r: strings.NewReader(s)})
+			if !assert.NoError(t, err) {
+				return
+			}
+			b, err := ioutil.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, s, string(b))
+		}
+	})
+}
+
+func gzipReader(t *testing.T, s string) io.Reader {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
@@ -0,0 +1,766 @@
+package cut
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// JoinType go-joinの結合の種類
+type JoinType int
+
+const (
+	// JoinInner 両方の入力にキーがある行だけを出力する (デフォルト)
+	JoinInner JoinType = iota
+	// JoinLeft 左の入力のすべての行を出力する。右の入力にキーがなければ右のフィールドは空になる
+	JoinLeft
+	// JoinFull 両方の入力のすべての行を出力する。キーがない方のフィールドは空になる
+	JoinFull
+)
+
+// ParseJoinType --typeで指定された文字列をJoinTypeに変換する
+func ParseJoinType(s string) (JoinType, error) {
+	switch s {
+	case "", "inner":
+		return JoinInner, nil
+	case "left":
+		return JoinLeft, nil
+	case "full":
+		return JoinFull, nil
+	}
+	return 0, fmt.Errorf("不正な結合の種類です: %q (inner, left, fullのいずれかを指定してください)", s)
+}
+
+// JoinMethod 結合のアルゴリズム
+type JoinMethod int
+
+const (
+	// JoinAuto 右の入力がMemoryLimitに収まればハッシュ結合、収まらなければソートマージ結合にする (デフォルト)
+	JoinAuto JoinMethod = iota
+	// JoinHash 右の入力をすべてメモリに読み込み、左の入力を1行ずつ突き合わせる
+	// 出力は左の入力の順番になり、JoinFullで右だけにある行は最後に右の入力の順番で出力する
+	JoinHash
+	// JoinMerge 両方の入力をキーでソートしてから突き合わせる
+	// メモリに収まらない分は一時ファイルに書き出すので、大きな入力も結合できる。出力はキーの昇順になる
+	JoinMerge
+)
+
+// ParseJoinMethod --methodで指定された文字列をJoinMethodに変換する
+func ParseJoinMethod(s string) (JoinMethod, error) {
+	switch s {
+	case "", "auto":
+		return JoinAuto, nil
+	case "hash":
+		return JoinHash, nil
+	case "merge":
+		return JoinMerge, nil
+	}
+	return 0, fmt.Errorf("不正な結合の方法です: %q (auto, hash, mergeのいずれかを指定してください)", s)
+}
+
+// JoinOptions go-joinの動作を指定するオプション
+// 入力の読み込みと出力の形式はgo-cutのOptionsの同じ名前のフィールドと同じ意味になる
+type JoinOptions struct {
+	Delimiter       string
+	RegexDelimiter  *regexp.Regexp
+	Whitespace      bool
+	Trim            bool
+	CSV             bool
+	LazyQuotes      bool
+	Header          bool
+	RecordSeparator string
+	MaxLineLength   int
+	OutputDelimiter string
+	Format          Format
+	Quote           QuoteMode
+
+	// LeftKey, RightKey 結合するキーのフィールド (-k, --left-key, --right-key)
+	// 1始まりのフィールド番号か、--headerのカラム名。空の場合は1番目のフィールド
+	LeftKey, RightKey string
+	// LeftFields, RightFields 出力するフィールド (--left-fields, --right-fields)
+	// 両方とも空の場合はキー、左のキー以外のフィールド、右のキー以外のフィールドの順に出力する
+	// どちらかを指定した場合は左で指定したフィールド、右で指定したフィールドの順に出力する
+	LeftFields, RightFields List
+	// Type 結合の種類 (--type)
+	Type JoinType
+	// Method 結合のアルゴリズム (--method)
+	Method JoinMethod
+	// MemoryLimit ハッシュ結合で右の入力を読み込むメモリとソートに使うメモリの目安 (バイト, --memory-limit)
+	// 0の場合は64MiB
+	MemoryLimit int
+	// TempDir ソートの一時ファイルを作るディレクトリ。空の場合はos.TempDir()
+	TempDir string
+}
+
+// cutOptions 入力の読み込みと出力に使うgo-cutのオプション
+func (o JoinOptions) cutOptions() Options {
+	return Options{
+		Delimiter:       o.Delimiter,
+		RegexDelimiter:  o.RegexDelimiter,
+		Whitespace:      o.Whitespace,
+		Trim:            o.Trim,
+		CSV:             o.CSV,
+		LazyQuotes:      o.LazyQuotes,
+		Header:          o.Header,
+		RecordSeparator: o.RecordSeparator,
+		MaxLineLength:   o.MaxLineLength,
+		OutputDelimiter: o.OutputDelimiter,
+		Format:          o.Format,
+		Quote:           o.Quote,
+		MemoryLimit:     o.MemoryLimit,
+		Fields:          List{{Low: 1}},
+	}
+}
+
+// Validate オプションの組み合わせが正しいかをチェックする
+func (o JoinOptions) Validate() error {
+	if err := o.cutOptions().Validate(); err != nil {
+		return err
+	}
+	for _, key := range []string{o.LeftKey, o.RightKey} {
+		if n, ok := columnNumber(key); ok {
+			if n < 1 {
+				return fmt.Errorf("フィールド番号は1から始まります: %q", key)
+			}
+		} else if key != "" && !o.Header {
+			return fmt.Errorf("キーにカラム名を使う場合は--headerと一緒に指定してください")
+		}
+	}
+	return nil
+}
+
+// columnNumber フィールド番号かカラム名で指定された値がフィールド番号であればその番号を返す
+func columnNumber(s string) (int, bool) {
+	if s == "" || !isDigits(s) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+// Join leftとrightをキーで結合し、結果をwに書き出す
+func Join(left, right io.Reader, w io.Writer, opts JoinOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	return join(&joinInput{r: left}, &joinInput{r: right}, w, opts)
+}
+
+// JoinFiles leftとrightのファイルをキーで結合し、結果をwに書き出す
+// どちらか一方は"-"を指定してstdinから読み込める。gzip, bzip2で圧縮されたファイルも読み込める
+// 読み込み中のエラーにはファイル名を付ける
+func JoinFiles(left, right string, stdin io.Reader, w io.Writer, opts JoinOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if left == StdinName && right == StdinName {
+		return fmt.Errorf("標準入力は左右のどちらか一方にしか指定できません")
+	}
+
+	inputs := make([]*joinInput, 2)
+	for i, name := range []string{left, right} {
+		r, err := openInput(name, stdin)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, unwrapPathError(err))
+		}
+		defer r.Close()
+		inputs[i] = &joinInput{name: name, r: r}
+	}
+	return join(inputs[0], inputs[1], w, opts)
+}
+
+// joinInput 結合する入力
+type joinInput struct {
+	// name エラーに付けるファイル名 (JoinFilesの場合のみ)
+	name string
+	r    io.Reader
+}
+
+// joinRow 結合する入力の1行
+// ソートの一時ファイルにgobで書き出すのでフィールドはエクスポートしている
+type joinRow struct {
+	Key    string
+	Fields []string
+	// NoKey キーのフィールドがない行。どの行とも結合しない
+	NoKey bool
+	// Seq 入力での順番 (ソートを安定にするために使う)
+	Seq int64
+}
+
+// size 行のおおよそのメモリ使用量
+func (r *joinRow) size() int {
+	n := groupOverhead
+	for _, field := range r.Fields {
+		n += keyOverhead + len(field)
+	}
+	return n
+}
+
+// joinLess ソートマージ結合での行の順番
+// キーのない行は同じ""のキーの行より前に並べ、結合の対象から外しやすくする
+func joinLess(a, b *joinRow) bool {
+	if a.Key != b.Key {
+		return a.Key < b.Key
+	}
+	if a.NoKey != b.NoKey {
+		return a.NoKey
+	}
+	return a.Seq < b.Seq
+}
+
+// joinSide 結合する入力の一方
+type joinSide struct {
+	input *joinInput
+	// c 入力の分割に使うcutter
+	c *cutter
+	// number 出力するフィールドの名前に使う番号 (左が1, 右が2)
+	number int
+	key    string
+	// keyIndex キーのフィールドのインデックス (カラム名の場合はヘッダを読み込むまで-1)
+	keyIndex int
+	fields   List
+	header   []string
+	// width 出力する行がない場合に空にするフィールドの数 (ヘッダか最初の行のフィールド数)
+	width int
+	seq   int64
+}
+
+func newJoinSide(input *joinInput, opts JoinOptions, number int, key string, fields List) *joinSide {
+	s := &joinSide{input: input, c: newCutter(opts.cutOptions()), number: number, key: key, keyIndex: -1, fields: fields}
+	if key == "" {
+		s.keyIndex = 0
+	} else if n, ok := columnNumber(key); ok {
+		s.keyIndex = n - 1
+	}
+	return s
+}
+
+// scan 入力を最後まで読み込み、ヘッダ以外の行をfnに渡す
+func (s *joinSide) scan(fn func(row *joinRow) error) error {
+	opts := s.c.opts
+	lines := newLineReader(s.input.r, opts.MaxLineLength, opts.RecordSeparator)
+	err := s.c.scan(lines, func(record string, lineNo int) error {
+		fields, err := s.c.split(record, lineNo)
+		if err != nil {
+			return err
+		}
+		if opts.Header && s.header == nil {
+			s.header = append(make([]string, 0, len(fields)), fields...)
+			s.width = len(fields)
+			return s.bindKey()
+		}
+		if s.width == 0 {
+			s.width = len(fields)
+		}
+
+		row := &joinRow{Fields: append(make([]string, 0, len(fields)), fields...), Seq: s.seq}
+		s.seq++
+		if s.keyIndex < len(fields) {
+			row.Key = fields[s.keyIndex]
+		} else {
+			row.NoKey = true
+		}
+		return fn(row)
+	})
+	if err != nil && s.input.name != "" {
+		return fmt.Errorf("%s: %w", s.input.name, err)
+	}
+	return err
+}
+
+// bindKey カラム名で指定されたキーをヘッダのインデックスに変換する
+func (s *joinSide) bindKey() error {
+	if s.keyIndex >= 0 {
+		return nil
+	}
+	for i, name := range s.header {
+		if name == s.key {
+			s.keyIndex = i
+			return nil
+		}
+	}
+	return &ColumnNotFoundError{Name: s.key, Available: s.header}
+}
+
+// name index番目のフィールドの名前
+// ヘッダがあればカラム名、なければ"1.3"のように左右の番号とフィールド番号を返す
+func (s *joinSide) name(index int) string {
+	if index < 0 {
+		// 入力が空でカラム名のキーを変換できなかった場合
+		return s.key
+	}
+	if index < len(s.header) {
+		return s.header[index]
+	}
+	return strconv.Itoa(s.number) + "." + strconv.Itoa(index+1)
+}
+
+// joiner 2つの入力を結合して書き出す
+type joiner struct {
+	opts        JoinOptions
+	left, right *joinSide
+	w           *bufio.Writer
+	// out 出力形式に従って書き出すrecordWriter (左の入力のcutterのものを使い、改行コードも左に合わせる)
+	out           recordWriter
+	headerWritten bool
+	keys, values  []string
+	indexes       []int
+}
+
+func join(left, right *joinInput, w io.Writer, opts JoinOptions) error {
+	j := &joiner{
+		opts:  opts,
+		left:  newJoinSide(left, opts, 1, opts.LeftKey, opts.LeftFields),
+		right: newJoinSide(right, opts, 2, opts.RightKey, opts.RightFields),
+		w:     bufio.NewWriter(w),
+	}
+	j.out = j.left.c.out
+
+	var err error
+	if opts.Method == JoinMerge {
+		err = j.mergeJoin(nil)
+	} else {
+		err = j.hashJoin()
+	}
+	if err != nil {
+		j.w.Flush()
+		return err
+	}
+	j.writeHeader()
+	j.out.close(j.w)
+	return j.w.Flush()
+}
+
+// hashJoin 右の入力をキーごとにメモリに読み込み、左の入力を1行ずつ突き合わせる
+// JoinAutoで右の入力がメモリの上限を超えた場合は、読み込んだ分を引き継いでソートマージ結合に切り替える
+func (j *joiner) hashJoin() error {
+	limit := j.opts.MemoryLimit
+	if limit == 0 {
+		limit = defaultMemoryLimit
+	}
+
+	var (
+		rows   []*joinRow // 右の入力の行 (入力の順番)
+		size   int
+		sorter *rowSorter // ソートマージ結合に切り替えた場合の右の入力
+	)
+	defer func() {
+		if sorter != nil {
+			sorter.close()
+		}
+	}()
+	err := j.right.scan(func(row *joinRow) error {
+		if sorter != nil {
+			return sorter.add(row)
+		}
+		rows = append(rows, row)
+		size += row.size()
+		if j.opts.Method == JoinAuto && size > limit {
+			sorter = newRowSorter(j.opts)
+			for _, row := range rows {
+				if err := sorter.add(row); err != nil {
+					return err
+				}
+			}
+			rows = nil
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if sorter != nil {
+		return j.mergeJoin(sorter)
+	}
+
+	table := make(map[string][]*joinRow)
+	for _, row := range rows {
+		if !row.NoKey {
+			table[row.Key] = append(table[row.Key], row)
+		}
+	}
+	matched := make(map[*joinRow]bool)
+	err = j.left.scan(func(l *joinRow) error {
+		var found []*joinRow
+		if !l.NoKey {
+			found = table[l.Key]
+		}
+		if len(found) == 0 {
+			if j.opts.Type != JoinInner {
+				j.write(l, nil)
+			}
+			return nil
+		}
+		for _, r := range found {
+			j.write(l, r)
+			if j.opts.Type == JoinFull {
+				matched[r] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if j.opts.Type == JoinFull {
+		for _, r := range rows {
+			if !matched[r] {
+				j.write(nil, r)
+			}
+		}
+	}
+	return nil
+}
+
+// mergeJoin 両方の入力をキーでソートしてから突き合わせる
+// rightにはすでに右の入力を読み込んだrowSorterを渡せる。nilの場合は右の入力から読み込む
+func (j *joiner) mergeJoin(right *rowSorter) error {
+	if right == nil {
+		right = newRowSorter(j.opts)
+		defer right.close()
+		if err := j.right.scan(right.add); err != nil {
+			return err
+		}
+	}
+	left := newRowSorter(j.opts)
+	defer left.close()
+	if err := j.left.scan(left.add); err != nil {
+		return err
+	}
+
+	ls, err := left.sorted()
+	if err != nil {
+		return err
+	}
+	defer ls.close()
+	rs, err := right.sorted()
+	if err != nil {
+		return err
+	}
+	defer rs.close()
+
+	l, err := ls.next()
+	if err != nil {
+		return err
+	}
+	r, err := rs.next()
+	if err != nil {
+		return err
+	}
+	var matches []*joinRow
+	for l != nil || r != nil {
+		switch {
+		case l != nil && (l.NoKey || r == nil || l.Key < r.Key):
+			if j.opts.Type != JoinInner {
+				j.write(l, nil)
+			}
+			l, err = ls.next()
+		case r != nil && (r.NoKey || l == nil || r.Key < l.Key):
+			if j.opts.Type == JoinFull {
+				j.write(nil, r)
+			}
+			r, err = rs.next()
+		default:
+			// 同じキーの右の行をまとめて、同じキーの左の行それぞれと結合する
+			key := l.Key
+			matches = matches[:0]
+			for r != nil && r.Key == key && err == nil {
+				matches = append(matches, r)
+				r, err = rs.next()
+			}
+			for l != nil && !l.NoKey && l.Key == key && err == nil {
+				for _, m := range matches {
+					j.write(l, m)
+				}
+				l, err = ls.next()
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHeader --headerがあれば、最初の行の前に出力するフィールドのカラム名を書き出す
+func (j *joiner) writeHeader() {
+	if j.headerWritten {
+		return
+	}
+	j.headerWritten = true
+	if !j.opts.Header {
+		return
+	}
+	var l, r *joinRow
+	if j.left.header != nil {
+		l = &joinRow{Key: j.left.name(j.left.keyIndex), Fields: j.left.header}
+	}
+	if j.right.header != nil {
+		r = &joinRow{Key: j.right.name(j.right.keyIndex), Fields: j.right.header}
+	}
+	j.selectFields(l, r)
+	j.out.writeHeader(j.w, j.values)
+}
+
+// write 結合した1行を書き出す。lかrのどちらかはnilにできる
+func (j *joiner) write(l, r *joinRow) {
+	j.writeHeader()
+	j.selectFields(l, r)
+	j.out.writeRecord(j.w, j.keys, j.values)
+}
+
+// selectFields 出力するフィールドとその名前をj.keys, j.valuesに詰める
+func (j *joiner) selectFields(l, r *joinRow) {
+	j.keys = j.keys[:0]
+	j.values = j.values[:0]
+	if len(j.left.fields) == 0 && len(j.right.fields) == 0 {
+		// キーを先頭にまとめる
+		key := ""
+		switch {
+		case l != nil && !l.NoKey:
+			key = l.Key
+		case r != nil && !r.NoKey:
+			key = r.Key
+		}
+		j.keys = append(j.keys, j.left.name(j.left.keyIndex))
+		j.values = append(j.values, key)
+	}
+	j.appendFields(j.left, l)
+	j.appendFields(j.right, r)
+}
+
+// appendFields sの入力の行rowから出力するフィールドを追加する。rowがnilの場合は空の値にする
+func (j *joiner) appendFields(s *joinSide, row *joinRow) {
+	var fields []string
+	n := s.width
+	if row != nil {
+		fields = row.Fields
+		n = len(fields)
+	}
+
+	j.indexes = j.indexes[:0]
+	switch {
+	case len(s.fields) > 0:
+		j.indexes = s.fields.appendIndexes(j.indexes, n, false)
+	case len(j.left.fields) == 0 && len(j.right.fields) == 0:
+		for i := 0; i < n; i++ {
+			if i != s.keyIndex {
+				j.indexes = append(j.indexes, i)
+			}
+		}
+	}
+	for _, index := range j.indexes {
+		j.keys = append(j.keys, s.name(index))
+		if index < len(fields) {
+			j.values = append(j.values, fields[index])
+		} else {
+			j.values = append(j.values, "")
+		}
+	}
+}
+
+// rowSorter ソートマージ結合のために行をキーでソートする
+// 見積もったメモリ使用量が上限を超えたら、ソートして一時ファイルに書き出す
+type rowSorter struct {
+	limit int
+	dir   string
+	rows  []*joinRow
+	size  int
+	// runs 書き出した一時ファイル (それぞれキーの順番)
+	runs []string
+}
+
+func newRowSorter(opts JoinOptions) *rowSorter {
+	limit := opts.MemoryLimit
+	if limit == 0 {
+		limit = defaultMemoryLimit
+	}
+	return &rowSorter{limit: limit, dir: opts.TempDir}
+}
+
+func (s *rowSorter) add(row *joinRow) error {
+	s.rows = append(s.rows, row)
+	s.size += row.size()
+	if s.size > s.limit {
+		return s.spill()
+	}
+	return nil
+}
+
+func (s *rowSorter) sortRows() {
+	sort.Slice(s.rows, func(i, k int) bool {
+		return joinLess(s.rows[i], s.rows[k])
+	})
+}
+
+// spill メモリ上の行をソートして一時ファイルに書き出す
+func (s *rowSorter) spill() error {
+	s.sortRows()
+	f, err := ioutil.TempFile(s.dir, "go-join-sort-")
+	if err != nil {
+		return fmt.Errorf("ソートの一時ファイルを作成できません: %w", err)
+	}
+	s.runs = append(s.runs, f.Name())
+
+	w := bufio.NewWriter(f)
+	enc := gob.NewEncoder(w)
+	for _, row := range s.rows {
+		if err = enc.Encode(row); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("ソートの一時ファイルに書き込めません: %w", err)
+	}
+	s.rows = s.rows[:0]
+	s.size = 0
+	return nil
+}
+
+// sorted ソートした行を順番に返すrowIteratorを返す
+// 一時ファイルに書き出した場合は、それぞれのファイルから読み込みながらマージする
+func (s *rowSorter) sorted() (rowIterator, error) {
+	if len(s.runs) == 0 {
+		s.sortRows()
+		return &sliceIterator{rows: s.rows}, nil
+	}
+	if len(s.rows) > 0 {
+		if err := s.spill(); err != nil {
+			return nil, err
+		}
+	}
+	m := &mergeIterator{}
+	for _, name := range s.runs {
+		r, err := openRowRun(name)
+		if err != nil {
+			m.close()
+			return nil, err
+		}
+		row, err := r.next()
+		if err != nil {
+			r.f.Close()
+			m.close()
+			return nil, err
+		}
+		if row == nil {
+			r.f.Close()
+			continue
+		}
+		r.row = row
+		m.runs = append(m.runs, r)
+	}
+	heap.Init(m)
+	return m, nil
+}
+
+// close 一時ファイルを削除する
+func (s *rowSorter) close() {
+	for _, name := range s.runs {
+		os.Remove(name)
+	}
+	s.runs = nil
+}
+
+// rowIterator ソートした行を順番に返す。最後まで返した後はnilを返す
+type rowIterator interface {
+	next() (*joinRow, error)
+	close()
+}
+
+type sliceIterator struct {
+	rows []*joinRow
+}
+
+func (s *sliceIterator) next() (*joinRow, error) {
+	if len(s.rows) == 0 {
+		return nil, nil
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	return row, nil
+}
+
+func (s *sliceIterator) close() {}
+
+// rowRun 一時ファイルから順番に行を読み込む
+type rowRun struct {
+	f   *os.File
+	dec *gob.Decoder
+	row *joinRow
+}
+
+func openRowRun(name string) (*rowRun, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("ソートの一時ファイルを読み込めません: %w", err)
+	}
+	return &rowRun{f: f, dec: gob.NewDecoder(bufio.NewReader(f))}, nil
+}
+
+// next 次の行を返す。ファイルの終わりではnilを返す
+func (r *rowRun) next() (*joinRow, error) {
+	row := new(joinRow)
+	if err := r.dec.Decode(row); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ソートの一時ファイルを読み込めません: %w", err)
+	}
+	return row, nil
+}
+
+// mergeIterator 複数の一時ファイルの先頭の行のうち、最も小さいものから順番に返す
+type mergeIterator struct {
+	runs []*rowRun
+}
+
+func (m *mergeIterator) Len() int           { return len(m.runs) }
+func (m *mergeIterator) Less(i, k int) bool { return joinLess(m.runs[i].row, m.runs[k].row) }
+func (m *mergeIterator) Swap(i, k int)      { m.runs[i], m.runs[k] = m.runs[k], m.runs[i] }
+func (m *mergeIterator) Push(x interface{}) { m.runs = append(m.runs, x.(*rowRun)) }
+func (m *mergeIterator) Pop() interface{} {
+	r := m.runs[len(m.runs)-1]
+	m.runs = m.runs[:len(m.runs)-1]
+	return r
+}
+
+func (m *mergeIterator) next() (*joinRow, error) {
+	if len(m.runs) == 0 {
+		return nil, nil
+	}
+	r := m.runs[0]
+	row := r.row
+	next, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		heap.Pop(m)
+		r.f.Close()
+	} else {
+		r.row = next
+		heap.Fix(m, 0)
+	}
+	return row, nil
+}
+
+func (m *mergeIterator) close() {
+	for _, r := range m.runs {
+		r.f.Close()
+	}
+	m.runs = nil
+}
@@ -0,0 +1,219 @@
+package cut
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJoinType(t *testing.T) {
+	for s, want := range map[string]JoinType{"": JoinInner, "inner": JoinInner, "left": JoinLeft, "full": JoinFull} {
+		got, err := ParseJoinType(s)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := ParseJoinType("right")
+	assert.EqualError(t, err, `不正な結合の種類です: "right" (inner, left, fullのいずれかを指定してください)`)
+
+	for s, want := range map[string]JoinMethod{"": JoinAuto, "auto": JoinAuto, "hash": JoinHash, "merge": JoinMerge} {
+		got, err := ParseJoinMethod(s)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err = ParseJoinMethod("nested")
+	assert.Error(t, err)
+}
+
+func TestJoin(t *testing.T) {
+	const users = "user_id,name\n3,carol\n1,alice\n2,bob\n"
+	const items = "id,user_id,item\n10,1,sword\n11,3,shield\n12,1,potion\n13,9,ghost\n"
+
+	tests := []struct {
+		name string
+		opts JoinOptions
+		// hash ハッシュ結合の結果 (左の入力の順番), merge ソートマージ結合の結果 (キーの昇順)
+		hash, merge string
+	}{
+		{
+			name:  "inner",
+			opts:  JoinOptions{Delimiter: ",", Header: true, LeftKey: "user_id", RightKey: "user_id"},
+			hash:  "user_id,name,id,item\n3,carol,11,shield\n1,alice,10,sword\n1,alice,12,potion\n",
+			merge: "user_id,name,id,item\n1,alice,10,sword\n1,alice,12,potion\n3,carol,11,shield\n",
+		},
+		{
+			name:  "left",
+			opts:  JoinOptions{Delimiter: ",", Header: true, LeftKey: "user_id", RightKey: "user_id", Type: JoinLeft},
+			hash:  "user_id,name,id,item\n3,carol,11,shield\n1,alice,10,sword\n1,alice,12,potion\n2,bob,,\n",
+			merge: "user_id,name,id,item\n1,alice,10,sword\n1,alice,12,potion\n2,bob,,\n3,carol,11,shield\n",
+		},
+		{
+			name:  "full",
+			opts:  JoinOptions{Delimiter: ",", Header: true, LeftKey: "user_id", RightKey: "user_id", Type: JoinFull},
+			hash:  "user_id,name,id,item\n3,carol,11,shield\n1,alice,10,sword\n1,alice,12,potion\n2,bob,,\n9,,13,ghost\n",
+			merge: "user_id,name,id,item\n1,alice,10,sword\n1,alice,12,potion\n2,bob,,\n3,carol,11,shield\n9,,13,ghost\n",
+		},
+		{
+			name:  "フィールド番号 ヘッダなし",
+			opts:  JoinOptions{Delimiter: ",", RightKey: "2"},
+			hash:  "user_id,name,id,item\n3,carol,11,shield\n1,alice,10,sword\n1,alice,12,potion\n",
+			merge: "1,alice,10,sword\n1,alice,12,potion\n3,carol,11,shield\nuser_id,name,id,item\n",
+		},
+		{
+			name:  "出力するフィールドを指定する",
+			opts:  JoinOptions{Delimiter: ",", Header: true, LeftKey: "user_id", RightKey: "user_id", LeftFields: mustParseList(t, "2"), RightFields: mustParseList(t, "3-"), Type: JoinLeft},
+			hash:  "name,item\ncarol,shield\nalice,sword\nalice,potion\nbob,\n",
+			merge: "name,item\nalice,sword\nalice,potion\nbob,\ncarol,shield\n",
+		},
+		{
+			name:  "右だけ指定する",
+			opts:  JoinOptions{Delimiter: ",", Header: true, LeftKey: "user_id", RightKey: "user_id", RightFields: mustParseList(t, "1")},
+			hash:  "id\n11\n10\n12\n",
+			merge: "id\n10\n12\n11\n",
+		},
+		{
+			name:  "ndjson",
+			opts:  JoinOptions{Delimiter: ",", Header: true, LeftKey: "user_id", RightKey: "user_id", RightFields: mustParseList(t, "3"), LeftFields: mustParseList(t, "2"), Format: FormatNDJSON},
+			hash:  "{\"name\":\"carol\",\"item\":\"shield\"}\n{\"name\":\"alice\",\"item\":\"sword\"}\n{\"name\":\"alice\",\"item\":\"potion\"}\n",
+			merge: "{\"name\":\"alice\",\"item\":\"sword\"}\n{\"name\":\"alice\",\"item\":\"potion\"}\n{\"name\":\"carol\",\"item\":\"shield\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			for method, want := range map[JoinMethod]string{JoinAuto: tt.hash, JoinHash: tt.hash, JoinMerge: tt.merge} {
+				opts := tt.opts
+				opts.Method = method
+				stdout := new(bytes.Buffer)
+				err := Join(strings.NewReader(users), strings.NewReader(items), stdout, opts)
+				assert.NoError(t, err)
+				assert.Equal(t, want, stdout.String(), "method=%d", method)
+			}
+		})
+	}
+
+	t.Run("同じキーが両方に複数ある場合はすべての組み合わせ", func(t *testing.T) {
+		t.Parallel()
+		for _, method := range []JoinMethod{JoinHash, JoinMerge} {
+			stdout := new(bytes.Buffer)
+			err := Join(strings.NewReader("a,1\na,2\n"), strings.NewReader("a,x\na,y\n"), stdout, JoinOptions{Delimiter: ",", Method: method})
+			assert.NoError(t, err)
+			assert.Equal(t, "a,1,x\na,1,y\na,2,x\na,2,y\n", stdout.String())
+		}
+	})
+
+	t.Run("キーのフィールドがない行は結合しない", func(t *testing.T) {
+		t.Parallel()
+		for _, method := range []JoinMethod{JoinHash, JoinMerge} {
+			stdout := new(bytes.Buffer)
+			opts := JoinOptions{Delimiter: ",", LeftKey: "2", RightKey: "2", Type: JoinFull, Method: method}
+			err := Join(strings.NewReader("a,\nb\n"), strings.NewReader("x,\ny\n"), stdout, opts)
+			assert.NoError(t, err)
+			lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+			assert.ElementsMatch(t, []string{",a,x", ",b,", ",,y"}, lines, "method=%d", method)
+		}
+	})
+
+	t.Run("csv 改行コードは左の入力に合わせる", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		opts := JoinOptions{Delimiter: ",", CSV: true, Format: FormatCSV}
+		err := Join(strings.NewReader("1,\"a\r\nb\"\r\n2,c\r\n"), strings.NewReader("1,\"x,y\"\n"), stdout, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, "1,\"a\nb\",\"x,y\"\r\n", stdout.String())
+	})
+
+	t.Run("入力が空", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		err := Join(strings.NewReader(""), strings.NewReader(""), stdout, JoinOptions{Delimiter: ",", Format: FormatJSON})
+		assert.NoError(t, err)
+		assert.Equal(t, "[]\n", stdout.String())
+	})
+
+	t.Run("異常系", func(t *testing.T) {
+		t.Parallel()
+		err := Join(strings.NewReader(users), strings.NewReader(items), new(bytes.Buffer), JoinOptions{Delimiter: ",", Header: true, LeftKey: "user_id", RightKey: "uid"})
+		assert.EqualError(t, err, "カラムが見つかりません: \"uid\" (指定できるカラム: id, user_id, item)")
+
+		tests := []JoinOptions{
+			{},
+			{Delimiter: ",", LeftKey: "user_id"},
+			{Delimiter: ",", RightKey: "0"},
+			{Delimiter: ",", MemoryLimit: -1},
+		}
+		for _, opts := range tests {
+			assert.Error(t, opts.Validate())
+		}
+	})
+}
+
+func TestJoin_Spill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cut")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var left, right strings.Builder
+	for i := 300; i > 0; i-- {
+		fmt.Fprintf(&left, "k%03d,l%d\n", i, i)
+		if i%3 != 0 {
+			fmt.Fprintf(&right, "k%03d,r%d\nk%03d,s%d\n", i, i, i, i)
+		}
+	}
+	fmt.Fprintf(&right, "k999,r999\n")
+
+	base := JoinOptions{Delimiter: ",", Type: JoinFull, Method: JoinMerge, TempDir: dir}
+	want := new(bytes.Buffer)
+	err = Join(strings.NewReader(left.String()), strings.NewReader(right.String()), want, base)
+	assert.NoError(t, err)
+	assert.Equal(t, 300/3*1+300/3*2*2+1, strings.Count(want.String(), "\n"))
+	assert.True(t, strings.HasPrefix(want.String(), "k001,l1,r1\nk001,l1,s1\nk002,l2,r2\nk002,l2,s2\nk003,l3,\n"))
+
+	// メモリの上限を小さくするとソートの一時ファイルを使うが、結果は同じになる
+	// JoinAutoでも右の入力が上限を超えるのでソートマージ結合になる
+	for _, method := range []JoinMethod{JoinMerge, JoinAuto} {
+		opts := base
+		opts.Method = method
+		opts.MemoryLimit = 4 << 10
+		got := new(bytes.Buffer)
+		err := Join(strings.NewReader(left.String()), strings.NewReader(right.String()), got, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, want.String(), got.String(), "method=%d", method)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestJoinFiles(t *testing.T) {
+	opts := JoinOptions{Delimiter: ",", Header: true, LeftKey: "id", RightKey: "id", Type: JoinLeft}
+
+	t.Run("圧縮したファイルと標準入力", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		err := JoinFiles("testdata/sample.csv.gz", "-", strings.NewReader("id,score\n2,80\n"), stdout, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, "id,name,score\n1,Gopher,\n2,Doctor,80\n", stdout.String())
+	})
+
+	t.Run("異常系", func(t *testing.T) {
+		t.Parallel()
+		err := JoinFiles("-", "-", strings.NewReader(""), new(bytes.Buffer), opts)
+		assert.EqualError(t, err, "標準入力は左右のどちらか一方にしか指定できません")
+
+		err = JoinFiles("testdata/sample.csv", "testdata/missing.csv", strings.NewReader(""), new(bytes.Buffer), opts)
+		assert.EqualError(t, err, "testdata/missing.csv: no such file or directory")
+
+		err = JoinFiles("testdata/sample.csv", "-", strings.NewReader("id,score\n1,\"x\n"), new(bytes.Buffer), JoinOptions{Delimiter: ",", CSV: true})
+		assert.Error(t, err)
+		assert.True(t, strings.HasPrefix(err.Error(), "-: "), err.Error())
+	})
+}
@@ -0,0 +1,154 @@
+package cut
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Range フィールドリストの1区間 (1始まりで両端を含む)
+// Highが0の場合は末尾までを表す
+type Range struct {
+	Low  int
+	High int
+}
+
+// List GNU cutのLIST形式で指定されたフィールドの集合
+// 昇順に並び、重複する区間はマージされている
+type List []Range
+
+// ParseList GNU cutのLIST形式の文字列をパースする
+// N, N-, N-M, -M とそれらのカンマ区切りに対応する
+func ParseList(s string) (List, error) {
+	if s == "" {
+		return nil, fmt.Errorf("フィールドリストが空です")
+	}
+
+	var list List
+	for _, part := range strings.Split(s, ",") {
+		r, err := parseRange(part)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list.normalize(), nil
+}
+
+func parseRange(s string) (Range, error) {
+	i := strings.Index(s, "-")
+	if i < 0 {
+		n, err := parsePosition(s)
+		if err != nil {
+			return Range{}, err
+		}
+		return Range{Low: n, High: n}, nil
+	}
+
+	lowStr, highStr := s[:i], s[i+1:]
+	if lowStr == "" && highStr == "" {
+		return Range{}, fmt.Errorf("不正な範囲です: %q", s)
+	}
+
+	r := Range{Low: 1}
+	if lowStr != "" {
+		n, err := parsePosition(lowStr)
+		if err != nil {
+			return Range{}, err
+		}
+		r.Low = n
+	}
+	if highStr != "" {
+		n, err := parsePosition(highStr)
+		if err != nil {
+			return Range{}, err
+		}
+		if n < r.Low {
+			return Range{}, fmt.Errorf("範囲が降順になっています: %q", s)
+		}
+		r.High = n
+	}
+	return r, nil
+}
+
+func parsePosition(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("不正なフィールド番号です: %q", s)
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("フィールド番号は1から始まります: %q", s)
+	}
+	return n, nil
+}
+
+// normalize 区間をソートし、重なり・隣接する区間をマージする
+func (l List) normalize() List {
+	sort.Slice(l, func(i, j int) bool {
+		return l[i].Low < l[j].Low
+	})
+
+	merged := make(List, 0, len(l))
+	for _, r := range l {
+		if len(merged) == 0 {
+			merged = append(merged, r)
+			continue
+		}
+		last := &merged[len(merged)-1]
+		switch {
+		case last.High == 0:
+			// 末尾までの区間にすべて含まれる
+		case r.Low > last.High+1:
+			merged = append(merged, r)
+		case r.High == 0 || r.High > last.High:
+			last.High = r.High
+		}
+	}
+	return merged
+}
+
+// Contains n番目(1始まり)のフィールドが含まれるかを返す
+func (l List) Contains(n int) bool {
+	for _, r := range l {
+		if n < r.Low {
+			return false
+		}
+		if r.High == 0 || n <= r.High {
+			return true
+		}
+	}
+	return false
+}
+
+// Indexes n個のフィールドから選択されるフィールドの0始まりのインデックスを昇順で返す
+// complementがtrueの場合は選択されなかったフィールドを返す
+func (l List) Indexes(n int, complement bool) []int {
+	return l.appendIndexes(make([]int, 0, n), n, complement)
+}
+
+// appendIndexes Indexesの結果をdstに追加する
+func (l List) appendIndexes(dst []int, n int, complement bool) []int {
+	for i := 1; i <= n; i++ {
+		if l.Contains(i) != complement {
+			dst = append(dst, i-1)
+		}
+	}
+	return dst
+}
+
+// MinFields リストのすべての区間の開始位置を満たすのに必要なフィールド数
+// 末尾が明示されている区間はその末尾まで必要とする
+func (l List) MinFields() int {
+	var min int
+	for _, r := range l {
+		n := r.High
+		if n == 0 {
+			n = r.Low
+		}
+		if n > min {
+			min = n
+		}
+	}
+	return min
+}
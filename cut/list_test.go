@@ -0,0 +1,50 @@
+package cut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseList(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want List
+	}{
+		{name: "単体", in: "2", want: List{{Low: 2, High: 2}}},
+		{name: "カンマ区切り", in: "1,3", want: List{{Low: 1, High: 1}, {Low: 3, High: 3}}},
+		{name: "範囲", in: "2-4", want: List{{Low: 2, High: 4}}},
+		{name: "末尾まで", in: "3-", want: List{{Low: 3}}},
+		{name: "先頭から", in: "-2", want: List{{Low: 1, High: 2}}},
+		{name: "順不同", in: "5,1", want: List{{Low: 1, High: 1}, {Low: 5, High: 5}}},
+		{name: "重複はマージ", in: "1-3,2-5,6", want: List{{Low: 1, High: 6}}},
+		{name: "末尾までに吸収", in: "2-,4,7-9", want: List{{Low: 2}}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseList(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("異常系", func(t *testing.T) {
+		for _, in := range []string{"", "0", "a", "-", "3-1", "1,,2", "-0"} {
+			_, err := ParseList(in)
+			assert.Error(t, err, in)
+		}
+	})
+}
+
+func TestList_Indexes(t *testing.T) {
+	list, err := ParseList("2,4-")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []int{1, 3, 4}, list.Indexes(5, false))
+	assert.Equal(t, []int{0, 2}, list.Indexes(5, true))
+	assert.Equal(t, []int{1}, list.Indexes(3, false))
+	assert.Equal(t, 4, list.MinFields())
+}
@@ -0,0 +1,84 @@
+package cut
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MissingPolicy 指定したフィールドが存在しない行の扱い
+type MissingPolicy int
+
+const (
+	// MissingLenient GNU cutと同様に存在するフィールドだけを出力する (--lenient, デフォルト)
+	// フィールドが1つもなければ空行になり、区切り文字を含まない行はそのまま出力する
+	MissingLenient MissingPolicy = iota
+	// MissingStrict 最初に見つかった行でFieldCountErrorを返して処理を止める (--strict)
+	MissingStrict
+	// MissingSkip その行を出力せずに読み飛ばす (--skip)
+	// 読み飛ばした行はCutFilesが最後にまとめて報告する
+	MissingSkip
+)
+
+// FieldCountError 指定したフィールドが存在しない行があった場合のエラー
+// errors.Is(err, ErrMissingField)で判定できる
+type FieldCountError struct {
+	// File 読み込んでいたファイル名 (CutFilesの場合のみ)
+	File string
+	// Line 行番号 (1始まり)。CSVで複数行にまたがるレコードは始まりの行
+	Line int
+	// Fields その行のフィールド数
+	Fields int
+	// Want 必要なフィールド数
+	Want int
+}
+
+func (e *FieldCountError) Error() string {
+	return fmt.Sprintf("%d行目: %v (フィールド数: %d, 必要なフィールド数: %d)", e.Line, ErrMissingField, e.Fields, e.Want)
+}
+
+func (e *FieldCountError) Unwrap() error {
+	return ErrMissingField
+}
+
+// maxSkippedLines 読み飛ばした行のうち、行番号を報告する最大数
+const maxSkippedLines = 10
+
+// skipSummary --skipで読み飛ばした行の集計
+type skipSummary struct {
+	count int
+	// lines 最初のmaxSkippedLines行の行番号
+	lines []int
+}
+
+func (s *skipSummary) add(line int) {
+	s.count++
+	if len(s.lines) < maxSkippedLines {
+		s.lines = append(s.lines, line)
+	}
+}
+
+// merge 並列処理したチャンクの集計を追加する
+// チャンクの行番号は先頭からの相対位置なので、それより前の行数offsetを足す
+func (s *skipSummary) merge(other skipSummary, offset int) {
+	for _, line := range other.lines {
+		if len(s.lines) < maxSkippedLines {
+			s.lines = append(s.lines, line+offset)
+		}
+	}
+	s.count += other.count
+}
+
+// String "3行 (2, 5, 8行目)"の形式で返す
+// 行番号はmaxSkippedLinesまでで、それより多い場合は"など"を付ける
+func (s skipSummary) String() string {
+	lines := make([]string, len(s.lines))
+	for i, line := range s.lines {
+		lines[i] = strconv.Itoa(line)
+	}
+	more := ""
+	if s.count > len(s.lines) {
+		more = "など"
+	}
+	return fmt.Sprintf("%d行 (%s行目%s)", s.count, strings.Join(lines, ", "), more)
+}
@@ -0,0 +1,95 @@
+package cut
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldCountError(t *testing.T) {
+	var err error = &FieldCountError{File: "sample.csv", Line: 3, Fields: 2, Want: 4}
+	assert.True(t, errors.Is(err, ErrMissingField))
+	assert.EqualError(t, err, "3行目: -fの値に該当するデータがありません (フィールド数: 2, 必要なフィールド数: 4)")
+
+	var fe *FieldCountError
+	assert.True(t, errors.As(fmt.Errorf("wrap: %w", err), &fe))
+	assert.Equal(t, "sample.csv", fe.File)
+}
+
+func TestSkipSummary(t *testing.T) {
+	var s skipSummary
+	for i := 1; i <= 3; i++ {
+		s.add(i * 2)
+	}
+	assert.Equal(t, "3行 (2, 4, 6行目)", s.String())
+
+	var chunk skipSummary
+	for i := 1; i <= maxSkippedLines; i++ {
+		chunk.add(i)
+	}
+	s.merge(chunk, 100)
+	assert.Equal(t, 3+maxSkippedLines, s.count)
+	assert.Equal(t, "13行 (2, 4, 6, 101, 102, 103, 104, 105, 106, 107行目など)", s.String())
+}
+
+func TestCutFiles_Missing(t *testing.T) {
+	const plain = "testdata/sample.csv"
+
+	t.Run("strict ファイル名と行番号", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		stderr := new(bytes.Buffer)
+		err := CutFiles([]string{"-", plain}, strings.NewReader("a,b,c\na,b\n"), stdout, stderr, Options{Delimiter: ",", Fields: mustParseList(t, "3"), Missing: MissingStrict})
+		assert.Equal(t, &FieldCountError{File: "-", Line: 2, Fields: 2, Want: 3}, err)
+		assert.Equal(t, "c\n", stdout.String())
+		assert.Equal(t, "-: 2行目: -fの値に該当するデータがありません (フィールド数: 2, 必要なフィールド数: 3)\n", stderr.String())
+	})
+
+	t.Run("strict 読み込めないファイルの後でも止める", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		stderr := new(bytes.Buffer)
+		err := CutFiles([]string{"testdata/missing.csv", "-", plain}, strings.NewReader("a,b,c\na,b\na,b,c\n"), stdout, stderr, Options{Delimiter: ",", Fields: mustParseList(t, "3"), Missing: MissingStrict})
+		assert.True(t, errors.Is(err, ErrMissingField))
+		// エラーの後の行も、残りのファイルも出力しない
+		assert.Equal(t, "c\n", stdout.String())
+		assert.Equal(t, "testdata/missing.csv: no such file or directory\n"+
+			"-: 2行目: -fの値に該当するデータがありません (フィールド数: 2, 必要なフィールド数: 3)\n", stderr.String())
+	})
+
+	t.Run("skip 読み飛ばした行を報告する", func(t *testing.T) {
+		t.Parallel()
+		var sb strings.Builder
+		for i := 1; i <= 30; i++ {
+			if i%2 == 0 {
+				sb.WriteString("short\n")
+			} else {
+				fmt.Fprintf(&sb, "%d,ok\n", i)
+			}
+		}
+		stdout := new(bytes.Buffer)
+		stderr := new(bytes.Buffer)
+		err := CutFiles(nil, strings.NewReader(sb.String()), stdout, stderr, Options{Delimiter: ",", Fields: mustParseList(t, "2"), Missing: MissingSkip})
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Repeat("ok\n", 15), stdout.String())
+		assert.Equal(t, "-: フィールドが足りない15行 (2, 4, 6, 8, 10, 12, 14, 16, 18, 20行目など)をスキップしました\n", stderr.String())
+	})
+
+	t.Run("skip 並列処理でも行番号は同じ", func(t *testing.T) {
+		t.Parallel()
+		input := strings.Repeat("a,b\nshort\n", 50)
+		opts := Options{Delimiter: ",", Fields: mustParseList(t, "2"), Missing: MissingSkip, Jobs: 3}
+		for _, chunkSize := range []int64{1, 7, 64} {
+			c := newCutter(opts)
+			stdout := new(bytes.Buffer)
+			err := c.cutParallel(strings.NewReader(input), 0, int64(len(input)), stdout, 3, chunkSize)
+			assert.NoError(t, err)
+			assert.Equal(t, strings.Repeat("b\n", 50), stdout.String())
+			assert.Equal(t, "50行 (2, 4, 6, 8, 10, 12, 14, 16, 18, 20行目など)", c.skipped.String(), "chunkSize=%d", chunkSize)
+		}
+	})
+}
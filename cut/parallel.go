@@ -0,0 +1,193 @@
+package cut
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+)
+
+// parallelChunkSize 並列処理で1つのワーカーに渡すチャンクのおおよそのサイズ
+// チャンクの境界は次の行頭までずらすので、実際のサイズは行の長さの分だけ大きくなる
+const parallelChunkSize = 4 << 20
+
+// parallelizable 行ごとに独立して処理でき、チャンクに分けて並列に処理できるか
+// CSVはクォート内の改行で行をまたぐレコードがあり、
+// JSONの配列とMarkdownのテーブルはレコードの間で状態を持つので逐次処理する
+// 複数バイトの行の区切りは途中から探すと逐次処理と区切る位置が変わることがあるので逐次処理する
+// --group-by, --describeは1つのsummarizerに集計するので逐次処理する
+func (o Options) parallelizable() bool {
+	return !o.CSV && len(o.RecordSeparator) <= 1 && !o.GroupBy && !o.Describe && !o.statefulOutput()
+}
+
+// seekableRange rがシーク可能であれば、現在位置から終端までの範囲を返す
+// 標準入力やパイプのようにシークできない場合はokがfalseになる
+func seekableRange(r io.Reader) (ra io.ReaderAt, start, end int64, ok bool) {
+	ra, isReaderAt := r.(io.ReaderAt)
+	seeker, isSeeker := r.(io.Seeker)
+	if !isReaderAt || !isSeeker {
+		return nil, 0, 0, false
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, 0, 0, false
+	}
+	// 終端までシークしておき、逐次処理と同じくrを読み切った状態にする
+	end, err = seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, 0, 0, false
+	}
+	return ra, start, end, true
+}
+
+// chunk 並列処理する入力の範囲 [start, end)
+type chunk struct {
+	start, end int64
+	result     chan chunkResult
+}
+
+// chunkResult チャンクを処理した結果
+type chunkResult struct {
+	out     []byte
+	lines   int
+	skipped skipSummary
+	err     error
+}
+
+// cutParallel raの[start, end)をチャンクに分けてjobs個のワーカーで並列に処理し、元の順番でwに書き出す
+func (c *cutter) cutParallel(ra io.ReaderAt, start, end int64, w io.Writer, jobs int, chunkSize int64) error {
+	// 1行目は逐次処理し、ヘッダのカラムと出力の改行コードを決めてから各ワーカーに渡す
+	delim := c.delim()
+	firstEnd, _ := nextLineStart(ra, start, end, delim)
+	lineNo, err := c.cutSection(ra, start, firstEnd, w, 0)
+	if err != nil {
+		return err
+	}
+	start = firstEnd
+
+	done := make(chan struct{})
+	// queue 読み込んだ順番にチャンクを並べる。容量で先読みするチャンク数を制限する
+	queue := make(chan *chunk, jobs*2)
+	work := make(chan *chunk)
+
+	go func() {
+		defer close(work)
+		defer close(queue)
+		for pos := start; pos < end; {
+			next := end
+			if pos+chunkSize < end {
+				// 読み込みに失敗した場合は残りを1つのチャンクにし、ワーカーにエラーを報告させる
+				next, _ = nextLineStart(ra, pos+chunkSize-1, end, delim)
+			}
+			ch := &chunk{start: pos, end: next, result: make(chan chunkResult, 1)}
+			select {
+			case queue <- ch:
+			case <-done:
+				return
+			}
+			select {
+			case work <- ch:
+			case <-done:
+				return
+			}
+			pos = next
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ch := range work {
+				out := new(bytes.Buffer)
+				clone := c.clone()
+				lines, err := clone.cutSection(ra, ch.start, ch.end, out, 0)
+				ch.result <- chunkResult{out: out.Bytes(), lines: lines, skipped: clone.skipped, err: err}
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(done)
+
+	for ch := range queue {
+		result := <-ch.result
+		if result.err != nil {
+			// 行番号を含むエラーと途中までの出力を逐次処理と同じにするため、
+			// エラーになったチャンクは前のチャンクまでの行数を引き継いでやり直す
+			if _, err := c.clone().cutSection(ra, ch.start, ch.end, w, lineNo); err != nil {
+				return err
+			}
+			return result.err
+		}
+		if _, err := w.Write(result.out); err != nil {
+			return err
+		}
+		c.skipped.merge(result.skipped, lineNo)
+		lineNo += result.lines
+	}
+
+	writer := bufio.NewWriter(w)
+	c.out.close(writer)
+	return writer.Flush()
+}
+
+// cutSection raの[start, end)を切り出してwに書き出し、読み込んだ行数を返す
+// lineNoはstartより前の行数で、エラーの行番号に使う
+func (c *cutter) cutSection(ra io.ReaderAt, start, end int64, w io.Writer, lineNo int) (int, error) {
+	lines := newLineReader(io.NewSectionReader(ra, start, end-start), c.opts.MaxLineLength, c.opts.RecordSeparator)
+	lines.lineNo = lineNo
+	writer := bufio.NewWriter(w)
+	err := c.run(lines, writer)
+	if ferr := writer.Flush(); err == nil {
+		err = ferr
+	}
+	return lines.lineNo - lineNo, err
+}
+
+// clone ヘッダから解決したカラムを引き継いだcutterを作る
+// 出力用のバッファは共有しないので、別のゴルーチンで使える
+func (c *cutter) clone() *cutter {
+	clone := newCutter(c.opts)
+	clone.file = c.file
+	clone.prefix = c.prefix
+	clone.eol = c.eol
+	clone.eolFixed = c.eolFixed
+	clone.minFields = c.minFields
+	clone.header = c.header
+	clone.columns = c.columns
+	clone.selected = c.selected
+	clone.whereColumns = c.whereColumns
+	return clone
+}
+
+// delim 行の区切りのバイト
+func (c *cutter) delim() byte {
+	if c.opts.RecordSeparator == "" {
+		return '\n'
+	}
+	return c.opts.RecordSeparator[0]
+}
+
+// nextLineStart pos以降で最初のdelimの次の位置を返す
+// delimがなければendを返す
+func nextLineStart(ra io.ReaderAt, pos, end int64, delim byte) (int64, error) {
+	buf := make([]byte, 64*1024)
+	for pos < end {
+		if int64(len(buf)) > end-pos {
+			buf = buf[:end-pos]
+		}
+		n, err := ra.ReadAt(buf, pos)
+		if i := bytes.IndexByte(buf[:n], delim); i >= 0 {
+			return pos + int64(i) + 1, nil
+		}
+		pos += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return end, err
+		}
+	}
+	return end, nil
+}
@@ -0,0 +1,179 @@
+package cut
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pipeReader シーク可能な入力をシークできない入力に見せかける
+type pipeReader struct {
+	r io.Reader
+}
+
+func (p pipeReader) Read(b []byte) (int, error) {
+	return p.r.Read(b)
+}
+
+func TestCut_Parallel(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("id,name,memo\n")
+	for i := 1; i <= 200; i++ {
+		switch {
+		case i%17 == 0:
+			sb.WriteString("no delimiter\n")
+		case i%23 == 0:
+			sb.WriteString("\n")
+		case i%31 == 0:
+			fmt.Fprintf(&sb, "%d,%s,crlf\r\n", i, strings.Repeat("long", 100))
+		default:
+			fmt.Fprintf(&sb, "%d,Gopher%d,こんにちは|世界\n", i, i)
+		}
+	}
+	sb.WriteString("201,最後の行,改行なし")
+	input := sb.String()
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "フィールド", opts: Options{Delimiter: ",", Fields: mustParseList(t, "2-")}},
+		{name: "complement", opts: Options{Delimiter: ",", Fields: mustParseList(t, "2"), Complement: true, OutputDelimiter: "\t"}},
+		{name: "区切り文字を含む行のみ", opts: Options{Delimiter: ",", Fields: mustParseList(t, "1,3"), OnlyDelimited: true}},
+		{name: "ヘッダ", opts: Options{Delimiter: ",", Header: true, Names: []string{"memo", "id"}}},
+		{name: "ヘッダ ndjson", opts: Options{Delimiter: ",", Header: true, Fields: mustParseList(t, "1-2"), Format: FormatNDJSON}},
+		{name: "tsv", opts: Options{Delimiter: ",", Fields: mustParseList(t, "3"), Format: FormatTSV}},
+		{name: "バイト", opts: Options{Bytes: mustParseList(t, "2-5"), NoSplitMultibyte: true}},
+		{name: "文字", opts: Options{Characters: mustParseList(t, "1,5-")}},
+		{name: "存在しないフィールドでエラー", opts: Options{Delimiter: ",", Fields: mustParseList(t, "3"), Missing: MissingStrict}},
+		{name: "長すぎる行でエラー", opts: Options{Delimiter: ",", Fields: mustParseList(t, "1"), MaxLineLength: 300}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			want := new(bytes.Buffer)
+			wantErr := Cut(strings.NewReader(input), want, tt.opts)
+
+			for _, chunkSize := range []int64{1, 7, 64, 1000, int64(len(input))} {
+				for _, jobs := range []int{2, 3, 8} {
+					opts := tt.opts
+					opts.Jobs = jobs
+					c := newCutter(opts)
+					got := new(bytes.Buffer)
+					err := c.cutParallel(strings.NewReader(input), 0, int64(len(input)), got, jobs, chunkSize)
+
+					msg := fmt.Sprintf("chunkSize=%d jobs=%d", chunkSize, jobs)
+					assert.Equal(t, want.String(), got.String(), msg)
+					if wantErr == nil {
+						assert.NoError(t, err, msg)
+					} else {
+						assert.EqualError(t, err, wantErr.Error(), msg)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCut_Jobs(t *testing.T) {
+	const input = "skip\n1,Gopher\n2,Doctor\n"
+
+	tests := []struct {
+		name string
+		r    func() io.Reader
+		opts Options
+		want string
+	}{
+		{
+			name: "シーク可能な入力は現在位置から読み込む",
+			r: func() io.Reader {
+				r := strings.NewReader(input)
+				r.Seek(5, io.SeekStart)
+				return r
+			},
+			opts: Options{Delimiter: ",", Fields: mustParseList(t, "2"), Jobs: 4},
+			want: "Gopher\nDoctor\n",
+		},
+		{
+			name: "シークできない入力は逐次処理する",
+			r:    func() io.Reader { return pipeReader{r: strings.NewReader(input)} },
+			opts: Options{Delimiter: ",", Fields: mustParseList(t, "2"), Jobs: 4},
+			want: "skip\nGopher\nDoctor\n",
+		},
+		{
+			name: "JSONは逐次処理する",
+			r:    func() io.Reader { return strings.NewReader(input) },
+			opts: Options{Delimiter: ",", Fields: mustParseList(t, "1"), Format: FormatJSON, Jobs: 4},
+			want: "[\n{\"1\":\"skip\"},\n{\"1\":\"1\"},\n{\"1\":\"2\"}\n]\n",
+		},
+		{
+			name: "CSVは逐次処理する",
+			r:    func() io.Reader { return strings.NewReader("a,\"b\nc\"\n") },
+			opts: Options{Delimiter: ",", Fields: mustParseList(t, "2"), CSV: true, Jobs: 4},
+			want: "\"b\nc\"\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			stdout := new(bytes.Buffer)
+			err := Cut(tt.r(), stdout, tt.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+
+	t.Run("異常系", func(t *testing.T) {
+		t.Parallel()
+		err := Cut(strings.NewReader(input), new(bytes.Buffer), Options{Delimiter: ",", Fields: mustParseList(t, "1"), Jobs: -1})
+		assert.Error(t, err)
+	})
+}
+
+func TestNextLineStart(t *testing.T) {
+	const input = "ab\ncd\n\nef"
+
+	tests := []struct {
+		pos  int64
+		want int64
+	}{
+		{pos: 0, want: 3},
+		{pos: 2, want: 3},
+		{pos: 3, want: 6},
+		{pos: 6, want: 7},
+		{pos: 7, want: int64(len(input))},
+		{pos: int64(len(input)), want: int64(len(input))},
+	}
+	for _, tt := range tests {
+		got, err := nextLineStart(strings.NewReader(input), tt.pos, int64(len(input)), '\n')
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got, "pos=%d", tt.pos)
+	}
+}
+
+func BenchmarkCut_Parallel(b *testing.B) {
+	line := "1,GoodAfternoon ,Illustrator,FALSE,2020-05-14,https://example.com/\n"
+	input := strings.Repeat(line, (64<<20)/len(line))
+	opts := Options{Delimiter: ",", Fields: List{{Low: 2, High: 3}}}
+
+	for _, jobs := range []int{1, 2, 4, 8} {
+		jobs := jobs
+		b.Run(fmt.Sprintf("j%d", jobs), func(b *testing.B) {
+			opts := opts
+			opts.Jobs = jobs
+			b.SetBytes(int64(len(input)))
+			for i := 0; i < b.N; i++ {
+				if err := Cut(strings.NewReader(input), ioutil.Discard, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package cut
+
+import (
+	"bufio"
+	"unicode/utf8"
+)
+
+// unit -b, -cで切り出す単位
+type unit int
+
+const (
+	// unitByte 1バイトを1単位とする (-b)
+	unitByte unit = iota
+	// unitRune UTF-8の1文字を1単位とする (-c)
+	unitRune
+	// unitRuneByLastByte 1文字を1単位とし、最後のバイトの位置で選択を判定する (-b -n)
+	unitRuneByLastByte
+)
+
+func (c *cutter) writeBytes(w *bufio.Writer, line string) {
+	if c.opts.NoSplitMultibyte {
+		c.writeUnits(w, line, c.opts.Bytes, unitRuneByLastByte)
+		return
+	}
+	c.writeUnits(w, line, c.opts.Bytes, unitByte)
+}
+
+func (c *cutter) writeCharacters(w *bufio.Writer, line string) {
+	c.writeUnits(w, line, c.opts.Characters, unitRune)
+}
+
+// writeUnits lineをuの単位で区切り、listで選択された単位を書き出す
+// 連続しない範囲の間には出力の区切り文字を挟む
+func (c *cutter) writeUnits(w *bufio.Writer, line string, list List, u unit) {
+	var (
+		pos      int // 単位の位置 (1始まり)
+		wrote    bool
+		adjacent bool // 直前の単位が選択されていたか
+	)
+	// 末尾が決まっているリストは、それより後ろを読む必要がない
+	last := 0
+	if n := len(list); n > 0 && !c.opts.Complement {
+		last = list[n-1].High
+	}
+	w.WriteString(c.prefix)
+	for i := 0; i < len(line); {
+		if last > 0 && pos >= last {
+			break
+		}
+		size := 1
+		if u != unitByte {
+			// 不正なUTF-8のバイトは1バイトを1文字として扱う
+			_, size = utf8.DecodeRuneInString(line[i:])
+		}
+
+		switch u {
+		case unitRuneByLastByte:
+			pos = i + size
+		default:
+			pos++
+		}
+
+		if list.Contains(pos) != c.opts.Complement {
+			if wrote && !adjacent {
+				w.WriteString(c.outDelimiter)
+			}
+			w.WriteString(line[i : i+size])
+			wrote = true
+			adjacent = true
+		} else {
+			adjacent = false
+		}
+		i += size
+	}
+	w.WriteString(c.eol)
+}
@@ -0,0 +1,72 @@
+package cut
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCut_Position(t *testing.T) {
+	const input = "abcdef\nこんにちは世界\n"
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "バイト",
+			opts: Options{Bytes: mustParseList(t, "1-3")},
+			want: "abc\nこ\n",
+		},
+		{
+			name: "バイト マルチバイトを分割する",
+			opts: Options{Bytes: mustParseList(t, "1-2,4")},
+			want: "abd\n\xe3\x81\xe3\n",
+		},
+		{
+			name: "バイト マルチバイトを分割しない",
+			opts: Options{Bytes: mustParseList(t, "1-4,9"), NoSplitMultibyte: true},
+			want: "abcd\nこに\n",
+		},
+		{
+			name: "文字",
+			opts: Options{Characters: mustParseList(t, "2-3,6-")},
+			want: "bcf\nんに世界\n",
+		},
+		{
+			name: "文字 complement",
+			opts: Options{Characters: mustParseList(t, "1-5"), Complement: true},
+			want: "f\n世界\n",
+		},
+		{
+			name: "文字 output-delimiter",
+			opts: Options{Characters: mustParseList(t, "1,3-4,6"), OutputDelimiter: ":"},
+			want: "a:cd:f\nこ:にち:世\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			stdout := new(bytes.Buffer)
+			err := Cut(bytes.NewBufferString(input), stdout, tt.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+
+	t.Run("異常系", func(t *testing.T) {
+		t.Parallel()
+		list := mustParseList(t, "1")
+		for _, opts := range []Options{
+			{Bytes: list, Characters: list},
+			{Characters: list, OnlyDelimited: true},
+			{Characters: list, NoSplitMultibyte: true},
+		} {
+			err := Cut(bytes.NewBufferString(input), new(bytes.Buffer), opts)
+			assert.Error(t, err)
+		}
+	})
+}
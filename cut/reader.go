@@ -0,0 +1,115 @@
+package cut
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrLineTooLong 1行の長さがOptions.MaxLineLengthを超えた場合のエラー
+var ErrLineTooLong = errors.New("行が長すぎます")
+
+// readerBufferSize 入力の読み込みに使うバッファのサイズ
+// これより長い行は複数回に分けて読み込んで連結する
+const readerBufferSize = 64 * 1024
+
+// lineReader 入力を1行ずつ読み込む
+// bufio.Scannerと違い行の長さに上限がなく、必要であればmaxで上限を指定できる
+type lineReader struct {
+	r   *bufio.Reader
+	max int
+	// sep 行の区切り。空の場合は改行で区切り、行末の\rも取り除く
+	sep []byte
+	// delim sepの最後のバイト (ReadSliceで読み進める単位)
+	delim byte
+	// buf バッファに収まらない長い行を連結するためのバッファ (使い回す)
+	buf []byte
+	// lineNo 最後に読み込んだ行の行番号 (1始まり)
+	lineNo int
+	// crlf 最後に読み込んだ行が\r\nで終わっていたか (sepが空の場合のみ)
+	crlf bool
+}
+
+func newLineReader(r io.Reader, max int, sep string) *lineReader {
+	l := &lineReader{
+		r:     bufio.NewReaderSize(r, readerBufferSize),
+		max:   max,
+		sep:   []byte(sep),
+		delim: '\n',
+	}
+	if sep != "" {
+		l.delim = sep[len(sep)-1]
+	}
+	return l
+}
+
+// next 次の1行を区切りを除いて返す
+// 区切りが改行の場合はbufio.ScanLinesと同様に行末の\rも取り除く。入力の終わりではio.EOFを返す
+func (l *lineReader) next() (string, error) {
+	line, err := l.r.ReadSlice(l.delim)
+	if err == bufio.ErrBufferFull || (err == nil && !l.complete(line)) {
+		// バッファに収まらない場合や、複数バイトの区切りの途中だった場合は
+		// bufに連結しながら行末まで読み進める
+		l.buf = append(l.buf[:0], line...)
+		for err == bufio.ErrBufferFull || (err == nil && !l.complete(l.buf)) {
+			if l.max > 0 && len(l.buf) > l.max+len(l.sep) {
+				return "", l.tooLong()
+			}
+			line, err = l.r.ReadSlice(l.delim)
+			l.buf = append(l.buf, line...)
+		}
+		line = l.buf
+	}
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	if len(line) == 0 {
+		return "", io.EOF
+	}
+
+	l.lineNo++
+	line = l.dropSeparator(line)
+	if l.max > 0 && len(line) > l.max {
+		return "", l.tooLong()
+	}
+	return string(line), nil
+}
+
+// complete lineが区切りで終わっているか
+func (l *lineReader) complete(line []byte) bool {
+	return len(l.sep) <= 1 || bytes.HasSuffix(line, l.sep)
+}
+
+// dropSeparator 行末の区切りを取り除く
+func (l *lineReader) dropSeparator(line []byte) []byte {
+	if len(l.sep) > 0 {
+		return bytes.TrimSuffix(line, l.sep)
+	}
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+	}
+	l.crlf = false
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+		l.crlf = true
+	}
+	return line
+}
+
+// ParseRecordSeparator --record-separatorで指定された行の区切りをパースする
+// '\r\n'や'\x00'のようなGoの文字列リテラルと同じエスケープが使える
+func ParseRecordSeparator(s string) (string, error) {
+	sep, err := strconv.Unquote(`"` + strings.Replace(s, `"`, `\"`, -1) + `"`)
+	if err != nil || sep == "" {
+		return "", fmt.Errorf("不正な区切りです: %q", s)
+	}
+	return sep, nil
+}
+
+func (l *lineReader) tooLong() error {
+	return fmt.Errorf("%d行目: %w (上限 %dバイト)", l.lineNo+1, ErrLineTooLong, l.max)
+}
@@ -0,0 +1,382 @@
+package cut
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineReader(t *testing.T) {
+	long := strings.Repeat("a", readerBufferSize*3+1)
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "改行で終わる",
+			input: "a,b\nc\n",
+			want:  []string{"a,b", "c"},
+		},
+		{
+			name:  "改行で終わらない",
+			input: "a,b\nc",
+			want:  []string{"a,b", "c"},
+		},
+		{
+			name:  "CRLF",
+			input: "a\r\nb\r",
+			want:  []string{"a", "b"},
+		},
+		{
+			name:  "空行",
+			input: "\n\na\n",
+			want:  []string{"", "", "a"},
+		},
+		{
+			name:  "バッファより長い行",
+			input: "x\n" + long + "\ny\n" + long,
+			want:  []string{"x", long, "y", long},
+		},
+		{
+			name:  "空の入力",
+			input: "",
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			l := newLineReader(strings.NewReader(tt.input), 0, "")
+			var got []string
+			for {
+				line, err := l.next()
+				if err == io.EOF {
+					break
+				}
+				if !assert.NoError(t, err) {
+					return
+				}
+				got = append(got, line)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, len(tt.want), l.lineNo)
+		})
+	}
+}
+
+func TestCut_MaxLineLength(t *testing.T) {
+	long := strings.Repeat("a", readerBufferSize*2)
+	input := "1,short\n2," + long + "\n"
+
+	t.Run("上限なし", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		err := Cut(strings.NewReader(input), stdout, Options{Delimiter: ",", Fields: mustParseList(t, "2")})
+		assert.NoError(t, err)
+		assert.Equal(t, "short\n"+long+"\n", stdout.String())
+	})
+
+	t.Run("上限ちょうど", func(t *testing.T) {
+		t.Parallel()
+		stdout := new(bytes.Buffer)
+		opts := Options{Delimiter: ",", Fields: mustParseList(t, "1"), MaxLineLength: len(long) + 2}
+		err := Cut(strings.NewReader(input), stdout, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, "1\n2\n", stdout.String())
+	})
+
+	tests := []struct {
+		name string
+		max  int
+	}{
+		{name: "バッファに収まる上限を超える", max: 10},
+		{name: "バッファより長い上限を超える", max: readerBufferSize + 1},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			stdout := new(bytes.Buffer)
+			opts := Options{Delimiter: ",", Fields: mustParseList(t, "1"), MaxLineLength: tt.max}
+			err := Cut(strings.NewReader(input), stdout, opts)
+			assert.True(t, errors.Is(err, ErrLineTooLong))
+			assert.Contains(t, err.Error(), "2行目")
+			// エラーになる前の行は出力されている
+			assert.Equal(t, "1\n", stdout.String())
+		})
+	}
+
+	t.Run("異常系", func(t *testing.T) {
+		t.Parallel()
+		err := Cut(strings.NewReader(input), ioutil.Discard, Options{Delimiter: ",", Fields: mustParseList(t, "1"), MaxLineLength: -1})
+		assert.Error(t, err)
+	})
+}
+
+func TestSplitInto(t *testing.T) {
+	tests := []struct {
+		s, sep string
+	}{
+		{s: "a,b,c", sep: ","},
+		{s: "", sep: ","},
+		{s: ",,", sep: ","},
+		{s: "a::b:c", sep: "::"},
+		{s: "no delimiter", sep: ","},
+		{s: "あ、い、う", sep: "、"},
+	}
+	buf := make([]string, 0, 1)
+	for _, tt := range tests {
+		buf = splitInto(buf[:0], tt.s, tt.sep)
+		assert.Equal(t, strings.Split(tt.s, tt.sep), buf, tt.s)
+	}
+}
+
+// repeatReader lineをleftバイトになるまで繰り返し返すio.Reader
+// ベンチマークで巨大な入力をメモリに載せずに作るために使う
+type repeatReader struct {
+	line []byte
+	pos  int
+	left int64
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	if r.left <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.left {
+		p = p[:r.left]
+	}
+	var n int
+	for n < len(p) {
+		c := copy(p[n:], r.line[r.pos:])
+		n += c
+		r.pos = (r.pos + c) % len(r.line)
+	}
+	r.left -= int64(n)
+	return n, nil
+}
+
+func benchmarkCut(b *testing.B, line string, size int64, opts Options) {
+	b.SetBytes(size)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := &repeatReader{line: []byte(line), left: size}
+		if err := Cut(r, ioutil.Discard, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCut_LongLines 約256MBの行4つからなる約1GBの入力
+func BenchmarkCut_LongLines(b *testing.B) {
+	field := strings.Repeat("x", 1<<20-1)
+	line := strings.Repeat(field+",", 255) + field + "\n"
+	size := int64(len(line)) * 4
+
+	b.Run("fields", func(b *testing.B) {
+		benchmarkCut(b, line, size, Options{Delimiter: ",", Fields: List{{Low: 2, High: 3}}})
+	})
+	b.Run("characters", func(b *testing.B) {
+		benchmarkCut(b, line, size, Options{Characters: List{{Low: 1, High: 10}}})
+	})
+}
+
+// BenchmarkCut_ShortLines 1行ごとのメモリ確保を計測するための短い行の入力
+func BenchmarkCut_ShortLines(b *testing.B) {
+	const size = 64 << 20
+	line := "1,GoodAfternoon ,Illustrator,FALSE,2020-05-14,https://example.com/\n"
+
+	b.Run("fields", func(b *testing.B) {
+		benchmarkCut(b, line, size, Options{Delimiter: ",", Fields: List{{Low: 2, High: 3}}})
+	})
+	b.Run("complement", func(b *testing.B) {
+		benchmarkCut(b, line, size, Options{Delimiter: ",", Fields: List{{Low: 2, High: 2}}, Complement: true})
+	})
+	b.Run("csv", func(b *testing.B) {
+		benchmarkCut(b, line, size, Options{Delimiter: ",", Fields: List{{Low: 2, High: 3}}, CSV: true})
+	})
+}
+
+func TestLineReader_Separator(t *testing.T) {
+	long := strings.Repeat("a", readerBufferSize+1)
+
+	tests := []struct {
+		name     string
+		input    string
+		sep      string
+		want     []string
+		wantCRLF []bool
+	}{
+		{
+			name:     "改行コードを判定する",
+			input:    "a\r\nb\nc\r\n",
+			want:     []string{"a", "b", "c"},
+			wantCRLF: []bool{true, false, true},
+		},
+		{
+			name:  "NUL",
+			input: "a\nb\x00c\r\n\x00d",
+			sep:   "\x00",
+			want:  []string{"a\nb", "c\r\n", "d"},
+		},
+		{
+			name:  "複数バイトの区切り",
+			input: "a|b||c|||d||",
+			sep:   "||",
+			want:  []string{"a|b", "c", "|d"},
+		},
+		{
+			name:  "明示的な改行は\\rを取り除かない",
+			input: "a\r\nb",
+			sep:   "\n",
+			want:  []string{"a\r", "b"},
+		},
+		{
+			name:  "バッファの境界をまたぐ区切り",
+			input: long[1:] + "\r\n" + long + "\r\nx",
+			sep:   "\r\n",
+			want:  []string{long[1:], long, "x"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			l := newLineReader(strings.NewReader(tt.input), 0, tt.sep)
+			var got []string
+			var crlf []bool
+			for {
+				line, err := l.next()
+				if err == io.EOF {
+					break
+				}
+				if !assert.NoError(t, err) {
+					return
+				}
+				got = append(got, line)
+				crlf = append(crlf, l.crlf)
+			}
+			assert.Equal(t, tt.want, got)
+			if tt.wantCRLF != nil {
+				assert.Equal(t, tt.wantCRLF, crlf)
+			}
+		})
+	}
+
+	t.Run("上限を超える", func(t *testing.T) {
+		t.Parallel()
+		l := newLineReader(strings.NewReader("abc||"+long+"||"), 3, "||")
+		line, err := l.next()
+		assert.NoError(t, err)
+		assert.Equal(t, "abc", line)
+		_, err = l.next()
+		assert.True(t, errors.Is(err, ErrLineTooLong))
+	})
+}
+
+func TestCut_RecordSeparator(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  Options
+		want  string
+	}{
+		{
+			name:  "CRLFの入力はCRLFで出力する",
+			input: "1,Hi,Gopher,TRUE\r\n2,Hello,Doctor,FALSE\r\nno delimiter\r\n",
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "2,4")},
+			want:  "Hi,TRUE\r\nHello,FALSE\r\nno delimiter\r\n",
+		},
+		{
+			name:  "LFの入力はLFで出力する",
+			input: "1,Hi\n2,Hello\r\n",
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "2")},
+			want:  "Hi\nHello\n",
+		},
+		{
+			name:  "CRLF 文字",
+			input: "あいう\r\nえお\r\n",
+			opts:  Options{Characters: mustParseList(t, "2")},
+			want:  "い\r\nお\r\n",
+		},
+		{
+			name:  "CRLF json",
+			input: "id,name\r\n1,Gopher\r\n",
+			opts:  Options{Delimiter: ",", Header: true, Fields: mustParseList(t, "2"), Format: FormatJSON},
+			want:  "[\r\n{\"name\":\"Gopher\"}\r\n]\r\n",
+		},
+		{
+			name:  "CRLF csv",
+			input: "a,\"b\r\nc\"\r\nd,e\r\n",
+			opts:  Options{Delimiter: ",", CSV: true, Fields: mustParseList(t, "2")},
+			want:  "\"b\nc\"\r\ne\r\n",
+		},
+		{
+			name:  "NUL",
+			input: "./a b.txt\x00./dir/c\nd.txt\x00",
+			opts:  Options{Delimiter: "/", Fields: mustParseList(t, "2-"), RecordSeparator: "\x00"},
+			want:  "a b.txt\x00dir/c\nd.txt\x00",
+		},
+		{
+			name:  "複数バイトの区切り",
+			input: "a,b;;c,d;;",
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "2"), RecordSeparator: ";;", Format: FormatNDJSON},
+			want:  "{\"2\":\"b\"};;{\"2\":\"d\"};;",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			stdout := new(bytes.Buffer)
+			err := Cut(strings.NewReader(tt.input), stdout, tt.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+
+	t.Run("並列処理でも逐次処理と同じ", func(t *testing.T) {
+		t.Parallel()
+		inputs := map[string]Options{
+			strings.Repeat("1,a,b\r\n2,c\r\nx\r\n", 20):       {Delimiter: ",", Fields: mustParseList(t, "2")},
+			strings.Repeat("./a/b\x00./c\x00\x00", 20):        {Delimiter: "/", Fields: mustParseList(t, "2"), RecordSeparator: "\x00"},
+			"id,v\r\n" + strings.Repeat("1,a\r\n2,b\r\n", 20): {Delimiter: ",", Header: true, Names: []string{"v"}, Format: FormatCSV},
+		}
+		for input, opts := range inputs {
+			want := new(bytes.Buffer)
+			assert.NoError(t, Cut(strings.NewReader(input), want, opts))
+			for _, chunkSize := range []int64{1, 5, 32} {
+				got := new(bytes.Buffer)
+				err := newCutter(opts).cutParallel(strings.NewReader(input), 0, int64(len(input)), got, 3, chunkSize)
+				assert.NoError(t, err)
+				assert.Equal(t, want.String(), got.String(), "chunkSize=%d", chunkSize)
+			}
+		}
+	})
+
+	t.Run("異常系", func(t *testing.T) {
+		t.Parallel()
+		err := Cut(strings.NewReader("a,b"), new(bytes.Buffer), Options{Delimiter: ",", Fields: mustParseList(t, "1"), CSV: true, RecordSeparator: "\x00"})
+		assert.Error(t, err)
+	})
+}
+
+func TestParseRecordSeparator(t *testing.T) {
+	for s, want := range map[string]string{";": ";", `\r\n`: "\r\n", `\x00`: "\x00", `"`: `"`, "<br>": "<br>"} {
+		got, err := ParseRecordSeparator(s)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, s)
+	}
+	for _, s := range []string{"", `\q`, `\`} {
+		_, err := ParseRecordSeparator(s)
+		assert.Error(t, err, s)
+	}
+}
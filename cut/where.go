@@ -0,0 +1,585 @@
+package cut
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Where --whereで指定された行を絞り込む条件式
+//
+// 式は次の要素からなる
+//   - フィールド: $2 (1始まりの番号), $name, ${created at} (--headerのカラム名)
+//   - リテラル: "文字列", '文字列', 数値 (10, -1.5, 1e-5), /正規表現/
+//   - 比較: ==, !=, <, <=, >, >=
+//   - 正規表現: =~, !~ (右辺は/正規表現/または文字列)
+//   - 論理演算: &&, ||, !, (...)
+//   - 関数: trim(値), lower(値), upper(値)
+//
+// 比較はawkと同様に、両辺が数値として読める場合は数値として、そうでなければ文字列として比較する
+// 数値かどうかは前後の空白を除いて判定する。"10"のようにクォートしたリテラルとの比較は常に文字列になる
+// 前後の空白を除いて文字列として比較する場合はtrim($2) == "Gopher"のように書く
+type Where struct {
+	expr string
+	root boolNode
+	// names 式で使われているカラム名 (nameNode.indexはこのスライスのインデックス)
+	names []string
+}
+
+// WhereSyntaxError --whereの式の構文エラー
+type WhereSyntaxError struct {
+	Expr string
+	// Pos エラーの位置 (1始まりの文字数)
+	Pos int
+	Msg string
+}
+
+func (e *WhereSyntaxError) Error() string {
+	// 式の下にエラーの位置を^で示す
+	var width int
+	for i, r := range []rune(e.Expr) {
+		if i >= e.Pos-1 {
+			break
+		}
+		width++
+		if utf8.RuneLen(r) >= 3 {
+			// 日本語などの全角文字は2文字分の幅で表示される
+			width++
+		}
+	}
+	return fmt.Sprintf("--whereの%d文字目: %s\n  %s\n  %s^", e.Pos, e.Msg, e.Expr, strings.Repeat(" ", width))
+}
+
+// ParseWhere --whereで指定された式をパースする
+func ParseWhere(s string) (*Where, error) {
+	p := &whereParser{expr: s}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, "不要な%sがあります", tok)
+	}
+	return &Where{expr: s, root: root, names: p.names}, nil
+}
+
+// String パースした元の式を返す
+func (w *Where) String() string {
+	return w.expr
+}
+
+// Names 式で使われているカラム名を返す
+func (w *Where) Names() []string {
+	return w.names
+}
+
+// bind 式で使われているカラム名をヘッダのインデックスに変換する
+func (w *Where) bind(header []string) ([]int, error) {
+	columns := make([]int, len(w.names))
+	for i, name := range w.names {
+		column := -1
+		for j, h := range header {
+			if h == name {
+				column = j
+				break
+			}
+		}
+		if column < 0 {
+			return nil, &ColumnNotFoundError{Name: name, Available: header}
+		}
+		columns[i] = column
+	}
+	return columns, nil
+}
+
+// match fieldsが条件を満たすか
+// columnsはbindで変換したカラム名のインデックス
+func (w *Where) match(fields []string, columns []int) bool {
+	return w.root.eval(&whereRow{fields: fields, columns: columns})
+}
+
+// whereRow 評価中の行
+type whereRow struct {
+	fields  []string
+	columns []int
+}
+
+// field index番目(0始まり)のフィールド。存在しない場合は空文字列になる
+func (r *whereRow) field(index int) string {
+	if index < len(r.fields) {
+		return r.fields[index]
+	}
+	return ""
+}
+
+// boolNode 真偽値を返すノード
+type boolNode interface {
+	eval(r *whereRow) bool
+}
+
+// valueNode 値を返すノード
+type valueNode interface {
+	value(r *whereRow) string
+	// quoted クォートした文字列リテラルか (常に文字列として比較する)
+	quoted() bool
+}
+
+type orNode struct{ left, right boolNode }
+
+func (n *orNode) eval(r *whereRow) bool { return n.left.eval(r) || n.right.eval(r) }
+
+type andNode struct{ left, right boolNode }
+
+func (n *andNode) eval(r *whereRow) bool { return n.left.eval(r) && n.right.eval(r) }
+
+type notNode struct{ node boolNode }
+
+func (n *notNode) eval(r *whereRow) bool { return !n.node.eval(r) }
+
+type compareNode struct {
+	op          string
+	left, right valueNode
+}
+
+func (n *compareNode) eval(r *whereRow) bool {
+	l, rv := n.left.value(r), n.right.value(r)
+	var cmp int
+	if lf, rf, ok := numbers(l, rv); ok && !n.left.quoted() && !n.right.quoted() {
+		switch {
+		case lf < rf:
+			cmp = -1
+		case lf > rf:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(l, rv)
+	}
+
+	switch n.op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+// numbers 両方の値が数値として読める場合はその値を返す
+func numbers(l, r string) (float64, float64, bool) {
+	lf, ok := parseDecimal(strings.TrimSpace(l))
+	if !ok {
+		return 0, 0, false
+	}
+	rf, ok := parseDecimal(strings.TrimSpace(r))
+	if !ok {
+		return 0, 0, false
+	}
+	return lf, rf, true
+}
+
+// decimalPattern awkと同様に数値として扱う10進数の書き方 (符号、小数点、指数)
+// strconv.ParseFloatはinf, nan, 0x10, 0x1_0も受け付けるので、先にこれで判定する
+var decimalPattern = regexp.MustCompile(`^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?$`)
+
+// parseDecimal sが10進数の数値であればその値を返す
+func parseDecimal(s string) (float64, bool) {
+	if !decimalPattern.MatchString(s) {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+type matchNode struct {
+	negate bool
+	left   valueNode
+	re     *regexp.Regexp
+}
+
+func (n *matchNode) eval(r *whereRow) bool {
+	return n.re.MatchString(n.left.value(r)) != n.negate
+}
+
+type fieldNode struct{ index int }
+
+func (n *fieldNode) value(r *whereRow) string { return r.field(n.index) }
+func (n *fieldNode) quoted() bool             { return false }
+
+type nameNode struct{ index int }
+
+func (n *nameNode) value(r *whereRow) string { return r.field(r.columns[n.index]) }
+func (n *nameNode) quoted() bool             { return false }
+
+type literalNode struct {
+	s     string
+	quote bool
+}
+
+func (n *literalNode) value(r *whereRow) string { return n.s }
+func (n *literalNode) quoted() bool             { return n.quote }
+
+type funcNode struct {
+	fn  func(string) string
+	arg valueNode
+}
+
+func (n *funcNode) value(r *whereRow) string { return n.fn(n.arg.value(r)) }
+func (n *funcNode) quoted() bool             { return n.arg.quoted() }
+
+var whereFuncs = map[string]func(string) string{
+	"trim":  strings.TrimSpace,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenField
+	tokenName
+	tokenString
+	tokenNumber
+	tokenRegex
+	tokenIdent
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// pos 式の中での位置 (0始まりの文字数)
+	pos int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "式の終わり"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// whereParser 再帰下降で式をパースする
+type whereParser struct {
+	expr   string
+	tokens []token
+	next   int
+	names  []string
+}
+
+func (p *whereParser) errorf(tok token, format string, args ...interface{}) error {
+	return &WhereSyntaxError{Expr: p.expr, Pos: tok.pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// tokenize 式をトークンに分割する
+func (p *whereParser) tokenize() error {
+	runes := []rune(p.expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '$':
+			i++
+			if i < len(runes) && runes[i] == '{' {
+				end := indexRune(runes, i+1, '}')
+				if end < 0 {
+					return p.errorf(token{pos: start}, "${の閉じ括弧がありません")
+				}
+				p.add(tokenName, string(runes[i+1:end]), start)
+				i = end + 1
+				continue
+			}
+			for i < len(runes) && isIdentRune(runes[i]) {
+				i++
+			}
+			name := string(runes[start+1 : i])
+			switch {
+			case name == "":
+				return p.errorf(token{pos: start}, "$の後にフィールド番号かカラム名を指定してください")
+			case isDigits(name):
+				p.add(tokenField, name, start)
+			default:
+				p.add(tokenName, name, start)
+			}
+			continue
+		case r == '"' || r == '\'' || r == '/':
+			s, end, ok := scanQuoted(runes, i)
+			if !ok {
+				if r == '/' {
+					return p.errorf(token{pos: start}, "正規表現が閉じられていません")
+				}
+				return p.errorf(token{pos: start}, "文字列が閉じられていません")
+			}
+			kind := tokenString
+			if r == '/' {
+				kind = tokenRegex
+			}
+			p.add(kind, s, start)
+			i = end
+			continue
+		case r == '-' || r == '.' || unicode.IsDigit(r):
+			i++
+			for i < len(runes) && (runes[i] == '.' || unicode.IsDigit(runes[i]) || runes[i] == 'e' || runes[i] == 'E' ||
+				((runes[i] == '+' || runes[i] == '-') && (runes[i-1] == 'e' || runes[i-1] == 'E'))) {
+				i++
+			}
+			text := string(runes[start:i])
+			if _, ok := parseDecimal(text); !ok {
+				return p.errorf(token{pos: start}, "不正な数値です: %q", text)
+			}
+			p.add(tokenNumber, text, start)
+			continue
+		case isIdentRune(r):
+			for i < len(runes) && isIdentRune(runes[i]) {
+				i++
+			}
+			p.add(tokenIdent, string(runes[start:i]), start)
+			continue
+		case r == '(':
+			p.add(tokenLParen, "(", start)
+			i++
+			continue
+		case r == ')':
+			p.add(tokenRParen, ")", start)
+			i++
+			continue
+		}
+
+		op := ""
+		for _, candidate := range []string{"==", "!=", "<=", ">=", "=~", "!~", "&&", "||", "<", ">", "!"} {
+			if strings.HasPrefix(string(runes[i:]), candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return p.errorf(token{pos: start}, "不正な文字です: %q", string(r))
+		}
+		p.add(tokenOp, op, start)
+		i += len(op)
+	}
+	p.add(tokenEOF, "", len(runes))
+	return nil
+}
+
+func (p *whereParser) add(kind tokenKind, text string, pos int) {
+	p.tokens = append(p.tokens, token{kind: kind, text: text, pos: pos})
+}
+
+// scanQuoted runes[start]の引用符で囲まれた文字列を読み込み、中身と閉じ引用符の次の位置を返す
+// \で引用符と\自身をエスケープできる。正規表現ではそれ以外の\はそのまま残す
+func scanQuoted(runes []rune, start int) (string, int, bool) {
+	quote := runes[start]
+	var sb strings.Builder
+	for i := start + 1; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == quote:
+			return sb.String(), i + 1, true
+		case r == '\\' && i+1 < len(runes):
+			next := runes[i+1]
+			if next == quote || (next == '\\' && quote != '/') {
+				sb.WriteRune(next)
+				i++
+				continue
+			}
+		}
+		sb.WriteRune(r)
+	}
+	return "", 0, false
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *whereParser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *whereParser) advance() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *whereParser) acceptOp(op string) bool {
+	if tok := p.peek(); tok.kind == tokenOp && tok.text == op {
+		p.next++
+		return true
+	}
+	return false
+}
+
+// parseOr or := and ('||' and)*
+func (p *whereParser) parseOr() (boolNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseAnd and := not ('&&' not)*
+func (p *whereParser) parseAnd() (boolNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseNot not := '!' not | '(' or ')' | comparison
+func (p *whereParser) parseNot() (boolNode, error) {
+	if p.acceptOp("!") {
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{node: node}, nil
+	}
+	if tok := p.peek(); tok.kind == tokenLParen {
+		p.advance()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if next := p.advance(); next.kind != tokenRParen {
+			return nil, p.errorf(next, "%sの対応する閉じ括弧がありません", tok)
+		}
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison comparison := value op value | value ('=~'|'!~') (regex|string)
+func (p *whereParser) parseComparison() (boolNode, error) {
+	left, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.advance()
+	if op.kind != tokenOp {
+		return nil, p.errorf(op, "比較演算子(==, !=, <, <=, >, >=, =~, !~)がありません")
+	}
+	switch op.text {
+	case "=~", "!~":
+		tok := p.advance()
+		if tok.kind != tokenRegex && tok.kind != tokenString {
+			return nil, p.errorf(tok, "%sの右辺には/正規表現/か文字列を指定してください", op.text)
+		}
+		re, err := regexp.Compile(tok.text)
+		if err != nil {
+			return nil, p.errorf(tok, "不正な正規表現です: %v", err)
+		}
+		return &matchNode{negate: op.text == "!~", left: left, re: re}, nil
+	case "==", "!=", "<", "<=", ">", ">=":
+		right, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: op.text, left: left, right: right}, nil
+	}
+	return nil, p.errorf(op, "%sは比較演算子ではありません", op)
+}
+
+// parseValue value := field | name | string | number | ident '(' value ')'
+func (p *whereParser) parseValue() (valueNode, error) {
+	tok := p.advance()
+	switch tok.kind {
+	case tokenField:
+		n, err := strconv.Atoi(tok.text)
+		if err != nil || n <= 0 {
+			return nil, p.errorf(tok, "フィールド番号は1から始まります: $%s", tok.text)
+		}
+		return &fieldNode{index: n - 1}, nil
+	case tokenName:
+		return &nameNode{index: p.nameIndex(tok.text)}, nil
+	case tokenString:
+		return &literalNode{s: tok.text, quote: true}, nil
+	case tokenNumber:
+		return &literalNode{s: tok.text}, nil
+	case tokenIdent:
+		fn, ok := whereFuncs[tok.text]
+		if !ok {
+			return nil, p.errorf(tok, "不明な関数です: %s (trim, lower, upperが使えます。カラム名は$%sのように指定してください)", tok.text, tok.text)
+		}
+		if open := p.advance(); open.kind != tokenLParen {
+			return nil, p.errorf(open, "%sの後に(がありません", tok.text)
+		}
+		arg, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if next := p.advance(); next.kind != tokenRParen {
+			return nil, p.errorf(next, "%s(の対応する閉じ括弧がありません", tok.text)
+		}
+		return &funcNode{fn: fn, arg: arg}, nil
+	case tokenRegex:
+		return nil, p.errorf(tok, "正規表現は=~, !~の右辺にのみ指定できます")
+	}
+	return nil, p.errorf(tok, "フィールドか値が必要ですが%sがあります", tok)
+}
+
+// nameIndex カラム名をp.namesに登録してそのインデックスを返す
+func (p *whereParser) nameIndex(name string) int {
+	for i, n := range p.names {
+		if n == name {
+			return i
+		}
+	}
+	p.names = append(p.names, name)
+	return len(p.names) - 1
+}
@@ -0,0 +1,203 @@
+package cut
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParseWhere(t *testing.T, s string) *Where {
+	t.Helper()
+	where, err := ParseWhere(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return where
+}
+
+func TestWhere_Match(t *testing.T) {
+	fields := []string{"5", "GoodEvening ", " Singer", "TRUE", "10", "9.5"}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{expr: `$4 == "TRUE"`, want: true},
+		{expr: `$4 != 'TRUE'`, want: false},
+		{expr: `$1 > 3 && $4 == "TRUE"`, want: true},
+		{expr: `$1 > 5 || $4 == "FALSE"`, want: false},
+		{expr: `!($1 > 5) && !($4 == "FALSE")`, want: true},
+		{expr: `$1 == 5.0`, want: true},
+		{expr: `$1 >= -1`, want: true},
+		{expr: `$1 <= 1e3`, want: true},
+		// 数値同士は数値として比較する
+		{expr: `$5 > $6`, want: true},
+		{expr: `$5 > 9.5`, want: true},
+		// クォートしたリテラルとは文字列として比較する
+		{expr: `$5 > "9.5"`, want: false},
+		// 数値として読めない場合は文字列として比較する
+		{expr: `$2 > 3`, want: true},
+		// 数値かどうかは前後の空白を除いて判定する
+		{expr: `" 10 " == 10`, want: false},
+		{expr: `$3 == "Singer"`, want: false},
+		{expr: `trim($3) == "Singer"`, want: true},
+		{expr: `lower(trim($2)) == "goodevening"`, want: true},
+		{expr: `upper($4) == "TRUE"`, want: true},
+		{expr: `$2 =~ /^Good/`, want: true},
+		{expr: `$2 =~ "Evening $"`, want: true},
+		{expr: `$3 !~ /^Singer/`, want: true},
+		{expr: `$2 =~ /^good/`, want: false},
+		{expr: `$2 =~ /(?i)^good/`, want: true},
+		{expr: `$2 =~ /a\/b/`, want: false},
+		// 存在しないフィールドは空文字列
+		{expr: `$10 == ""`, want: true},
+		// &&は||より優先される
+		{expr: `$1 == 1 && $4 == "TRUE" || $1 == 5`, want: true},
+		{expr: `$1 == 1 && ($4 == "TRUE" || $1 == 5)`, want: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+			where := mustParseWhere(t, tt.expr)
+			assert.Equal(t, tt.want, where.match(fields, nil))
+		})
+	}
+}
+
+func TestWhere_Match_Numbers(t *testing.T) {
+	fields := []string{"inf", "nan", "0x10", "Infinity", "0.00002", "1e-5"}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// awkと同様に10進数以外は文字列として比較する
+		{expr: `$1 == $4`, want: false},
+		{expr: `$2 == 1`, want: false},
+		{expr: `$3 == 16`, want: false},
+		{expr: `$3 < 1`, want: true},
+		// 符号付きの指数
+		{expr: `$5 > 1e-5`, want: true},
+		{expr: `$5 < 1E+3`, want: true},
+		{expr: `$6 == 0.00001`, want: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+			where := mustParseWhere(t, tt.expr)
+			assert.Equal(t, tt.want, where.match(fields, nil))
+		})
+	}
+}
+
+func TestParseWhere_Error(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+		msg  string
+	}{
+		{expr: `$4 = "TRUE"`, pos: 4, msg: `不正な文字です: "="`},
+		{expr: `$4 == "TRUE`, pos: 7, msg: "文字列が閉じられていません"},
+		{expr: `$2 =~ /abc`, pos: 7, msg: "正規表現が閉じられていません"},
+		{expr: `$2 =~ /a(b/`, pos: 7, msg: "不正な正規表現です: error parsing regexp: missing closing ): `a(b`"},
+		{expr: `($1 == 1`, pos: 9, msg: `"("の対応する閉じ括弧がありません`},
+		{expr: `$1 == 1)`, pos: 8, msg: `不要な")"があります`},
+		{expr: `$1 == 1 &&`, pos: 11, msg: "フィールドか値が必要ですが式の終わりがあります"},
+		{expr: `$1`, pos: 3, msg: "比較演算子(==, !=, <, <=, >, >=, =~, !~)がありません"},
+		{expr: `$0 == 1`, pos: 1, msg: "フィールド番号は1から始まります: $0"},
+		{expr: `$ == 1`, pos: 1, msg: "$の後にフィールド番号かカラム名を指定してください"},
+		{expr: `name == 1`, pos: 1, msg: "不明な関数です: name (trim, lower, upperが使えます。カラム名は$nameのように指定してください)"},
+		{expr: `/a/ == $1`, pos: 1, msg: "正規表現は=~, !~の右辺にのみ指定できます"},
+		{expr: `$1 =~ 1`, pos: 7, msg: "=~の右辺には/正規表現/か文字列を指定してください"},
+		{expr: `${名前 == 1`, pos: 1, msg: "${の閉じ括弧がありません"},
+		{expr: `$1 == 1.2.3`, pos: 7, msg: `不正な数値です: "1.2.3"`},
+		{expr: `$1 == 1e`, pos: 7, msg: `不正な数値です: "1e"`},
+		{expr: `$1 == 1e+`, pos: 7, msg: `不正な数値です: "1e+"`},
+		{expr: `trim $1 == 1`, pos: 6, msg: "trimの後に(がありません"},
+		{expr: ``, pos: 1, msg: "フィールドか値が必要ですが式の終わりがあります"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+			_, err := ParseWhere(tt.expr)
+			if !assert.IsType(t, &WhereSyntaxError{}, err) {
+				return
+			}
+			se := err.(*WhereSyntaxError)
+			assert.Equal(t, tt.pos, se.Pos)
+			assert.Equal(t, tt.msg, se.Msg)
+		})
+	}
+
+	t.Run("エラーの位置を示す", func(t *testing.T) {
+		t.Parallel()
+		_, err := ParseWhere(`$名前 == "太郎" && ($1 > 1`)
+		assert.EqualError(t, err, "--whereの23文字目: \"(\"の対応する閉じ括弧がありません\n"+
+			"  $名前 == \"太郎\" && ($1 > 1\n"+
+			"  "+strings.Repeat(" ", 26)+"^")
+	})
+}
+
+func TestCut_Where(t *testing.T) {
+	const input = "1,GoodAfternoon ,Illustrator,FALSE\n2,Hi,Gopher,TRUE\n3,GoodMorning,Doctor,TRUE\n4,Hello,Gopher,FALSE\n5,GoodEvening , Singer,TRUE\n"
+	const header = "id,greeting,job,active\n"
+
+	tests := []struct {
+		name  string
+		input string
+		opts  Options
+		want  string
+	}{
+		{
+			name:  "4番目がTRUEの行の2番目",
+			input: input,
+			opts:  Options{Delimiter: ",", Fields: mustParseList(t, "2"), Where: mustParseWhere(t, `$4 == "TRUE"`)},
+			want:  "Hi\nGoodMorning\nGoodEvening \n",
+		},
+		{
+			name:  "カラム名",
+			input: header + input,
+			opts:  Options{Delimiter: ",", Header: true, Names: []string{"id", "job"}, Where: mustParseWhere(t, `$active == "TRUE" && trim($job) =~ /^(Gopher|Singer)$/`)},
+			want:  "id,job\n2,Gopher\n5, Singer\n",
+		},
+		{
+			name:  "カラム名 json",
+			input: header + input,
+			opts:  Options{Delimiter: ",", Header: true, Fields: mustParseList(t, "2"), Format: FormatJSON, Where: mustParseWhere(t, `$id >= 4`)},
+			want:  "[\n{\"greeting\":\"Hello\"},\n{\"greeting\":\"GoodEvening \"}\n]\n",
+		},
+		{
+			name:  "csv",
+			input: "a,\"x,y\"\nb,z\n",
+			opts:  Options{Delimiter: ",", CSV: true, Fields: mustParseList(t, "1"), Where: mustParseWhere(t, `$2 == "x,y"`)},
+			want:  "a\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			stdout := new(bytes.Buffer)
+			err := Cut(strings.NewReader(tt.input), stdout, tt.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+
+	t.Run("異常系", func(t *testing.T) {
+		t.Parallel()
+		err := Cut(strings.NewReader(header+input), new(bytes.Buffer), Options{Delimiter: ",", Header: true, Fields: mustParseList(t, "1"), Where: mustParseWhere(t, `$missing == 1`)})
+		assert.EqualError(t, err, "カラムが見つかりません: \"missing\" (指定できるカラム: id, greeting, job, active)")
+
+		err = Cut(strings.NewReader(input), new(bytes.Buffer), Options{Delimiter: ",", Fields: mustParseList(t, "1"), Where: mustParseWhere(t, `$id == 1`)})
+		assert.Error(t, err)
+
+		err = Cut(strings.NewReader(input), new(bytes.Buffer), Options{Characters: mustParseList(t, "1"), Where: mustParseWhere(t, `$1 == 1`)})
+		assert.Error(t, err)
+	})
+}
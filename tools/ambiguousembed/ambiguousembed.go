@@ -0,0 +1,191 @@
+package ambiguousembed
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+const doc = `ambiguousembed 埋め込みフィールド同士の名前の衝突を検出する
+
+chapter3.FaceのようにNoseとMouthを埋め込み、両方にBreathe()がある場合、
+Face自身にBreathe()を定義し忘れるとf.Breathe()は曖昧なセレクタになる。
+コンパイラは呼び出し箇所でしかエラーにしないため、structの定義時点で
+同じ深さから昇格してくるメソッド・フィールドの衝突を報告する。`
+
+// Analyzer 埋め込みによる曖昧なメソッド・フィールドを検出するAnalyzer
+var Analyzer = &analysis.Analyzer{
+	Name: "ambiguousembed",
+	Doc:  doc,
+	Run:  run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				checkTypeSpec(pass, spec.(*ast.TypeSpec))
+			}
+		}
+	}
+	return nil, nil
+}
+
+func checkTypeSpec(pass *analysis.Pass, spec *ast.TypeSpec) {
+	obj, ok := pass.TypesInfo.Defs[spec.Name].(*types.TypeName)
+	if !ok {
+		return
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok {
+		return
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return
+	}
+
+	conflicts := collectConflicts(named, st)
+	names := make([]string, 0, len(conflicts))
+	for name := range conflicts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		// 実際に曖昧なセレクタになるかはgo/typesの探索結果で確定させる
+		// (別パッケージの非公開名同士などは衝突しない)
+		found, index, _ := types.LookupFieldOrMethod(types.NewPointer(named), false, pass.Pkg, name)
+		if found != nil || index == nil {
+			continue
+		}
+
+		origins := conflicts[name]
+		if origins.isMethod() {
+			pass.Reportf(spec.Name.Pos(),
+				"ambiguous method %s.%s promoted from %s; define %s.%s to resolve it",
+				obj.Name(), name, origins.paths(), obj.Name(), name)
+		} else {
+			pass.Reportf(spec.Name.Pos(),
+				"ambiguous field %s.%s promoted from %s",
+				obj.Name(), name, origins.paths())
+		}
+	}
+}
+
+// origin 昇格してくる名前の出どころ
+type origin struct {
+	path string // 埋め込みフィールドのパス (例: Mouth, Head.Nose)
+	obj  types.Object
+}
+
+type origins []origin
+
+func (os origins) isMethod() bool {
+	for _, o := range os {
+		if _, ok := o.obj.(*types.Func); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (os origins) paths() string {
+	paths := make([]string, len(os))
+	for i, o := range os {
+		paths[i] = o.path
+	}
+	if len(paths) == 1 {
+		return paths[0]
+	}
+	return strings.Join(paths[:len(paths)-1], ", ") + " and " + paths[len(paths)-1]
+}
+
+// embedded 探索中の埋め込みフィールド
+type embedded struct {
+	typ  types.Type
+	path string
+}
+
+// collectConflicts 同じ深さで複数の埋め込みフィールドから昇格してくる名前を返す
+// Goのセレクタの規則と同様に浅い深さで見つかった名前はそれより深い名前を隠す
+func collectConflicts(named *types.Named, st *types.Struct) map[string]origins {
+	// 自身で定義しているフィールドとメソッドは明示的なオーバーライドとみなす
+	decided := make(map[string]bool)
+	var current []embedded
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		decided[f.Name()] = true
+		if f.Embedded() {
+			current = append(current, embedded{typ: f.Type(), path: f.Name()})
+		}
+	}
+	for i := 0; i < named.NumMethods(); i++ {
+		decided[named.Method(i).Name()] = true
+	}
+
+	conflicts := make(map[string]origins)
+	seen := map[*types.Named]bool{named: true}
+	for len(current) > 0 {
+		found := make(map[string]origins)
+		var next []embedded
+		var visited []*types.Named
+
+		for _, e := range current {
+			typ := e.typ
+			if p, ok := typ.(*types.Pointer); ok {
+				typ = p.Elem()
+			}
+			if n, ok := typ.(*types.Named); ok {
+				if seen[n] {
+					// 浅い深さですでに探索済みの型は無視する
+					continue
+				}
+				visited = append(visited, n)
+				for i := 0; i < n.NumMethods() && !types.IsInterface(n); i++ {
+					m := n.Method(i)
+					found[m.Name()] = append(found[m.Name()], origin{path: e.path, obj: m})
+				}
+			}
+
+			switch u := typ.Underlying().(type) {
+			case *types.Struct:
+				for i := 0; i < u.NumFields(); i++ {
+					f := u.Field(i)
+					found[f.Name()] = append(found[f.Name()], origin{path: e.path, obj: f})
+					if f.Embedded() {
+						next = append(next, embedded{typ: f.Type(), path: e.path + "." + f.Name()})
+					}
+				}
+			case *types.Interface:
+				for i := 0; i < u.NumMethods(); i++ {
+					m := u.Method(i)
+					found[m.Name()] = append(found[m.Name()], origin{path: e.path, obj: m})
+				}
+			}
+		}
+
+		for name, os := range found {
+			if decided[name] {
+				continue
+			}
+			decided[name] = true
+			if len(os) > 1 {
+				conflicts[name] = os
+			}
+		}
+		for _, n := range visited {
+			seen[n] = true
+		}
+		current = next
+	}
+	return conflicts
+}
@@ -0,0 +1,14 @@
+// ambiguousembed 埋め込みによるメソッド・フィールドの衝突を検出するvetツール
+//
+//	go build -o ambiguousembed ./tools/ambiguousembed/cmd/ambiguousembed
+//	go vet -vettool=$(pwd)/ambiguousembed ./...
+package main
+
+import (
+	"github.com/apbgo/go-study-group/tools/ambiguousembed"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(ambiguousembed.Analyzer)
+}
@@ -0,0 +1,106 @@
+package face
+
+// chapter3/kadai4.goの型をそのまま利用したテストケース
+
+type Eye struct {
+	isOpen bool
+}
+
+func (e *Eye) Watch() {
+	e.isOpen = true
+}
+
+type Nose struct {
+	isOpen bool
+}
+
+func (n *Nose) Breathe() {
+	n.isOpen = true
+}
+
+type Mouth struct {
+	isOpen  bool
+	hasFood bool
+}
+
+func (m *Mouth) Eat() {
+	m.hasFood = true
+}
+
+func (m *Mouth) Breathe() {
+	m.isOpen = true
+}
+
+// Face chapter3.Faceと同じ定義
+// Breatheはオーバーライドしているが、isOpenは3つの埋め込みフィールドで衝突している
+type Face struct { // want `ambiguous field Face.isOpen promoted from Eye, Nose and Mouth`
+	Eye
+	Nose
+	Mouth
+}
+
+func (f *Face) Breathe() {
+	f.Nose.Breathe()
+	f.Mouth.Breathe()
+}
+
+// ForgetfulFace Breatheの定義を忘れたFace
+type ForgetfulFace struct { // want `ambiguous field ForgetfulFace.isOpen promoted from Eye, Nose and Mouth` `ambiguous method ForgetfulFace.Breathe promoted from Nose and Mouth; define ForgetfulFace.Breathe to resolve it`
+	Eye
+	Nose
+	Mouth
+}
+
+// PointerFace ポインタで埋め込んでも同様に衝突する
+type PointerFace struct { // want `ambiguous method PointerFace.Breathe promoted from Nose and Mouth`
+	*Nose
+	*Mouth
+	isOpen bool
+}
+
+// CompleteFace フィールドとメソッドを両方オーバーライドしているので衝突しない
+type CompleteFace struct {
+	Eye
+	Nose
+	Mouth
+	isOpen bool
+}
+
+func (f *CompleteFace) Breathe() {
+	f.Nose.Breathe()
+	f.Mouth.Breathe()
+}
+
+// Head 深さが異なる場合は浅い方が優先されるので衝突しない
+type Hair struct {
+	Nose
+}
+
+type Head struct {
+	Nose
+	Hair
+}
+
+// Breather 埋め込んだインターフェイスのメソッドも対象になる
+type Breather interface {
+	Breathe()
+}
+
+type Robot struct { // want `ambiguous method Robot.Breathe promoted from Breather and Nose`
+	Breather
+	Nose
+}
+
+// Twins 同じ型を別の経路で同じ深さに埋め込んだ場合も衝突する
+type Left struct {
+	Eye
+}
+
+type Right struct {
+	Eye
+}
+
+type Twins struct { // want `ambiguous field Twins.Eye promoted from Left and Right` `ambiguous method Twins.Watch promoted from Left.Eye and Right.Eye` `ambiguous field Twins.isOpen promoted from Left.Eye and Right.Eye`
+	Left
+	Right
+}
@@ -0,0 +1,352 @@
+// Package cassette decorgenで生成したデコレータの呼び出しを記録し、テストで再生する
+//
+// gomockのように期待する呼び出しを手で書く代わりに、本物の実装を包んだデコレータで
+// 呼び出しと結果をJSONのカセットに記録しておき、次からはカセットから同じ結果を返す。
+//
+//	c, err := cassette.New("testdata/provide.json", cassette.Auto)
+//	repo := NewIFUserItemRepositoryDecorator(realRepo, c.Hooks())
+//	... repoを使うテスト ...
+//	assert.NoError(t, c.Close())
+//
+// 再生するときは記録したときと同じ順番で呼び出す必要がある。
+// context.Contextの引数は値を比べない。*sql.Txのように値を比べられない引数はMatchで登録する。
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/apbgo/go-study-group/tools/decorgen/decor"
+)
+
+// ErrMismatch 再生中の呼び出しが記録と一致しない場合のエラー
+var ErrMismatch = errors.New("呼び出しが記録と一致しません")
+
+// Mode カセットを記録するか再生するか
+type Mode int
+
+const (
+	// Replay カセットから結果を返す。包んだ実装は呼び出さない
+	Replay Mode = iota
+	// Record 包んだ実装を呼び出し、呼び出しと結果をカセットに書き出す
+	Record
+	// Auto カセットのファイルがなければ記録し、あれば再生する
+	Auto
+)
+
+// ArgMatcher 引数をカセットに書き出す値に変換する。対象外の引数の場合はokをfalseにする
+// 再生するときは変換した値どうしを比べる
+type ArgMatcher func(arg interface{}) (value interface{}, ok bool)
+
+// IgnoreType typと同じ型の引数は値を比べず、カセットには型の名前だけを書き出すArgMatcher
+// インタフェースへのポインタ(例: (*context.Context)(nil))を渡すと、そのインタフェースを実装する引数すべてが対象になる
+func IgnoreType(typ interface{}) ArgMatcher {
+	t := reflect.TypeOf(typ)
+	match := func(at reflect.Type) bool { return at == t }
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Interface {
+		t = t.Elem()
+		match = func(at reflect.Type) bool { return at.Implements(t) }
+	}
+	name := t.String()
+	return func(arg interface{}) (interface{}, bool) {
+		if arg == nil || !match(reflect.TypeOf(arg)) {
+			return nil, false
+		}
+		return name, true
+	}
+}
+
+// Interaction カセットに記録した1回の呼び出し
+type Interaction struct {
+	// Method "Interface.Method"の形式の名前
+	Method string `json:"method"`
+	// Args ArgMatcherで変換した引数
+	Args []json.RawMessage `json:"args"`
+	// Results 最後のerrorを除く戻り値
+	Results []json.RawMessage `json:"results"`
+	// Error エラーのメッセージ。エラーがない場合は空
+	Error string `json:"error,omitempty"`
+	// Sentinel エラーがRegisterErrorで登録したエラーを含む場合はそのメッセージ
+	Sentinel string `json:"sentinel,omitempty"`
+}
+
+// file カセットのファイルの形式
+type file struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Cassette 1つのファイルに記録する呼び出しの並び
+type Cassette struct {
+	path     string
+	mode     Mode
+	matchers []ArgMatcher
+
+	mu           sync.Mutex
+	sentinels    []error
+	interactions []Interaction
+	// next 次に再生するinteractionsの位置
+	next int
+	// err 最初に起きたエラー。Closeで返す
+	err error
+}
+
+// New pathのカセットを作る。ReplayとAutoで再生する場合はファイルを読み込む
+func New(path string, mode Mode) (*Cassette, error) {
+	if mode == Auto {
+		mode = Replay
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			mode = Record
+		}
+	}
+	c := &Cassette{
+		path:     path,
+		mode:     mode,
+		matchers: []ArgMatcher{IgnoreType((*context.Context)(nil))},
+	}
+	if mode == Replay {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var f file
+		if err := json.Unmarshal(b, &f); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		c.interactions = f.Interactions
+	}
+	return c, nil
+}
+
+// Mode 記録するか再生するか。Autoで作った場合はどちらになったかを返す
+func (c *Cassette) Mode() Mode {
+	return c.mode
+}
+
+// Match 引数を変換するArgMatcherを登録する。先に登録したものやcontext.Contextを無視するものより優先する
+func (c *Cassette) Match(matchers ...ArgMatcher) {
+	c.matchers = append(append([]ArgMatcher{}, matchers...), c.matchers...)
+}
+
+// RegisterError 再生したエラーでもerrors.Isで判定できるようにするエラーを登録する
+// 記録したエラーが登録したエラーを含む場合、再生したエラーはそのエラーを包む
+func (c *Cassette) RegisterError(errs ...error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sentinels = append(c.sentinels, errs...)
+}
+
+// Hooks デコレータに渡すHooksを返す
+// Recordでは包んだ実装の呼び出しを記録し、Replayでは包んだ実装を呼び出さずにカセットの結果を返す
+func (c *Cassette) Hooks() decor.Hooks {
+	if c.mode == Record {
+		return decor.Hooks{After: c.record}
+	}
+	return decor.Hooks{Replace: c.replay}
+}
+
+// Close Recordではカセットをファイルに書き出す
+// Replayでは一致しない呼び出しがあった場合や、再生していない呼び出しが残っている場合にエラーを返す
+func (c *Cassette) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	if c.mode == Replay {
+		if rest := c.interactions[c.next:]; len(rest) > 0 {
+			names := make([]string, len(rest))
+			for i, in := range rest {
+				names[i] = in.Method
+			}
+			return fmt.Errorf("%s: 再生していない呼び出しが%d件あります: %s", c.path, len(rest), strings.Join(names, ", "))
+		}
+		return nil
+	}
+
+	interactions := c.interactions
+	if interactions == nil {
+		interactions = []Interaction{}
+	}
+	b, err := json.MarshalIndent(file{Interactions: interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, append(b, '\n'), 0644)
+}
+
+func (c *Cassette) record(call *decor.Call) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	args, err := c.encodeArgs(call.Args)
+	if err != nil {
+		c.fail(fmt.Errorf("%s: 引数を記録できません: %w", call.Name(), err))
+		return
+	}
+	results := make([]json.RawMessage, len(call.Results))
+	for i, r := range call.Results {
+		if results[i], err = encode(r); err != nil {
+			c.fail(fmt.Errorf("%s: 戻り値%dを記録できません: %w", call.Name(), i, err))
+			return
+		}
+	}
+	in := Interaction{Method: call.Name(), Args: args, Results: results}
+	if call.Err != nil {
+		in.Error = call.Err.Error()
+		for _, s := range c.sentinels {
+			if errors.Is(call.Err, s) {
+				in.Sentinel = s.Error()
+				break
+			}
+		}
+	}
+	c.interactions = append(c.interactions, in)
+}
+
+func (c *Cassette) replay(call *decor.Call) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.replayLocked(call); err != nil {
+		c.fail(err)
+		call.Err = err
+	}
+	return true
+}
+
+func (c *Cassette) replayLocked(call *decor.Call) error {
+	n := c.next + 1
+	if c.next >= len(c.interactions) {
+		return fmt.Errorf("%w: %d番目の呼び出し%sは記録されていません", ErrMismatch, n, call.Name())
+	}
+	in := c.interactions[c.next]
+	args, err := c.encodeArgs(call.Args)
+	if err != nil {
+		return fmt.Errorf("%s: 引数を変換できません: %w", call.Name(), err)
+	}
+	if diff := diffCall(in, call.Name(), args); diff != "" {
+		return fmt.Errorf("%w: %d番目の呼び出し\n%s", ErrMismatch, n, diff)
+	}
+	if len(in.Results) != len(call.Out) {
+		return fmt.Errorf("%s: 記録した戻り値は%d個ですが、%d個必要です", call.Name(), len(in.Results), len(call.Out))
+	}
+	c.next++
+
+	call.Results = make([]interface{}, len(call.Out))
+	for i, out := range call.Out {
+		if err := json.Unmarshal(in.Results[i], out); err != nil {
+			return fmt.Errorf("%s: 戻り値%dを再生できません: %w", call.Name(), i, err)
+		}
+		call.Results[i] = reflect.ValueOf(out).Elem().Interface()
+	}
+	call.Err = nil
+	if in.Error != "" {
+		e := &replayedError{msg: in.Error}
+		for _, s := range c.sentinels {
+			if in.Sentinel != "" && s.Error() == in.Sentinel {
+				e.sentinel = s
+				break
+			}
+		}
+		call.Err = e
+	}
+	return nil
+}
+
+// fail 最初のエラーだけを覚えておく
+func (c *Cassette) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+func (c *Cassette) encodeArgs(args []interface{}) ([]json.RawMessage, error) {
+	encoded := make([]json.RawMessage, len(args))
+	for i, arg := range args {
+		for _, m := range c.matchers {
+			if v, ok := m(arg); ok {
+				arg = v
+				break
+			}
+		}
+		b, err := encode(arg)
+		if err != nil {
+			return nil, fmt.Errorf("引数%d: %w", i, err)
+		}
+		encoded[i] = b
+	}
+	return encoded, nil
+}
+
+// encode vをキーの順番や空白によらない形のJSONにする
+func encode(v interface{}) (json.RawMessage, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return canonical(b)
+}
+
+func canonical(b []byte) (json.RawMessage, error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// diffCall 記録した呼び出しと異なるメソッドや引数を1行ずつ書き出す。一致する場合は空にする
+func diffCall(in Interaction, method string, args []json.RawMessage) string {
+	var b strings.Builder
+	if in.Method != method {
+		fmt.Fprintf(&b, "  メソッド:\n    - 記録: %s\n    + 実際: %s\n", in.Method, method)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "  メソッド: %s\n", method)
+	if len(in.Args) != len(args) {
+		fmt.Fprintf(&b, "  引数の数:\n    - 記録: %d\n    + 実際: %d\n", len(in.Args), len(args))
+		return b.String()
+	}
+	differ := false
+	for i := range args {
+		recorded, err := canonical(in.Args[i])
+		if err != nil {
+			recorded = in.Args[i]
+		}
+		if bytes.Equal(recorded, args[i]) {
+			continue
+		}
+		differ = true
+		fmt.Fprintf(&b, "  引数%d:\n    - 記録: %s\n    + 実際: %s\n", i, recorded, args[i])
+	}
+	if !differ {
+		return ""
+	}
+	return b.String()
+}
+
+// replayedError 記録したメッセージを返すエラー。登録したエラーを含んでいた場合はそれを包む
+type replayedError struct {
+	msg      string
+	sentinel error
+}
+
+func (e *replayedError) Error() string {
+	return e.msg
+}
+
+func (e *replayedError) Unwrap() error {
+	return e.sentinel
+}
@@ -0,0 +1,299 @@
+package cassette
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apbgo/go-study-group/tools/decorgen/decor"
+	"github.com/stretchr/testify/assert"
+)
+
+var errNotFound = errors.New("not found")
+
+type item struct {
+	ID    int64
+	Count int64
+}
+
+// find decorgenで生成したデコレータと同じようにCallを作ってhooksを呼び出す
+func find(hooks decor.Hooks, ctx context.Context, tx *sql.Tx, id int64, next func(int64) (*item, bool, error)) (r0 *item, r1 bool, r2 error) {
+	call := &decor.Call{Context: ctx, Interface: "Repository", Method: "Find", Args: []interface{}{ctx, tx, id}, Out: []interface{}{&r0, &r1}}
+	hooks.Invoke(call, func() error {
+		r0, r1, r2 = next(id)
+		call.Results = []interface{}{r0, r1}
+		return r2
+	})
+	return r0, r1, call.Err
+}
+
+func backend(id int64) (*item, bool, error) {
+	if id == 0 {
+		return nil, false, fmt.Errorf("id=%d: %w", id, errNotFound)
+	}
+	return &item{ID: id, Count: id * 10}, true, nil
+}
+
+// unused 再生中に呼び出されたら失敗させる
+func unused(t *testing.T) func(int64) (*item, bool, error) {
+	return func(id int64) (*item, bool, error) {
+		t.Errorf("再生中に呼び出されました: %d", id)
+		return nil, false, nil
+	}
+}
+
+// tempDir テスト用の一時ディレクトリを作る。終わったらremoveを呼び出す
+func tempDir(t *testing.T) (dir string, remove func()) {
+	dir, err := ioutil.TempDir("", "cassette")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+// recordFind idsの順にFindを呼び出したカセットをpathに記録する
+func recordFind(t *testing.T, path string, ids ...int64) {
+	c, err := New(path, Record)
+	if !assert.NoError(t, err) {
+		return
+	}
+	c.Match(IgnoreType((*sql.Tx)(nil)))
+	c.RegisterError(errNotFound)
+	for _, id := range ids {
+		find(c.Hooks(), context.Background(), &sql.Tx{}, id, backend)
+	}
+	assert.NoError(t, c.Close())
+}
+
+func TestCassette(t *testing.T) {
+	t.Run("記録した結果を再生する", func(t *testing.T) {
+		t.Parallel()
+		dir, remove := tempDir(t)
+		defer remove()
+		path := filepath.Join(dir, "testdata", "find.json")
+		recordFind(t, path, 1, 0)
+
+		c, err := New(path, Replay)
+		if !assert.NoError(t, err) {
+			return
+		}
+		c.Match(IgnoreType((*sql.Tx)(nil)))
+		c.RegisterError(errNotFound)
+		// contextと*sql.Txは記録したときと違う値でも一致する
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		got, ok, err := find(c.Hooks(), ctx, nil, 1, unused(t))
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, &item{ID: 1, Count: 10}, got)
+
+		got, ok, err = find(c.Hooks(), ctx, nil, 0, unused(t))
+		assert.Nil(t, got)
+		assert.False(t, ok)
+		assert.EqualError(t, err, "id=0: not found")
+		assert.True(t, errors.Is(err, errNotFound))
+		assert.NoError(t, c.Close())
+	})
+
+	t.Run("引数が違う場合は差分をエラーにする", func(t *testing.T) {
+		t.Parallel()
+		dir, remove := tempDir(t)
+		defer remove()
+		path := filepath.Join(dir, "find.json")
+		recordFind(t, path, 1)
+
+		c, err := New(path, Replay)
+		if !assert.NoError(t, err) {
+			return
+		}
+		c.Match(IgnoreType((*sql.Tx)(nil)))
+		_, _, err = find(c.Hooks(), context.Background(), nil, 2, unused(t))
+		assert.True(t, errors.Is(err, ErrMismatch))
+		want := "呼び出しが記録と一致しません: 1番目の呼び出し\n" +
+			"  メソッド: Repository.Find\n" +
+			"  引数2:\n" +
+			"    - 記録: 1\n" +
+			"    + 実際: 2\n"
+		assert.Equal(t, want, err.Error())
+		assert.Equal(t, err, c.Close())
+	})
+
+	t.Run("Matchで登録していない引数は値を比べる", func(t *testing.T) {
+		t.Parallel()
+		dir, remove := tempDir(t)
+		defer remove()
+		path := filepath.Join(dir, "find.json")
+		recordFind(t, path, 1)
+
+		c, err := New(path, Replay)
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, _, err = find(c.Hooks(), context.Background(), nil, 1, unused(t))
+		assert.True(t, errors.Is(err, ErrMismatch))
+		assert.Contains(t, err.Error(), "    - 記録: \"*sql.Tx\"\n    + 実際: null\n")
+	})
+
+	t.Run("記録より多く呼び出した", func(t *testing.T) {
+		t.Parallel()
+		dir, remove := tempDir(t)
+		defer remove()
+		path := filepath.Join(dir, "find.json")
+		recordFind(t, path)
+
+		c, err := New(path, Replay)
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, _, err = find(c.Hooks(), context.Background(), nil, 1, unused(t))
+		assert.EqualError(t, err, "呼び出しが記録と一致しません: 1番目の呼び出しRepository.Findは記録されていません")
+	})
+
+	t.Run("再生していない呼び出しが残っている", func(t *testing.T) {
+		t.Parallel()
+		dir, remove := tempDir(t)
+		defer remove()
+		path := filepath.Join(dir, "find.json")
+		recordFind(t, path, 1, 2)
+
+		c, err := New(path, Replay)
+		if !assert.NoError(t, err) {
+			return
+		}
+		c.Match(IgnoreType((*sql.Tx)(nil)))
+		find(c.Hooks(), context.Background(), nil, 1, unused(t))
+		assert.EqualError(t, c.Close(), path+": 再生していない呼び出しが1件あります: Repository.Find")
+	})
+
+	t.Run("Autoはファイルがなければ記録する", func(t *testing.T) {
+		t.Parallel()
+		dir, remove := tempDir(t)
+		defer remove()
+		path := filepath.Join(dir, "find.json")
+
+		c, err := New(path, Auto)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, Record, c.Mode())
+		assert.NoError(t, c.Close())
+
+		c, err = New(path, Auto)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, Replay, c.Mode())
+		assert.NoError(t, c.Close())
+	})
+
+	t.Run("Replayでファイルがない", func(t *testing.T) {
+		t.Parallel()
+		_, err := New(filepath.Join("testdata", "notfound.json"), Replay)
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestCassette_File(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+	path := filepath.Join(dir, "find.json")
+	recordFind(t, path, 1, 0)
+
+	b, err := ioutil.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	want := `{
+  "interactions": [
+    {
+      "method": "Repository.Find",
+      "args": [
+        "context.Context",
+        "*sql.Tx",
+        1
+      ],
+      "results": [
+        {
+          "Count": 10,
+          "ID": 1
+        },
+        true
+      ]
+    },
+    {
+      "method": "Repository.Find",
+      "args": [
+        "context.Context",
+        "*sql.Tx",
+        0
+      ],
+      "results": [
+        null,
+        false
+      ],
+      "error": "id=0: not found",
+      "sentinel": "not found"
+    }
+  ]
+}
+`
+	assert.Equal(t, want, string(b))
+}
+
+func TestIgnoreType(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  interface{}
+		arg  interface{}
+		want interface{}
+		ok   bool
+	}{
+		{name: "同じ型", typ: (*sql.Tx)(nil), arg: &sql.Tx{}, want: "*sql.Tx", ok: true},
+		{name: "nilのポインタ", typ: (*sql.Tx)(nil), arg: (*sql.Tx)(nil), want: "*sql.Tx", ok: true},
+		{name: "違う型", typ: (*sql.Tx)(nil), arg: &sql.DB{}},
+		{name: "インタフェースを実装する", typ: (*context.Context)(nil), arg: context.TODO(), want: "context.Context", ok: true},
+		{name: "nil", typ: (*context.Context)(nil), arg: nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := IgnoreType(tt.typ)(tt.arg)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDiffCall(t *testing.T) {
+	in := Interaction{Method: "Repository.Find", Args: []json.RawMessage{json.RawMessage(`{"b": 1, "a": 2}`)}}
+	tests := []struct {
+		name   string
+		method string
+		args   []string
+		want   string
+	}{
+		{name: "一致する", method: "Repository.Find", args: []string{`{"a":2,"b":1}`}},
+		{name: "メソッドが違う", method: "Repository.Insert", args: []string{`{"a":2,"b":1}`},
+			want: "  メソッド:\n    - 記録: Repository.Find\n    + 実際: Repository.Insert\n"},
+		{name: "引数の数が違う", method: "Repository.Find", args: []string{`{"a":2,"b":1}`, `1`},
+			want: "  メソッド: Repository.Find\n  引数の数:\n    - 記録: 1\n    + 実際: 2\n"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			args := make([]json.RawMessage, len(tt.args))
+			for i, a := range tt.args {
+				args[i] = json.RawMessage(a)
+			}
+			assert.Equal(t, tt.want, diffCall(in, tt.method, args))
+		})
+	}
+}
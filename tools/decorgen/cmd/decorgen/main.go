@@ -0,0 +1,57 @@
+// decorgen インタフェースのデコレータを生成するコマンド
+//
+// mockgenと同様にgo:generateから呼び出す。
+//
+//	//go:generate go run github.com/apbgo/go-study-group/tools/decorgen/cmd/decorgen -type IFCalcService
+//
+// -outputを指定しない場合は、go:generateを書いたファイル名に_decorator.goを付けたファイルに書き出す
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apbgo/go-study-group/tools/decorgen"
+)
+
+var typeNames = flag.String("type", "", "デコレータを生成するインタフェース (カンマ区切りで複数指定できます)")
+var output = flag.String("output", "", "出力するファイル (デフォルトは$GOFILEに_decorator.goを付けたもの)")
+var dir = flag.String("dir", ".", "インタフェースを定義したパッケージのディレクトリ")
+
+func main() {
+	flag.Parse()
+	if *typeNames == "" {
+		fmt.Fprintln(os.Stderr, "-typeを指定してください")
+		flag.Usage()
+		os.Exit(1)
+	}
+	names := strings.Split(*typeNames, ",")
+
+	name := *output
+	if name == "" {
+		base := "decorator.go"
+		if gofile := os.Getenv("GOFILE"); gofile != "" {
+			base = strings.TrimSuffix(gofile, ".go") + "_decorator.go"
+		}
+		name = filepath.Join(*dir, base)
+	}
+
+	pkg, err := decorgen.Load(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	src, err := decorgen.Generate(pkg, names)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := ioutil.WriteFile(name, src, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,193 @@
+// Package decor decorgenで生成したデコレータが呼び出すフック
+//
+// 生成したデコレータはメソッドを呼び出すたびにCallを作り、Hooks.Invokeに渡す。
+// ログ、メトリクス、リトライはHooksとして差し込み、Chainで組み合わせる。
+package decor
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Call デコレータで呼び出したメソッドの情報
+type Call struct {
+	// Context 最初の引数がcontext.Contextの場合はその値。それ以外はnil
+	Context context.Context
+	// Interface インタフェースの名前 (例: IFCalcService)
+	Interface string
+	// Method メソッドの名前
+	Method string
+	// Args 引数。可変長引数は最後の要素にスライスのまま入る
+	Args []interface{}
+	// Results 最後のerrorを除く戻り値。Afterで参照できる
+	Results []interface{}
+	// Out 最後のerrorを除く戻り値を指すポインタ。Replaceでメソッドの代わりに戻り値を設定するために使う
+	Out []interface{}
+	// Err 最後の戻り値がerrorの場合はその値。Afterで参照できる
+	// デコレータはAfterの後のErrを返すので、Afterでエラーを置き換えることもできる
+	Err error
+	// Duration メソッドの実行にかかった時間。Afterで参照できる
+	Duration time.Duration
+	// Attempt 何回目の呼び出しか (1始まり)。Retryでtrueを返すと増える
+	Attempt int
+}
+
+// Name "Interface.Method"の形式の名前を返す
+func (c *Call) Name() string {
+	return c.Interface + "." + c.Method
+}
+
+// Hooks メソッドの前後で呼び出す関数。nilのものは呼び出さない
+type Hooks struct {
+	// Before メソッドを呼び出す前に呼び出す
+	Before func(call *Call)
+	// After メソッドが返った後に呼び出す
+	After func(call *Call)
+	// Retry Afterの後に呼び出し、trueを返すとメソッドをもう一度呼び出す
+	Retry func(call *Call) bool
+	// Replace Beforeの後に呼び出し、trueを返すとメソッドを呼び出さない
+	// その場合はCall.Outの指す先、Call.Results、Call.Errに戻り値を設定する (記録した結果の再生などに使う)
+	Replace func(call *Call) bool
+}
+
+// Invoke hooksを呼び出しながらfnを実行する。fnはメソッドのerrorの戻り値を返す
+// 生成したデコレータから呼び出す
+func (h Hooks) Invoke(call *Call, fn func() error) {
+	for call.Attempt = 1; ; call.Attempt++ {
+		if h.Before != nil {
+			h.Before(call)
+		}
+		start := time.Now()
+		if h.Replace == nil || !h.Replace(call) {
+			call.Err = fn()
+		}
+		call.Duration = time.Since(start)
+		if h.After != nil {
+			h.After(call)
+		}
+		if h.Retry == nil || !h.Retry(call) {
+			return
+		}
+	}
+}
+
+// Chain hooksを順番に呼び出すHooksを作る
+// Afterは内側から外側へ戻るように逆順で呼び出し、RetryとReplaceは最初にtrueを返したものを使う
+func Chain(hooks ...Hooks) Hooks {
+	return Hooks{
+		Before: func(call *Call) {
+			for _, h := range hooks {
+				if h.Before != nil {
+					h.Before(call)
+				}
+			}
+		},
+		After: func(call *Call) {
+			for i := len(hooks) - 1; i >= 0; i-- {
+				if hooks[i].After != nil {
+					hooks[i].After(call)
+				}
+			}
+		},
+		Retry: func(call *Call) bool {
+			for _, h := range hooks {
+				if h.Retry != nil && h.Retry(call) {
+					return true
+				}
+			}
+			return false
+		},
+		Replace: func(call *Call) bool {
+			for _, h := range hooks {
+				if h.Replace != nil && h.Replace(call) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// Logging メソッドが返るたびにloggerへ名前、引数、戻り値、エラー、時間を書き出すHooks
+func Logging(logger *log.Logger) Hooks {
+	return Hooks{
+		After: func(call *Call) {
+			if call.Err != nil {
+				logger.Printf("%s%v -> %v error=%v (%v, attempt=%d)", call.Name(), call.Args, call.Results, call.Err, call.Duration, call.Attempt)
+				return
+			}
+			logger.Printf("%s%v -> %v (%v)", call.Name(), call.Args, call.Results, call.Duration)
+		},
+	}
+}
+
+// Retry エラーがretryableな場合に、最大attempts回まで呼び出すHooks
+// retryableがnilの場合はすべてのエラーでリトライする。Contextが終わっている場合はリトライしない
+func Retry(attempts int, retryable func(error) bool) Hooks {
+	return Hooks{
+		Retry: func(call *Call) bool {
+			if call.Err == nil || call.Attempt >= attempts {
+				return false
+			}
+			if call.Context != nil && call.Context.Err() != nil {
+				return false
+			}
+			return retryable == nil || retryable(call.Err)
+		},
+	}
+}
+
+// Metrics メソッドごとの呼び出し回数、エラー数、合計時間を集計する
+type Metrics struct {
+	mu      sync.Mutex
+	methods map[string]*MethodStats
+}
+
+// MethodStats 1つのメソッドの集計
+type MethodStats struct {
+	Name   string
+	Calls  int
+	Errors int
+	Total  time.Duration
+}
+
+// NewMetrics 空のMetricsを作る
+func NewMetrics() *Metrics {
+	return &Metrics{methods: make(map[string]*MethodStats)}
+}
+
+// Hooks 呼び出しを集計するHooksを返す。リトライした場合はそれぞれを1回と数える
+func (m *Metrics) Hooks() Hooks {
+	return Hooks{
+		After: func(call *Call) {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			name := call.Name()
+			stats, ok := m.methods[name]
+			if !ok {
+				stats = &MethodStats{Name: name}
+				m.methods[name] = stats
+			}
+			stats.Calls++
+			if call.Err != nil {
+				stats.Errors++
+			}
+			stats.Total += call.Duration
+		},
+	}
+}
+
+// Stats 名前の順にメソッドごとの集計を返す
+func (m *Metrics) Stats() []MethodStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := make([]MethodStats, 0, len(m.methods))
+	for _, s := range m.methods {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
@@ -0,0 +1,135 @@
+package decor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks_Invoke(t *testing.T) {
+	t.Run("前後のフックを順番に呼び出す", func(t *testing.T) {
+		t.Parallel()
+		var events []string
+		hook := func(name string) Hooks {
+			return Hooks{
+				Before: func(call *Call) { events = append(events, name+".before") },
+				After:  func(call *Call) { events = append(events, name+".after") },
+			}
+		}
+		call := &Call{Interface: "IFCalcService", Method: "XXX"}
+		Chain(hook("outer"), hook("inner"), Hooks{}).Invoke(call, func() error {
+			events = append(events, "call")
+			call.Results = []interface{}{1}
+			return nil
+		})
+		assert.Equal(t, []string{"outer.before", "inner.before", "call", "inner.after", "outer.after"}, events)
+		assert.Equal(t, 1, call.Attempt)
+		assert.NoError(t, call.Err)
+		assert.True(t, call.Duration >= 0)
+	})
+
+	t.Run("Replaceがtrueを返すとメソッドを呼び出さない", func(t *testing.T) {
+		t.Parallel()
+		var r0 int
+		call := &Call{Out: []interface{}{&r0}}
+		replace := Hooks{Replace: func(call *Call) bool {
+			*call.Out[0].(*int) = 2
+			call.Results = []interface{}{2}
+			call.Err = errors.New("replaced")
+			return true
+		}}
+		Chain(Hooks{Replace: func(*Call) bool { return false }}, replace).Invoke(call, func() error {
+			t.Error("呼び出されました")
+			return nil
+		})
+		assert.Equal(t, 2, r0)
+		assert.Equal(t, []interface{}{2}, call.Results)
+		assert.EqualError(t, call.Err, "replaced")
+	})
+
+	t.Run("フックがなくても呼び出す", func(t *testing.T) {
+		t.Parallel()
+		call := &Call{}
+		Hooks{}.Invoke(call, func() error { return errors.New("failed") })
+		assert.EqualError(t, call.Err, "failed")
+	})
+}
+
+func TestRetry(t *testing.T) {
+	errTemporary := errors.New("temporary")
+	errPermanent := errors.New("permanent")
+	retryable := func(err error) bool { return err == errTemporary }
+
+	tests := []struct {
+		name     string
+		errs     []error
+		ctx      context.Context
+		attempts int
+		err      error
+	}{
+		{name: "成功するまでリトライする", errs: []error{errTemporary, errTemporary, nil}, attempts: 3},
+		{name: "回数の上限で止める", errs: []error{errTemporary, errTemporary, errTemporary, nil}, attempts: 3, err: errTemporary},
+		{name: "リトライできないエラー", errs: []error{errPermanent, nil}, attempts: 1, err: errPermanent},
+		{name: "Contextが終わっている", errs: []error{errTemporary, nil}, ctx: canceledContext(), attempts: 1, err: errTemporary},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var n int
+			call := &Call{Context: tt.ctx}
+			Retry(3, retryable).Invoke(call, func() error {
+				err := tt.errs[n]
+				n++
+				return err
+			})
+			assert.Equal(t, tt.attempts, n)
+			assert.Equal(t, tt.attempts, call.Attempt)
+			assert.Equal(t, tt.err, call.Err)
+		})
+	}
+}
+
+func canceledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+func TestLogging(t *testing.T) {
+	buf := new(bytes.Buffer)
+	hooks := Logging(log.New(buf, "", 0))
+	hooks.Invoke(&Call{Interface: "IFCalcService", Method: "YYY", Args: []interface{}{1, 2}}, func() error { return nil })
+	hooks.Invoke(&Call{Interface: "IFUserItemService", Method: "Provide", Args: []interface{}{int64(1)}}, func() error { return errors.New("deadlock") })
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.True(t, strings.HasPrefix(lines[0], "IFCalcService.YYY[1 2] -> [] ("), lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], "IFUserItemService.Provide[1] -> [] error=deadlock ("), lines[1])
+		assert.True(t, strings.HasSuffix(lines[1], ", attempt=1)"), lines[1])
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics()
+	hooks := metrics.Hooks()
+	for _, err := range []error{nil, errors.New("failed"), nil} {
+		err := err
+		hooks.Invoke(&Call{Interface: "IFCalcService", Method: "YYY"}, func() error { return err })
+	}
+	hooks.Invoke(&Call{Interface: "IFCalcService", Method: "XXX"}, func() error { return nil })
+
+	stats := metrics.Stats()
+	if assert.Len(t, stats, 2) {
+		assert.Equal(t, "IFCalcService.XXX", stats[0].Name)
+		assert.Equal(t, 1, stats[0].Calls)
+		assert.Equal(t, "IFCalcService.YYY", stats[1].Name)
+		assert.Equal(t, 3, stats[1].Calls)
+		assert.Equal(t, 1, stats[1].Errors)
+	}
+}
@@ -0,0 +1,268 @@
+// Package decorgen インタフェースを実装するデコレータのコードを生成する
+//
+// 生成したデコレータは各メソッドでdecor.Callを作り、decor.Hooksの前後のフックを呼び出してから
+// 包んだ実装のメソッドを呼び出す。ログやメトリクス、リトライを手で書かずに差し込むために使う。
+package decorgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// decorPath 生成したコードがimportするフックのパッケージ
+const decorPath = "github.com/apbgo/go-study-group/tools/decorgen/decor"
+
+// Load dirのパッケージを読み込んで型情報を返す
+// 前回生成したファイルが古くなっていても生成し直せるように、型エラーは無視する
+func Load(dir string) (*types.Package, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps,
+		Dir:  dir,
+	}
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 || pkgs[0].Types == nil {
+		return nil, fmt.Errorf("%s: パッケージを読み込めません", dir)
+	}
+	return pkgs[0].Types, nil
+}
+
+// Generate pkgのインタフェースnamesのデコレータを生成する
+// デコレータの名前は"インタフェース名Decorator"、コンストラクタは"New"を付けたものになる
+func Generate(pkg *types.Package, names []string) ([]byte, error) {
+	g := &generator{pkg: pkg, imports: map[string]string{}}
+	g.importName(decorPath, "decor")
+
+	var body bytes.Buffer
+	for _, name := range names {
+		obj := pkg.Scope().Lookup(name)
+		if obj == nil {
+			return nil, fmt.Errorf("%sは%sに定義されていません", name, pkg.Path())
+		}
+		iface, ok := obj.Type().Underlying().(*types.Interface)
+		if !ok {
+			return nil, fmt.Errorf("%sはインタフェースではありません", name)
+		}
+		g.writeDecorator(&body, name, iface.Complete())
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by decorgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkg.Name())
+	buf.WriteString("import (\n")
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	// goimportsと同様に標準パッケージとそれ以外を分ける
+	sort.Slice(paths, func(i, j int) bool {
+		if isStd(paths[i]) != isStd(paths[j]) {
+			return isStd(paths[i])
+		}
+		return paths[i] < paths[j]
+	})
+	for i, path := range paths {
+		if i > 0 && isStd(path) != isStd(paths[i-1]) {
+			buf.WriteString("\n")
+		}
+		name := g.imports[path]
+		if name == defaultImportName(path) {
+			fmt.Fprintf(&buf, "\t%q\n", path)
+		} else {
+			fmt.Fprintf(&buf, "\t%s %q\n", name, path)
+		}
+	}
+	buf.WriteString(")\n")
+	buf.Write(body.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("生成したコードが不正です: %v\n%s", err, buf.Bytes())
+	}
+	return src, nil
+}
+
+type generator struct {
+	pkg *types.Package
+	// imports importするパッケージのパスと名前
+	imports map[string]string
+}
+
+// importName pathのパッケージをimportし、コード中で使う名前を返す
+// 名前が衝突する場合は数字を付けた別名にする
+func (g *generator) importName(path, name string) string {
+	if n, ok := g.imports[path]; ok {
+		return n
+	}
+	used := func(n string) bool {
+		for _, v := range g.imports {
+			if v == n {
+				return true
+			}
+		}
+		return false
+	}
+	n := name
+	for i := 2; used(n); i++ {
+		n = name + strconv.Itoa(i)
+	}
+	g.imports[path] = n
+	return n
+}
+
+func (g *generator) qualifier(pkg *types.Package) string {
+	if pkg == g.pkg {
+		return ""
+	}
+	return g.importName(pkg.Path(), pkg.Name())
+}
+
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, g.qualifier)
+}
+
+// isStd pathが標準パッケージか (最初の要素にドメインのドットがない)
+func isStd(path string) bool {
+	return !strings.Contains(strings.SplitN(path, "/", 2)[0], ".")
+}
+
+// defaultImportName importのパスの最後の要素 (別名を書かなくてよいか判定するため)
+func defaultImportName(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+func (g *generator) writeDecorator(w *bytes.Buffer, name string, iface *types.Interface) {
+	decorator := name + "Decorator"
+	fmt.Fprintf(w, "\n// %s %sの各メソッドの前後でdecor.Hooksを呼び出すデコレータ\n", decorator, name)
+	fmt.Fprintf(w, "type %s struct {\n\tnext  %s\n\thooks decor.Hooks\n}\n", decorator, name)
+	fmt.Fprintf(w, "\n// New%s nextをhooksで包んだ%sを作る\n", decorator, decorator)
+	fmt.Fprintf(w, "func New%s(next %s, hooks decor.Hooks) *%s {\n", decorator, name, decorator)
+	fmt.Fprintf(w, "\treturn &%s{next: next, hooks: hooks}\n}\n", decorator)
+
+	for i := 0; i < iface.NumMethods(); i++ {
+		g.writeMethod(w, name, decorator, iface.Method(i))
+	}
+}
+
+// variable 生成するメソッドの引数と戻り値
+type variable struct {
+	name string
+	typ  string
+}
+
+func (g *generator) writeMethod(w *bytes.Buffer, ifaceName, decorator string, m *types.Func) {
+	sig := m.Type().(*types.Signature)
+
+	// 引数の名前は元の名前を使い、空や生成するコードの名前と衝突する場合はpNにする
+	reserved := map[string]bool{"d": true, "call": true}
+	for _, n := range g.imports {
+		reserved[n] = true
+	}
+	params := make([]variable, sig.Params().Len())
+	for i := range params {
+		p := sig.Params().At(i)
+		name := p.Name()
+		if name == "" || name == "_" || reserved[name] || token.Lookup(name).IsKeyword() {
+			name = "p" + strconv.Itoa(i)
+		}
+		reserved[name] = true
+		typ := g.typeString(p.Type())
+		if sig.Variadic() && i == len(params)-1 {
+			typ = "..." + g.typeString(p.Type().(*types.Slice).Elem())
+		}
+		params[i] = variable{name: name, typ: typ}
+	}
+	results := make([]variable, sig.Results().Len())
+	for i := range results {
+		name := "r" + strconv.Itoa(i)
+		for reserved[name] {
+			name = "_" + name
+		}
+		results[i] = variable{name: name, typ: g.typeString(sig.Results().At(i).Type())}
+	}
+	// 最後の戻り値がerrorであればCall.Errにする
+	errIndex := -1
+	if n := len(results); n > 0 && types.Identical(sig.Results().At(n-1).Type(), types.Universe.Lookup("error").Type()) {
+		errIndex = n - 1
+	}
+
+	var paramList, argNames, callArgs, resultList, resultNames []string
+	for _, p := range params {
+		paramList = append(paramList, p.name+" "+p.typ)
+		argNames = append(argNames, p.name)
+		callArgs = append(callArgs, p.name)
+	}
+	if sig.Variadic() {
+		callArgs[len(callArgs)-1] += "..."
+	}
+	var values, pointers []string
+	for i, r := range results {
+		resultList = append(resultList, r.name+" "+r.typ)
+		if i == errIndex {
+			// Afterで置き換えたエラーを返す
+			resultNames = append(resultNames, "call.Err")
+			continue
+		}
+		resultNames = append(resultNames, r.name)
+		values = append(values, r.name)
+		pointers = append(pointers, "&"+r.name)
+	}
+
+	fmt.Fprintf(w, "\n// %s %s.%sの前後でフックを呼び出す\n", m.Name(), ifaceName, m.Name())
+	fmt.Fprintf(w, "func (d *%s) %s(%s) ", decorator, m.Name(), strings.Join(paramList, ", "))
+	if len(resultList) > 0 {
+		fmt.Fprintf(w, "(%s) ", strings.Join(resultList, ", "))
+	}
+	fmt.Fprintf(w, "{\n")
+	fmt.Fprintf(w, "\tcall := &decor.Call{")
+	if len(params) > 0 && isContext(sig.Params().At(0).Type()) {
+		fmt.Fprintf(w, "Context: %s, ", params[0].name)
+	}
+	fmt.Fprintf(w, "Interface: %q, Method: %q, Args: []interface{}{%s}", ifaceName, m.Name(), strings.Join(argNames, ", "))
+	if len(pointers) > 0 {
+		fmt.Fprintf(w, ", Out: []interface{}{%s}", strings.Join(pointers, ", "))
+	}
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "\td.hooks.Invoke(call, func() error {\n")
+	invoke := fmt.Sprintf("d.next.%s(%s)", m.Name(), strings.Join(callArgs, ", "))
+	if len(results) > 0 {
+		names := make([]string, len(results))
+		for i, r := range results {
+			names[i] = r.name
+		}
+		fmt.Fprintf(w, "\t\t%s = %s\n", strings.Join(names, ", "), invoke)
+	} else {
+		fmt.Fprintf(w, "\t\t%s\n", invoke)
+	}
+	fmt.Fprintf(w, "\t\tcall.Results = []interface{}{%s}\n", strings.Join(values, ", "))
+	if errIndex >= 0 {
+		fmt.Fprintf(w, "\t\treturn %s\n", results[errIndex].name)
+	} else {
+		fmt.Fprintf(w, "\t\treturn nil\n")
+	}
+	fmt.Fprintf(w, "\t})\n")
+	if len(results) > 0 {
+		fmt.Fprintf(w, "\treturn %s\n", strings.Join(resultNames, ", "))
+	}
+	fmt.Fprintf(w, "}\n")
+}
+
+// isContext tがcontext.Contextか
+func isContext(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "context" && obj.Name() == "Context"
+}
@@ -0,0 +1,42 @@
+package decorgen
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// update trueの場合、testdataの期待する出力を生成したコードで書き換える
+var update = flag.Bool("update", false, "testdataの期待する出力を作り直す")
+
+func TestGenerate(t *testing.T) {
+	pkg, err := Load("testdata/src/example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Generate(pkg, []string{"Service"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const golden = "testdata/src/example/example_decorator.go.golden"
+	if *update {
+		if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(want), string(got))
+
+	t.Run("異常系", func(t *testing.T) {
+		_, err := Generate(pkg, []string{"Missing"})
+		assert.EqualError(t, err, "Missingはgithub.com/apbgo/go-study-group/tools/decorgen/testdata/src/exampleに定義されていません")
+		_, err = Generate(pkg, []string{"Reward"})
+		assert.EqualError(t, err, "Rewardはインタフェースではありません")
+	})
+}
@@ -0,0 +1,23 @@
+package example
+
+import (
+	"context"
+	"database/sql"
+	"io"
+)
+
+type Reward struct {
+	ItemID int64
+	Count  int64
+}
+
+// Service デコレータを生成するインタフェース
+// 埋め込み、可変長引数、名前のない引数、生成するコードの名前と衝突する引数を含む
+type Service interface {
+	io.Closer
+	Provide(ctx context.Context, userID int64, rewards ...Reward) error
+	Find(ctx context.Context, tx *sql.Tx, ids []int64) (rewards map[int64]*Reward, ok bool, err error)
+	Name(int, string) string
+	Notify(d, call string, _ func(error))
+	Reset()
+}
@@ -0,0 +1,112 @@
+// go-mutate パッケージのミュータントを作り、テストで検出できなかったものを報告するコマンド
+//
+//	go run ./tools/mutate/cmd/go-mutate ./chapter1
+//	go run ./tools/mutate/cmd/go-mutate -run TestCalc -kind 算術演算子,比較演算子 ./chapter1
+//
+// ソースファイルは書き換えず、go test -overlayで書き換えたファイルに差し替えてテストする。
+// 生き残ったミュータントをfile:line:columnの形式で書き出し、-min-scoreを下回った場合は終了コード1で終わる
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/apbgo/go-study-group/tools/mutate"
+)
+
+var run = flag.String("run", "", "go test -runに渡すテストの名前の正規表現")
+var timeout = flag.Duration("timeout", 0, "書き換える前のテストと1つのミュータントのテストにかける時間 (デフォルトは書き換える前が10分、ミュータントはその実行時間の10倍で最低10秒)")
+var kinds = flag.String("kind", "", "作るミュータントの種類 (カンマ区切り。デフォルトはすべて)")
+var file = flag.String("file", "", "ファイル名にこの文字列を含むファイルだけを対象にする")
+var minScore = flag.Float64("min-score", 0, "スコア(%)がこれを下回ったら終了コード1で終わる")
+var verbose = flag.Bool("v", false, "ミュータントをテストするたびに結果を書き出す")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "使い方: go-mutate [flags] [パッケージのディレクトリ]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	dir := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		dir = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	pkg, err := mutate.Load(dir)
+	if err != nil {
+		fatal(err)
+	}
+	mutants, err := mutate.Generate(pkg)
+	if err != nil {
+		fatal(err)
+	}
+	mutants, err = filter(mutants)
+	if err != nil {
+		fatal(err)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		stop()
+	}()
+
+	runner := &mutate.Runner{Dir: dir, Run: *run, Timeout: *timeout}
+	if *verbose {
+		runner.Progress = os.Stderr
+	}
+	fmt.Fprintf(os.Stderr, "%sのミュータント%d件をテストします\n", pkg.PkgPath, len(mutants))
+	results, err := runner.Test(ctx, mutants)
+	if err != nil {
+		fatal(err)
+	}
+	runner.WriteReport(os.Stdout, results)
+	if mutate.Summarize(results).Score() < *minScore {
+		os.Exit(1)
+	}
+}
+
+// filter -kindと-fileで指定したミュータントだけを残す
+func filter(mutants []*mutate.Mutant) ([]*mutate.Mutant, error) {
+	want := map[string]bool{}
+	if *kinds != "" {
+		all := map[string]bool{}
+		for k := mutate.Arithmetic; k <= mutate.SwapErrBranch; k++ {
+			all[k.String()] = true
+		}
+		for _, k := range strings.Split(*kinds, ",") {
+			if !all[k] {
+				return nil, fmt.Errorf("-kind: %qは指定できません", k)
+			}
+			want[k] = true
+		}
+	}
+	var filtered []*mutate.Mutant
+	for _, m := range mutants {
+		if len(want) > 0 && !want[m.Kind.String()] {
+			continue
+		}
+		if *file != "" && !strings.Contains(m.Pos.Filename, *file) {
+			continue
+		}
+		filtered = append(filtered, m)
+	}
+	return filtered, nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
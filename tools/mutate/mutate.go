@@ -0,0 +1,361 @@
+// Package mutate パッケージのソースを少しずつ書き換えたミュータントを作り、テストで検出できるか調べる
+//
+// 演算子や定数を変えたり文を削除したりしても go test が成功する場合、
+// テストはその部分の間違いを検出できていない(ミュータントが生き残った)ことになる。
+// ソースファイルは書き換えず、go test -overlay で書き換えたファイルに差し替えてテストする。
+package mutate
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Kind ミュータントの種類
+type Kind int
+
+const (
+	// Arithmetic 算術演算子を入れ替える (+と-、*と/など)
+	Arithmetic Kind = iota
+	// Comparison 比較演算子を入れ替える (==と!=、<と<=など)
+	Comparison
+	// Constant 定数を変える (1を2に、trueをfalseになど)
+	Constant
+	// RemoveStatement 文を削除する
+	RemoveStatement
+	// SwapErrBranch if err != nil の分岐を入れ替える
+	SwapErrBranch
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Arithmetic:
+		return "算術演算子"
+	case Comparison:
+		return "比較演算子"
+	case Constant:
+		return "定数"
+	case RemoveStatement:
+		return "文の削除"
+	case SwapErrBranch:
+		return "エラー分岐の入れ替え"
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Mutant ソースを1か所だけ書き換えたもの
+type Mutant struct {
+	Kind Kind
+	// Pos 書き換えた場所 (Filenameは絶対パス)
+	Pos token.Position
+	// Description 書き換えた内容 (例: "+を-に変更")
+	Description string
+	// Source 書き換えた後のファイル全体
+	Source []byte
+}
+
+func (m *Mutant) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", m.Pos.Filename, m.Pos.Line, m.Pos.Column, m.Kind, m.Description)
+}
+
+// Load dirのパッケージをミュータントを作れるように読み込む
+func Load(dir string) (*packages.Package, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps,
+		Dir:  dir,
+	}
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("%s: パッケージを読み込めません", dir)
+	}
+	pkg := pkgs[0]
+	if len(pkg.Errors) > 0 {
+		return nil, fmt.Errorf("%s: %v", dir, pkg.Errors[0])
+	}
+	return pkg, nil
+}
+
+// generated 自動生成したファイルのコメント (https://golang.org/s/generatedcode)
+var generated = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// Generate pkgのテスト以外のファイルからミュータントを作る。自動生成したファイルは対象にしない
+// ミュータントはファイル、行の順に並ぶ
+func Generate(pkg *packages.Package) ([]*Mutant, error) {
+	var mutants []*Mutant
+	for _, file := range pkg.Syntax {
+		if isGenerated(file) {
+			continue
+		}
+		g := &generator{fset: pkg.Fset, info: pkg.TypesInfo, file: file}
+		if err := g.generate(); err != nil {
+			return nil, err
+		}
+		mutants = append(mutants, g.mutants...)
+	}
+	return mutants, nil
+}
+
+func isGenerated(file *ast.File) bool {
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			return false
+		}
+		for _, c := range group.List {
+			if generated.MatchString(c.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// generator 1つのファイルのASTを一時的に書き換えてミュータントを作る
+type generator struct {
+	fset    *token.FileSet
+	info    *types.Info
+	file    *ast.File
+	mutants []*Mutant
+	err     error
+}
+
+// arithmetic 算術演算子の入れ替え先
+var arithmetic = map[token.Token]token.Token{
+	token.ADD:        token.SUB,
+	token.SUB:        token.ADD,
+	token.MUL:        token.QUO,
+	token.QUO:        token.MUL,
+	token.REM:        token.MUL,
+	token.ADD_ASSIGN: token.SUB_ASSIGN,
+	token.SUB_ASSIGN: token.ADD_ASSIGN,
+	token.MUL_ASSIGN: token.QUO_ASSIGN,
+	token.QUO_ASSIGN: token.MUL_ASSIGN,
+}
+
+// comparison 比較演算子の入れ替え先。境界の値の間違いを検出できるかを調べる
+var comparison = map[token.Token]token.Token{
+	token.EQL: token.NEQ,
+	token.NEQ: token.EQL,
+	token.LSS: token.LEQ,
+	token.LEQ: token.LSS,
+	token.GTR: token.GEQ,
+	token.GEQ: token.GTR,
+}
+
+func (g *generator) generate() error {
+	// importのパスや構造体のタグ、if errの条件は他の種類で書き換えない
+	skip := map[ast.Node]bool{}
+	for _, spec := range g.file.Imports {
+		skip[spec.Path] = true
+	}
+	ast.Inspect(g.file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Field:
+			if n.Tag != nil {
+				skip[n.Tag] = true
+			}
+		case *ast.IfStmt:
+			if g.isErrCheck(n.Cond) {
+				skip[n.Cond] = true
+			}
+		}
+		return true
+	})
+
+	ast.Inspect(g.file, func(n ast.Node) bool {
+		if g.err != nil || skip[n] {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.BinaryExpr:
+			g.binary(n)
+		case *ast.AssignStmt:
+			if to, ok := arithmetic[n.Tok]; ok && g.isNumeric(n.Lhs[0]) {
+				from := n.Tok
+				g.mutate(n.TokPos, Arithmetic, fmt.Sprintf("%sを%sに変更", from, to), func() { n.Tok = to }, func() { n.Tok = from })
+			}
+		case *ast.BasicLit:
+			g.literal(n)
+		case *ast.Ident:
+			g.boolean(n)
+		case *ast.IfStmt:
+			if skip[n.Cond] {
+				g.swapErrBranch(n)
+			}
+		case *ast.BlockStmt:
+			g.removeStatements(n.List)
+		case *ast.CaseClause:
+			g.removeStatements(n.Body)
+		case *ast.CommClause:
+			g.removeStatements(n.Body)
+		}
+		return true
+	})
+	return g.err
+}
+
+// mutate applyで書き換えたファイルをミュータントにし、revertで元に戻す
+func (g *generator) mutate(pos token.Pos, kind Kind, description string, apply, revert func()) {
+	apply()
+	var buf bytes.Buffer
+	err := format.Node(&buf, g.fset, g.file)
+	revert()
+	if err != nil {
+		g.err = fmt.Errorf("%s: ミュータントを書き出せません: %v", g.fset.Position(pos), err)
+		return
+	}
+	g.mutants = append(g.mutants, &Mutant{Kind: kind, Pos: g.fset.Position(pos), Description: description, Source: buf.Bytes()})
+}
+
+func (g *generator) binary(n *ast.BinaryExpr) {
+	from := n.Op
+	if to, ok := arithmetic[from]; ok && g.isNumeric(n) {
+		g.mutate(n.OpPos, Arithmetic, fmt.Sprintf("%sを%sに変更", from, to), func() { n.Op = to }, func() { n.Op = from })
+	}
+	if to, ok := comparison[from]; ok {
+		g.mutate(n.OpPos, Comparison, fmt.Sprintf("%sを%sに変更", from, to), func() { n.Op = to }, func() { n.Op = from })
+	}
+}
+
+// isNumeric 文字列の連結のように数値以外の演算子は書き換えない
+func (g *generator) isNumeric(e ast.Expr) bool {
+	basic, ok := g.info.TypeOf(e).Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsNumeric != 0
+}
+
+func (g *generator) literal(n *ast.BasicLit) {
+	from := n.Value
+	var to string
+	switch n.Kind {
+	case token.INT:
+		v, err := strconv.ParseInt(from, 0, 64)
+		if err != nil {
+			return
+		}
+		switch v {
+		case 0:
+			to = "1"
+		case 1:
+			to = "0"
+		default:
+			to = strconv.FormatInt(v+1, 10)
+		}
+	case token.FLOAT:
+		v, err := strconv.ParseFloat(from, 64)
+		if err != nil {
+			return
+		}
+		to = strconv.FormatFloat(v+1, 'g', -1, 64)
+		if !strings.ContainsAny(to, ".e") {
+			to += ".0"
+		}
+	case token.STRING:
+		if s, err := strconv.Unquote(from); err == nil && s == "" {
+			to = `"mutated"`
+		} else {
+			to = `""`
+		}
+	default:
+		return
+	}
+	g.mutate(n.ValuePos, Constant, fmt.Sprintf("%sを%sに変更", from, to), func() { n.Value = to }, func() { n.Value = from })
+}
+
+func (g *generator) boolean(n *ast.Ident) {
+	obj := g.info.Uses[n]
+	if obj == nil || obj.Parent() != types.Universe {
+		return
+	}
+	from := n.Name
+	var to string
+	switch from {
+	case "true":
+		to = "false"
+	case "false":
+		to = "true"
+	default:
+		return
+	}
+	g.mutate(n.NamePos, Constant, fmt.Sprintf("%sを%sに変更", from, to), func() { n.Name = to }, func() { n.Name = from })
+}
+
+// isErrCheck condが"err != nil"や"nil == err"のようなerrorとnilの比較か
+func (g *generator) isErrCheck(cond ast.Expr) bool {
+	b, ok := cond.(*ast.BinaryExpr)
+	if !ok || (b.Op != token.NEQ && b.Op != token.EQL) {
+		return false
+	}
+	x, y := b.X, b.Y
+	if g.isNil(x) {
+		x, y = y, x
+	}
+	errorType := types.Universe.Lookup("error").Type()
+	return g.isNil(y) && types.Identical(g.info.TypeOf(x), errorType)
+}
+
+func (g *generator) isNil(e ast.Expr) bool {
+	id, ok := e.(*ast.Ident)
+	return ok && g.info.Uses[id] == types.Universe.Lookup("nil")
+}
+
+// swapErrBranch elseがあれば本体と入れ替え、なければ条件を逆にする
+func (g *generator) swapErrBranch(n *ast.IfStmt) {
+	cond := n.Cond.(*ast.BinaryExpr)
+	description := fmt.Sprintf("if %s の分岐を入れ替え", g.source(cond))
+	if els, ok := n.Else.(*ast.BlockStmt); ok {
+		body := n.Body
+		swap := func() { n.Body, n.Else = els, body }
+		g.mutate(n.If, SwapErrBranch, description, swap, func() { n.Body, n.Else = body, els })
+		return
+	}
+	if n.Else != nil {
+		// else ifの場合は入れ替えられない
+		return
+	}
+	from := cond.Op
+	to := comparison[from]
+	g.mutate(n.If, SwapErrBranch, description, func() { cond.Op = to }, func() { cond.Op = from })
+}
+
+// removeStatements 削除しても構文が正しいままの文を1つずつ空の文にする
+// 変数の宣言やreturnは削除するとコンパイルできないので対象にしない
+func (g *generator) removeStatements(list []ast.Stmt) {
+	for i, stmt := range list {
+		switch s := stmt.(type) {
+		case *ast.ExprStmt, *ast.IncDecStmt, *ast.SendStmt, *ast.DeferStmt, *ast.GoStmt:
+		case *ast.AssignStmt:
+			if s.Tok == token.DEFINE {
+				continue
+			}
+		default:
+			continue
+		}
+		i, stmt := i, stmt
+		g.mutate(stmt.Pos(), RemoveStatement, fmt.Sprintf("%sを削除", g.source(stmt)),
+			func() { list[i] = &ast.EmptyStmt{Semicolon: stmt.Pos(), Implicit: true} },
+			func() { list[i] = stmt })
+	}
+}
+
+// source nodeのソースを1行にして返す。長い場合は省略する
+func (g *generator) source(node ast.Node) string {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, g.fset, node); err != nil {
+		return "?"
+	}
+	s := strings.Join(strings.Fields(buf.String()), " ")
+	if r := []rune(s); len(r) > 40 {
+		s = string(r[:40]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+package mutate
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// summary テストで比べるミュータントの内容
+type summary struct {
+	Kind        Kind
+	File        string
+	Line        int
+	Description string
+}
+
+func TestGenerate(t *testing.T) {
+	pkg, err := Load("testdata/src/example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutants, err := Generate(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]summary, len(mutants))
+	for i, m := range mutants {
+		got[i] = summary{Kind: m.Kind, File: filepath.Base(m.Pos.Filename), Line: m.Pos.Line, Description: m.Description}
+	}
+	// importのパス、構造体のタグ、文字列の連結、自動生成したファイルからは作らない
+	want := []summary{
+		{Comparison, "example.go", 15, "<を<=に変更"},
+		{Constant, "example.go", 15, "0を1に変更"},
+		{Constant, "example.go", 23, "0を1に変更"},
+		{RemoveStatement, "example.go", 25, "total += vを削除"},
+		{Arithmetic, "example.go", 25, "+=を-=に変更"},
+		{Comparison, "example.go", 32, "==を!=に変更"},
+		{Constant, "example.go", 32, `""を"mutated"に変更`},
+		{Constant, "example.go", 33, "0を1に変更"},
+		{Constant, "example.go", 33, `"empty"を""に変更`},
+		{SwapErrBranch, "example.go", 36, "if err != nil の分岐を入れ替え"},
+		{Constant, "example.go", 37, "0を1に変更"},
+		{Constant, "example.go", 44, `"hello, "を""に変更`},
+		{Constant, "example.go", 50, "0を1に変更"},
+		{Constant, "example.go", 51, `"zero"を""に変更`},
+		{Constant, "example.go", 52, "1を0に変更"},
+		{Constant, "example.go", 53, `"one"を""に変更`},
+		{Constant, "example.go", 55, `"many"を""に変更`},
+		{Constant, "example.go", 60, "0を1に変更"},
+		{Constant, "example.go", 60, "0を1に変更"},
+		{Comparison, "example.go", 61, "<を<=に変更"},
+		{RemoveStatement, "example.go", 62, "i++を削除"},
+		{RemoveStatement, "example.go", 63, "c++を削除"},
+	}
+	assert.Equal(t, want, got)
+
+	t.Run("ミュータントのソース", func(t *testing.T) {
+		// 書き換えた1か所以外は元のソースのまま
+		src := string(mutants[4].Source)
+		assert.Contains(t, src, "\t\ttotal -= v\n")
+		assert.Contains(t, src, "\tif x < 0 {\n")
+		assert.Contains(t, src, "`json:\"name\"`")
+		assert.Contains(t, string(mutants[9].Source), "\tif err == nil {\n\t\treturn 0, err\n\t}\n")
+		assert.NotContains(t, string(mutants[20].Source), "\t\ti++\n")
+		assert.True(t, strings.HasSuffix(mutants[0].String(), "example.go:15:7: 比較演算子: <を<=に変更"), mutants[0].String())
+	})
+}
+
+func TestSwapErrBranch(t *testing.T) {
+	pkg, err := Load("testdata/src/errbranch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutants, err := Generate(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var swapped []string
+	for _, m := range mutants {
+		if m.Kind == SwapErrBranch {
+			swapped = append(swapped, m.Description)
+			assert.Contains(t, string(m.Source), "\tif err != nil {\n\t\treturn \"ok\"\n\t} else {\n\t\treturn err.Error()\n\t}\n")
+		}
+	}
+	// else ifがある場合は入れ替えない
+	assert.Equal(t, []string{"if err != nil の分岐を入れ替え"}, swapped)
+}
+
+func TestKind_String(t *testing.T) {
+	tests := []struct {
+		kind Kind
+		want string
+	}{
+		{Arithmetic, "算術演算子"},
+		{Comparison, "比較演算子"},
+		{Constant, "定数"},
+		{RemoveStatement, "文の削除"},
+		{SwapErrBranch, "エラー分岐の入れ替え"},
+		{Kind(10), "Kind(10)"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.kind.String())
+	}
+}
@@ -0,0 +1,239 @@
+package mutate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Status ミュータントをテストした結果
+type Status int
+
+const (
+	// Killed テストが失敗した (ミュータントを検出できた)
+	Killed Status = iota
+	// Survived テストが成功した (ミュータントを検出できなかった)
+	Survived
+	// Timeout テストが時間内に終わらなかった。無限ループなどを検出できたとみなす
+	Timeout
+	// Invalid コンパイルできなかった。スコアには含めない
+	Invalid
+)
+
+func (s Status) String() string {
+	switch s {
+	case Killed:
+		return "検出"
+	case Survived:
+		return "生き残り"
+	case Timeout:
+		return "タイムアウト"
+	case Invalid:
+		return "コンパイルエラー"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// Result 1つのミュータントをテストした結果
+type Result struct {
+	Mutant *Mutant
+	Status Status
+}
+
+// ErrBaseline 書き換える前のテストが失敗した場合のエラー
+var ErrBaseline = errors.New("書き換える前のテストが失敗しています")
+
+// DefaultBaselineTimeout Runner.Timeoutを指定しない場合に、書き換える前のテストにかける時間 (go testのデフォルトと同じ)
+const DefaultBaselineTimeout = 10 * time.Minute
+
+// Runner ミュータントごとにgo testを実行する
+type Runner struct {
+	// Dir テストするパッケージのディレクトリ
+	Dir string
+	// Run go test -runに渡すテストの名前の正規表現。空の場合はすべてのテストを実行する
+	Run string
+	// Timeout 書き換える前のテストと1つのミュータントのテストにかける時間
+	// 0の場合、書き換える前のテストはDefaultBaselineTimeout、ミュータントはそれにかかった時間の10倍(最低10秒)
+	Timeout time.Duration
+	// Progress nilでなければミュータントをテストするたびに結果を書き出す
+	Progress io.Writer
+}
+
+// Test 書き換える前のテストが成功することを確かめてから、mutantsを1つずつテストする
+func (r *Runner) Test(ctx context.Context, mutants []*Mutant) ([]Result, error) {
+	tmp, err := ioutil.TempDir("", "go-mutate")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	// テストが終わらないパッケージで止まったままにならないよう、書き換える前のテストも時間を制限する
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultBaselineTimeout
+	}
+	start := time.Now()
+	if status, out, err := r.goTest(ctx, "", timeout); err != nil {
+		return nil, err
+	} else if status == Timeout {
+		return nil, fmt.Errorf("%w (%vで終わりませんでした)\n%s", ErrBaseline, timeout, out)
+	} else if status != Survived {
+		return nil, fmt.Errorf("%w\n%s", ErrBaseline, out)
+	}
+	if r.Timeout <= 0 {
+		timeout = 10 * time.Since(start)
+		if timeout < 10*time.Second {
+			timeout = 10 * time.Second
+		}
+	}
+
+	results := make([]Result, 0, len(mutants))
+	for i, m := range mutants {
+		overlay, err := writeOverlay(tmp, m)
+		if err != nil {
+			return nil, err
+		}
+		status, _, err := r.goTest(ctx, overlay, timeout)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, Result{Mutant: m, Status: status})
+		if r.Progress != nil {
+			fmt.Fprintf(r.Progress, "[%d/%d] %s: %s\n", i+1, len(mutants), status, r.relative(m))
+		}
+	}
+	return results, nil
+}
+
+// writeOverlay mのソースとgo test -overlayに渡すJSONをdirに書き出し、JSONのパスを返す
+func writeOverlay(dir string, m *Mutant) (string, error) {
+	src := filepath.Join(dir, filepath.Base(m.Pos.Filename))
+	if err := ioutil.WriteFile(src, m.Source, 0644); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(map[string]map[string]string{"Replace": {m.Pos.Filename: src}})
+	if err != nil {
+		return "", err
+	}
+	overlay := filepath.Join(dir, "overlay.json")
+	return overlay, ioutil.WriteFile(overlay, b, 0644)
+}
+
+// goTest overlayを指定してgo testを実行する。timeoutが0の場合は時間を制限しない
+// go testを実行できない場合だけエラーを返す
+func (r *Runner) goTest(ctx context.Context, overlay string, timeout time.Duration) (Status, []byte, error) {
+	args := []string{"test", "-count=1"}
+	if timeout > 0 {
+		// テストのバイナリを確実に止めるためgo test -timeoutで制限し、
+		// コンパイルが終わらない場合に備えてgoコマンドも余裕を持って止める
+		args = append(args, "-timeout="+timeout.String())
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 2*timeout+time.Minute)
+		defer cancel()
+	}
+	if overlay != "" {
+		args = append(args, "-overlay="+overlay)
+	}
+	if r.Run != "" {
+		args = append(args, "-run="+r.Run)
+	}
+	args = append(args, ".")
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = r.Dir
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return Timeout, out.Bytes(), nil
+	}
+	if ctx.Err() != nil {
+		return 0, nil, ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return 0, nil, err
+	}
+	if err == nil {
+		return Survived, out.Bytes(), nil
+	}
+	if isBuildFailure(out.String()) {
+		return Invalid, out.Bytes(), nil
+	}
+	if strings.Contains(out.String(), "panic: test timed out") {
+		return Timeout, out.Bytes(), nil
+	}
+	return Killed, out.Bytes(), nil
+}
+
+// isBuildFailure go testの出力がコンパイルエラーによるものか
+// go vetのエラーもテストを実行する前に起きるのでコンパイルエラーとして扱う
+func isBuildFailure(out string) bool {
+	return strings.Contains(out, "[build failed]") || strings.Contains(out, "[setup failed]")
+}
+
+func (r *Runner) relative(m *Mutant) string {
+	name := m.Pos.Filename
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, name); err == nil && !strings.HasPrefix(rel, "..") {
+			name = rel
+		}
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", name, m.Pos.Line, m.Pos.Column, m.Kind, m.Description)
+}
+
+// Summary 結果の件数
+type Summary struct {
+	Total, Killed, Survived, Timeout, Invalid int
+}
+
+// Summarize resultsの件数を数える
+func Summarize(results []Result) Summary {
+	s := Summary{Total: len(results)}
+	for _, r := range results {
+		switch r.Status {
+		case Killed:
+			s.Killed++
+		case Survived:
+			s.Survived++
+		case Timeout:
+			s.Timeout++
+		case Invalid:
+			s.Invalid++
+		}
+	}
+	return s
+}
+
+// Score コンパイルできたミュータントのうち検出できた割合 (0から100)。対象がない場合は100
+func (s Summary) Score() float64 {
+	n := s.Total - s.Invalid
+	if n == 0 {
+		return 100
+	}
+	return float64(s.Killed+s.Timeout) * 100 / float64(n)
+}
+
+// WriteReport 生き残ったミュータントをfile:line:columnの形式で書き出し、最後に件数とスコアを書き出す
+func (r *Runner) WriteReport(w io.Writer, results []Result) {
+	s := Summarize(results)
+	if s.Survived > 0 {
+		fmt.Fprintln(w, "生き残ったミュータント:")
+		for _, res := range results {
+			if res.Status == Survived {
+				fmt.Fprintf(w, "  %s\n", r.relative(res.Mutant))
+			}
+		}
+	}
+	fmt.Fprintf(w, "ミュータント %d件: 検出 %d, 生き残り %d, タイムアウト %d, コンパイルエラー %d (スコア %.1f%%)\n",
+		s.Total, s.Killed, s.Survived, s.Timeout, s.Invalid, s.Score())
+}
@@ -0,0 +1,95 @@
+package mutate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_Test(t *testing.T) {
+	if testing.Short() {
+		t.Skip("ミュータントごとにgo testを実行するので時間がかかる")
+	}
+	pkg, err := Load("testdata/src/example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutants, err := Generate(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	progress := new(bytes.Buffer)
+	runner := &Runner{Dir: "testdata/src/example", Timeout: 2 * time.Second, Progress: progress}
+	results, err := runner.Test(context.Background(), mutants)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := make([]Status, len(results))
+	for i, r := range results {
+		statuses[i] = r.Status
+	}
+	assert.Equal(t, []Status{
+		Survived, Survived, Killed, Invalid, Survived,
+		Killed, Survived, Survived, Survived, Killed,
+		Survived, Killed, Invalid, Killed, Invalid,
+		Killed, Killed, Killed, Killed, Killed,
+		Timeout, Killed,
+	}, statuses)
+	assert.Contains(t, progress.String(), "[22/22] 検出: "+filepath.Join("testdata", "src", "example", "example.go")+":63:3: 文の削除: c++を削除\n")
+
+	out := new(bytes.Buffer)
+	runner.WriteReport(out, results)
+	file := filepath.Join("testdata", "src", "example", "example.go")
+	assert.Equal(t, "生き残ったミュータント:\n"+
+		"  "+file+":15:7: 比較演算子: <を<=に変更\n"+
+		"  "+file+":15:9: 定数: 0を1に変更\n"+
+		"  "+file+":25:9: 算術演算子: +=を-=に変更\n"+
+		"  "+file+":32:10: 定数: \"\"を\"mutated\"に変更\n"+
+		"  "+file+":33:10: 定数: 0を1に変更\n"+
+		"  "+file+":33:24: 定数: \"empty\"を\"\"に変更\n"+
+		"  "+file+":37:10: 定数: 0を1に変更\n"+
+		"ミュータント 22件: 検出 11, 生き残り 7, タイムアウト 1, コンパイルエラー 3 (スコア 63.2%)\n", out.String())
+}
+
+func TestRunner_Test_Baseline(t *testing.T) {
+	runner := &Runner{Dir: "testdata/src/failing"}
+	_, err := runner.Test(context.Background(), nil)
+	assert.True(t, errors.Is(err, ErrBaseline), err)
+}
+
+func TestRunner_Test_BaselineTimeout(t *testing.T) {
+	runner := &Runner{Dir: "testdata/src/hanging", Timeout: 2 * time.Second}
+	_, err := runner.Test(context.Background(), nil)
+	assert.True(t, errors.Is(err, ErrBaseline), err)
+	assert.Contains(t, err.Error(), "2sで終わりませんでした")
+}
+
+func TestSummary_Score(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary Summary
+		want    float64
+	}{
+		{name: "タイムアウトは検出とみなす", summary: Summary{Total: 5, Killed: 2, Timeout: 1, Survived: 1, Invalid: 1}, want: 75},
+		{name: "すべて生き残り", summary: Summary{Total: 2, Survived: 2}, want: 0},
+		{name: "対象がない", summary: Summary{Total: 1, Invalid: 1}, want: 100},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, tt.summary.Score())
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	results := []Result{{Status: Killed}, {Status: Survived}, {Status: Killed}, {Status: Timeout}, {Status: Invalid}}
+	assert.Equal(t, Summary{Total: 5, Killed: 2, Survived: 1, Timeout: 1, Invalid: 1}, Summarize(results))
+}
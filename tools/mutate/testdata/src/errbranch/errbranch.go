@@ -0,0 +1,20 @@
+package errbranch
+
+// Describe elseがある場合は本体と入れ替える
+func Describe(err error) string {
+	if err != nil {
+		return err.Error()
+	} else {
+		return "ok"
+	}
+}
+
+// Check else ifがある場合は入れ替えない
+func Check(err error, ok bool) string {
+	if err != nil {
+		return "error"
+	} else if ok {
+		return "ok"
+	}
+	return "ng"
+}
@@ -0,0 +1,66 @@
+package example
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Config ミュータントを作らないタグと文字列の連結を含む
+type Config struct {
+	Name string `json:"name"`
+}
+
+// Abs xの絶対値を返す
+func Abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Sum sliceの合計を返す
+func Sum(slice []int) int {
+	total := 0
+	for _, v := range slice {
+		total += v
+	}
+	return total
+}
+
+// Parse sを数値にする。空の場合はエラーにする
+func Parse(s string) (int, error) {
+	if s == "" {
+		return 0, errors.New("empty")
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Greet 挨拶を返す
+func Greet(c Config) string {
+	return "hello, " + c.Name
+}
+
+// Name 番号の名前を返す。0と1を入れ替えるとcaseが重複してコンパイルできない
+func Name(n int) string {
+	switch n {
+	case 0:
+		return "zero"
+	case 1:
+		return "one"
+	}
+	return "many"
+}
+
+// Count nまで数えた回数を返す。i++を削除すると終わらなくなる
+func Count(n int) int {
+	i, c := 0, 0
+	for i < n {
+		i++
+		c++
+	}
+	return c
+}
@@ -0,0 +1,43 @@
+package example
+
+import "testing"
+
+func TestAbs(t *testing.T) {
+	if Abs(-2) != 2 || Abs(3) != 3 {
+		t.Error("Abs")
+	}
+}
+
+// TestSum 空のスライスしか試さないので、足し算を引き算にしても検出できない
+func TestSum(t *testing.T) {
+	if Sum(nil) != 0 {
+		t.Error("Sum")
+	}
+}
+
+func TestParse(t *testing.T) {
+	if n, err := Parse("12"); n != 12 || err != nil {
+		t.Error("Parse")
+	}
+	if _, err := Parse("x"); err == nil {
+		t.Error("Parse")
+	}
+}
+
+func TestGreet(t *testing.T) {
+	if Greet(Config{Name: "go"}) != "hello, go" {
+		t.Error("Greet")
+	}
+}
+
+func TestName(t *testing.T) {
+	if Name(0) != "zero" || Name(1) != "one" || Name(2) != "many" {
+		t.Error("Name")
+	}
+}
+
+func TestCount(t *testing.T) {
+	if Count(3) != 3 {
+		t.Error("Count")
+	}
+}
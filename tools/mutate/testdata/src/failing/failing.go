@@ -0,0 +1,6 @@
+package failing
+
+// One 1を返すはずが間違っている
+func One() int {
+	return 2
+}
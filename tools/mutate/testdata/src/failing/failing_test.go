@@ -0,0 +1,9 @@
+package failing
+
+import "testing"
+
+func TestOne(t *testing.T) {
+	if One() != 1 {
+		t.Error("One")
+	}
+}
@@ -0,0 +1,8 @@
+package hanging
+
+import "time"
+
+// Wait 終わらない処理
+func Wait() {
+	time.Sleep(time.Hour)
+}
@@ -0,0 +1,7 @@
+package hanging
+
+import "testing"
+
+func TestWait(t *testing.T) {
+	Wait()
+}
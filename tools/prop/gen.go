@@ -0,0 +1,245 @@
+package prop
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+)
+
+// DefaultAlphabet Stringで使う文字。ASCIIに加えてひらがな、カタカナ、漢字、4バイトの文字を含む
+const DefaultAlphabet = "abcxyzABCXYZ0189 _-.,:\t" + "あいうアイウ日本語漢字" + "éü" + "😀🍣"
+
+// Gen 値を生成するジェネレータ。プロパティが成り立たなかった値を小さくする方法も持つ
+type Gen struct {
+	typ      reflect.Type
+	generate func(r *rand.Rand, size int) interface{}
+	// shrink vより小さい値の候補を小さいものから順に返す
+	shrink func(v interface{}) []interface{}
+}
+
+// Generate rを使って値を1つ生成する。sizeは0から100で、大きいほど長いスライスや文字列を生成する
+func (g Gen) Generate(r *rand.Rand, size int) interface{} {
+	return g.generate(r, size)
+}
+
+// Shrink vより小さい値の候補を返す
+func (g Gen) Shrink(v interface{}) []interface{} {
+	if g.shrink == nil {
+		return nil
+	}
+	return g.shrink(v)
+}
+
+// Int minからmaxまでのintを生成する。0に近づくように小さくする
+// minがmaxより大きい場合はパニックする
+func Int(min, max int) Gen {
+	if min > max {
+		panic(fmt.Sprintf("prop.Int: minの%dがmaxの%dより大きくなっています", min, max))
+	}
+	// 範囲に0を含まない場合は0に近い方の端に近づける
+	target := 0
+	if min > 0 {
+		target = min
+	} else if max < 0 {
+		target = max
+	}
+	// span 範囲の幅-1。Int(math.MinInt64, math.MaxInt64)でもあふれないように符号なしで持つ
+	span := uint64(max) - uint64(min)
+	return Gen{
+		typ: reflect.TypeOf(0),
+		generate: func(r *rand.Rand, size int) interface{} {
+			// 境界の値は間違いやすいので多めに生成する
+			switch r.Intn(10) {
+			case 0:
+				return min
+			case 1:
+				return max
+			case 2:
+				return target
+			}
+			return min + int(randUint64n(r, span))
+		},
+		shrink: func(v interface{}) []interface{} {
+			return shrinkInt(v.(int), target)
+		},
+	}
+}
+
+// randUint64n 0からmaxまでの値を一様に生成する
+func randUint64n(r *rand.Rand, max uint64) uint64 {
+	if max < math.MaxInt64 {
+		return uint64(r.Int63n(int64(max) + 1))
+	}
+	// 範囲が半分以上なので、範囲外の値を捨てても平均2回以内で生成できる
+	for {
+		if n := r.Uint64(); n <= max {
+			return n
+		}
+	}
+}
+
+// shrinkInt targetから始めて、vとの差を半分ずつにした値を返す
+// 差はintに収まらないことがあるので符号なしで計算する
+func shrinkInt(v, target int) []interface{} {
+	var candidates []interface{}
+	if v >= target {
+		for d := uint64(v) - uint64(target); d != 0; d /= 2 {
+			candidates = append(candidates, v-int(d))
+		}
+	} else {
+		for d := uint64(target) - uint64(v); d != 0; d /= 2 {
+			candidates = append(candidates, v+int(d))
+		}
+	}
+	return candidates
+}
+
+// String DefaultAlphabetの文字からなる、minLenからmaxLen文字の文字列を生成する
+func String(minLen, maxLen int) Gen {
+	return StringOf(DefaultAlphabet, minLen, maxLen)
+}
+
+// StringOf charsの文字からなる、minLenからmaxLen文字(バイトではなくルーン)の文字列を生成する
+// 文字を取り除き、残った文字をcharsの最初の文字に置き換えるように小さくする
+func StringOf(chars string, minLen, maxLen int) Gen {
+	runes := []rune(chars)
+	return Gen{
+		typ: reflect.TypeOf(""),
+		generate: func(r *rand.Rand, size int) interface{} {
+			n := genLen(r, size, minLen, maxLen)
+			s := make([]rune, n)
+			for i := range s {
+				s[i] = runes[r.Intn(len(runes))]
+			}
+			return string(s)
+		},
+		shrink: func(v interface{}) []interface{} {
+			s := []rune(v.(string))
+			var candidates []interface{}
+			for _, c := range removeChunks(len(s), minLen) {
+				candidates = append(candidates, string(append(append([]rune{}, s[:c.from]...), s[c.to:]...)))
+			}
+			for i, c := range s {
+				if c != runes[0] {
+					t := append([]rune{}, s...)
+					t[i] = runes[0]
+					candidates = append(candidates, string(t))
+				}
+			}
+			return candidates
+		},
+	}
+}
+
+// SliceOf elemで生成した値を要素とする、minLenからmaxLen個のスライスを生成する
+// スライスの型はelemの値の型のスライス(Int()なら[]int)になる
+// 要素を取り除いてから、残った要素をelemで小さくする
+func SliceOf(elem Gen, minLen, maxLen int) Gen {
+	typ := reflect.SliceOf(elem.typ)
+	return Gen{
+		typ: typ,
+		generate: func(r *rand.Rand, size int) interface{} {
+			n := genLen(r, size, minLen, maxLen)
+			s := reflect.MakeSlice(typ, n, n)
+			for i := 0; i < n; i++ {
+				s.Index(i).Set(reflect.ValueOf(elem.Generate(r, size)))
+			}
+			return s.Interface()
+		},
+		shrink: func(v interface{}) []interface{} {
+			s := reflect.ValueOf(v)
+			n := s.Len()
+			var candidates []interface{}
+			for _, c := range removeChunks(n, minLen) {
+				t := reflect.MakeSlice(typ, 0, n-(c.to-c.from))
+				t = reflect.AppendSlice(t, s.Slice(0, c.from))
+				t = reflect.AppendSlice(t, s.Slice(c.to, n))
+				candidates = append(candidates, t.Interface())
+			}
+			for i := 0; i < n; i++ {
+				for _, e := range elem.Shrink(s.Index(i).Interface()) {
+					t := reflect.MakeSlice(typ, n, n)
+					reflect.Copy(t, s)
+					t.Index(i).Set(reflect.ValueOf(e))
+					candidates = append(candidates, t.Interface())
+				}
+			}
+			return candidates
+		},
+	}
+}
+
+// CSVLine delimiterで区切ったminFieldsからmaxFields個のフィールドからなる1行を生成する
+// フィールドはString(0, 8)の文字のうち、delimiterと改行を含まないものにする
+// フィールドが0個の行と空のフィールドが1個の行は区別できないので、minFieldsが1より小さい場合はパニックする
+func CSVLine(delimiter string, minFields, maxFields int) Gen {
+	if minFields < 1 {
+		panic(fmt.Sprintf("prop.CSVLine: minFieldsには1以上を指定してください (%d)", minFields))
+	}
+	chars := strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || strings.ContainsRune(delimiter, r) {
+			return -1
+		}
+		return r
+	}, DefaultAlphabet)
+	fields := SliceOf(StringOf(chars, 0, 8), minFields, maxFields)
+	return Gen{
+		typ: reflect.TypeOf(""),
+		generate: func(r *rand.Rand, size int) interface{} {
+			return strings.Join(fields.Generate(r, size).([]string), delimiter)
+		},
+		shrink: func(v interface{}) []interface{} {
+			var candidates []interface{}
+			for _, f := range fields.Shrink(strings.Split(v.(string), delimiter)) {
+				candidates = append(candidates, strings.Join(f.([]string), delimiter))
+			}
+			return candidates
+		},
+	}
+}
+
+// OneOf valuesのいずれかを生成する。前にある値ほど小さいとみなす
+// valuesはすべて同じ型にする
+func OneOf(values ...interface{}) Gen {
+	return Gen{
+		typ: reflect.TypeOf(values[0]),
+		generate: func(r *rand.Rand, size int) interface{} {
+			return values[r.Intn(len(values))]
+		},
+		shrink: func(v interface{}) []interface{} {
+			for i, value := range values {
+				if reflect.DeepEqual(value, v) {
+					return values[:i:i]
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// genLen sizeに応じてminLenからmaxLenまでの長さを選ぶ
+func genLen(r *rand.Rand, size, minLen, maxLen int) int {
+	max := minLen + (maxLen-minLen)*size/100
+	return minLen + r.Intn(max-minLen+1)
+}
+
+// chunk 取り除く範囲 [from, to)
+type chunk struct {
+	from, to int
+}
+
+// removeChunks 長さnから取り除く範囲を、大きいものから順に返す。残りがminLenより短くなるものは除く
+func removeChunks(n, minLen int) []chunk {
+	var chunks []chunk
+	for size := n; size > 0; size /= 2 {
+		if n-size < minLen {
+			continue
+		}
+		for from := 0; from+size <= n; from += size {
+			chunks = append(chunks, chunk{from: from, to: from + size})
+		}
+	}
+	return chunks
+}
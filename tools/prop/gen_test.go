@@ -0,0 +1,167 @@
+package prop
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInt(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max int
+		v        int
+		shrink   []interface{}
+	}{
+		{name: "0に近づける", min: -100, max: 100, v: 10, shrink: []interface{}{0, 5, 8, 9}},
+		{name: "負の数", min: -100, max: 100, v: -5, shrink: []interface{}{0, -3, -4}},
+		{name: "範囲が正の数", min: 3, max: 10, v: 7, shrink: []interface{}{3, 5, 6}},
+		{name: "範囲が負の数", min: -10, max: -3, v: -7, shrink: []interface{}{-3, -5, -6}},
+		{name: "これ以上小さくならない", min: -100, max: 100, v: 0},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			g := Int(tt.min, tt.max)
+			assert.Equal(t, tt.shrink, g.Shrink(tt.v))
+			r := rand.New(rand.NewSource(1))
+			for i := 0; i < 100; i++ {
+				v := g.Generate(r, i).(int)
+				assert.True(t, tt.min <= v && v <= tt.max, v)
+			}
+		})
+	}
+}
+
+func TestInt_Extremes(t *testing.T) {
+	const (
+		maxInt = int(^uint(0) >> 1)
+		minInt = -maxInt - 1
+	)
+
+	t.Run("範囲がintの全体", func(t *testing.T) {
+		t.Parallel()
+		tests := []struct {
+			min, max int
+		}{
+			{min: 0, max: maxInt},
+			{min: minInt, max: 0},
+			{min: minInt, max: maxInt},
+			{min: -1, max: maxInt},
+		}
+		r := rand.New(rand.NewSource(1))
+		for _, tt := range tests {
+			g := Int(tt.min, tt.max)
+			for i := 0; i < 100; i++ {
+				v := g.Generate(r, i).(int)
+				assert.True(t, tt.min <= v && v <= tt.max, v)
+			}
+		}
+	})
+
+	t.Run("差があふれる値を小さくする", func(t *testing.T) {
+		t.Parallel()
+		tests := []struct {
+			name      string
+			v, target int
+			first     int
+			last      int
+		}{
+			{name: "最大値", v: maxInt, target: 0, first: 0, last: maxInt - 1},
+			{name: "最小値", v: minInt, target: 0, first: 0, last: minInt + 1},
+			{name: "最大値から最小値", v: maxInt, target: minInt, first: minInt, last: maxInt - 1},
+			{name: "最小値から最大値", v: minInt, target: maxInt, first: maxInt, last: minInt + 1},
+		}
+		for _, tt := range tests {
+			candidates := shrinkInt(tt.v, tt.target)
+			if assert.NotEmpty(t, candidates, tt.name) {
+				assert.Equal(t, tt.first, candidates[0], tt.name)
+				assert.Equal(t, tt.last, candidates[len(candidates)-1], tt.name)
+			}
+			// すべてvとtargetの間にある
+			for _, c := range candidates {
+				c := c.(int)
+				if tt.v < tt.target {
+					assert.True(t, tt.v < c && c <= tt.target, tt.name)
+				} else {
+					assert.True(t, tt.target <= c && c < tt.v, tt.name)
+				}
+			}
+		}
+	})
+
+	t.Run("minがmaxより大きい", func(t *testing.T) {
+		t.Parallel()
+		assert.PanicsWithValue(t, "prop.Int: minの5がmaxの1より大きくなっています", func() { Int(5, 1) })
+	})
+}
+
+func TestString(t *testing.T) {
+	t.Run("マルチバイトの文字を含む", func(t *testing.T) {
+		t.Parallel()
+		g := String(0, 20)
+		r := rand.New(rand.NewSource(1))
+		multibyte := false
+		for i := 0; i < 100; i++ {
+			s := g.Generate(r, 100).(string)
+			assert.True(t, utf8.ValidString(s))
+			assert.True(t, utf8.RuneCountInString(s) <= 20, s)
+			multibyte = multibyte || len(s) != utf8.RuneCountInString(s)
+		}
+		assert.True(t, multibyte)
+	})
+
+	t.Run("文字を取り除いてから置き換える", func(t *testing.T) {
+		t.Parallel()
+		g := StringOf("ab", 1, 10)
+		assert.Equal(t, []interface{}{"b", "b", "ab", "ba"}, g.Shrink("bb"))
+		// minLenより短くしない
+		assert.Equal(t, []interface{}{"a"}, g.Shrink("b"))
+	})
+}
+
+func TestSliceOf(t *testing.T) {
+	g := SliceOf(Int(0, 10), 1, 5)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		s := g.Generate(r, i).([]int)
+		assert.True(t, 1 <= len(s) && len(s) <= 5, s)
+	}
+	assert.Equal(t, []interface{}{[]int{2}, []int{1}, []int{0, 2}, []int{1, 0}, []int{1, 1}}, g.Shrink([]int{1, 2}))
+	assert.Equal(t, []interface{}{[]int{0}}, g.Shrink([]int{1}))
+	// 要素の型のスライスになる
+	assert.Equal(t, []interface{}{[]string{}}, SliceOf(String(0, 1), 0, 1).Shrink([]string{""}))
+}
+
+func TestCSVLine(t *testing.T) {
+	g := CSVLine(",", 2, 4)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		line := g.Generate(r, 100).(string)
+		n := len(strings.Split(line, ","))
+		assert.True(t, 2 <= n && n <= 4, line)
+		assert.False(t, strings.ContainsAny(line, "\r\n"), line)
+	}
+	// フィールドを取り除いてから、各フィールドを小さくする
+	assert.Equal(t, []interface{}{"b,c", "a,c", "a,b", ",b,c", "a,,c", "a,a,c", "a,b,", "a,b,a"}, g.Shrink("a,b,c"))
+
+	// 1フィールドの空の行を0フィールドに縮めない
+	assert.Equal(t, []interface{}(nil), CSVLine(",", 1, 3).Shrink(""))
+	assert.PanicsWithValue(t, "prop.CSVLine: minFieldsには1以上を指定してください (0)", func() { CSVLine(",", 0, 3) })
+}
+
+func TestOneOf(t *testing.T) {
+	g := OneOf("+", "-", "×", "÷")
+	r := rand.New(rand.NewSource(1))
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		seen[g.Generate(r, i).(string)] = true
+	}
+	assert.Len(t, seen, 4)
+	assert.Equal(t, []interface{}{"+", "-"}, g.Shrink("×"))
+	assert.Empty(t, g.Shrink("+"))
+}
@@ -0,0 +1,181 @@
+// Package prop ランダムに生成した値でプロパティ(常に成り立つ性質)を確かめる
+//
+// テーブルテストでは手で選んだ値しか試せないので、ジェネレータで生成した多くの値で関数を呼び出し、
+// 成り立たない値が見つかった場合はできるだけ小さい値に縮めてから報告する。
+//
+//	prop.Check(t, func(s []int) bool {
+//		return reflect.DeepEqual(Unique(Unique(s)), Unique(s))
+//	}, prop.SliceOf(prop.Int(-5, 5), 0, 20))
+//
+// 失敗したときに表示するシードを-prop.seedで指定すると、同じ値で再現できる。
+package prop
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var seedFlag = flag.Int64("prop.seed", 0, "プロパティテストで使う乱数のシード (0の場合は時刻から決める)")
+var runsFlag = flag.Int("prop.runs", 0, "プロパティテストで値を生成する回数 (0の場合はConfig.Runs)")
+
+// DefaultRuns Config.Runsを指定しない場合に値を生成する回数
+const DefaultRuns = 100
+
+// DefaultMaxShrinks Config.MaxShrinksを指定しない場合に値を小さくする回数の上限
+const DefaultMaxShrinks = 1000
+
+// TB Checkが結果を報告する先。*testing.Tが満たす
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Config プロパティを確かめる設定
+type Config struct {
+	// Runs 値を生成する回数。0の場合はDefaultRuns
+	Runs int
+	// Seed 乱数のシード。0の場合は-prop.seed、それも0の場合は時刻から決める
+	Seed int64
+	// MaxShrinks 値を小さくする回数の上限。0の場合はDefaultMaxShrinks
+	MaxShrinks int
+}
+
+// Check デフォルトの設定でConfig.Checkを呼び出す
+func Check(t TB, property interface{}, gens ...Gen) {
+	t.Helper()
+	Config{}.Check(t, property, gens...)
+}
+
+// Check gensで生成した値を引数にしてpropertyを呼び出し、成り立たない場合はtに報告する
+// propertyはgensと同じ数と型の引数を受け取り、boolかerrorを返す関数にする
+// falseかnilでないerrorを返すか、パニックした場合に成り立たないとみなす
+func (c Config) Check(t TB, property interface{}, gens ...Gen) {
+	t.Helper()
+	f := reflect.ValueOf(property)
+	if err := checkSignature(f.Type(), gens); err != nil {
+		t.Errorf("prop: %v", err)
+		return
+	}
+
+	runs := c.Runs
+	if *runsFlag > 0 {
+		runs = *runsFlag
+	}
+	if runs <= 0 {
+		runs = DefaultRuns
+	}
+	seed := c.Seed
+	if seed == 0 {
+		seed = *seedFlag
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	maxShrinks := c.MaxShrinks
+	if maxShrinks <= 0 {
+		maxShrinks = DefaultMaxShrinks
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	for i := 0; i < runs; i++ {
+		size := 100
+		if runs > 1 {
+			size = i * 100 / (runs - 1)
+		}
+		args := make([]interface{}, len(gens))
+		for j, g := range gens {
+			args[j] = g.Generate(r, size)
+		}
+		err := call(f, args)
+		if err == nil {
+			continue
+		}
+		original := args
+		args, shrinks, err := shrink(f, gens, args, err, maxShrinks)
+		t.Errorf("プロパティが成り立ちません (seed=%d, %d回目, %d回縮小)\n%s  理由: %v\n  元の値:\n%s",
+			seed, i+1, shrinks, formatArgs(args), err, formatArgs(original))
+		return
+	}
+}
+
+func checkSignature(typ reflect.Type, gens []Gen) error {
+	if typ.Kind() != reflect.Func {
+		return fmt.Errorf("プロパティは関数にしてください: %v", typ)
+	}
+	if typ.NumIn() != len(gens) {
+		return fmt.Errorf("プロパティの引数は%d個ですが、ジェネレータは%d個です", typ.NumIn(), len(gens))
+	}
+	for i, g := range gens {
+		if !g.typ.AssignableTo(typ.In(i)) {
+			return fmt.Errorf("引数%dの型%vに%vは渡せません", i, typ.In(i), g.typ)
+		}
+	}
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	if typ.NumOut() != 1 || (typ.Out(0).Kind() != reflect.Bool && typ.Out(0) != errorType) {
+		return fmt.Errorf("プロパティはboolかerrorを1つ返してください: %v", typ)
+	}
+	return nil
+}
+
+// call propertyを呼び出し、成り立たない場合はその理由を返す
+func call(f reflect.Value, args []interface{}) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("パニックしました: %v", p)
+		}
+	}()
+	in := make([]reflect.Value, len(args))
+	for i, a := range args {
+		in[i] = reflect.ValueOf(a)
+	}
+	out := f.Call(in)[0]
+	if out.Kind() == reflect.Bool {
+		if !out.Bool() {
+			return fmt.Errorf("falseを返しました")
+		}
+		return nil
+	}
+	if out.IsNil() {
+		return nil
+	}
+	return out.Interface().(error)
+}
+
+// shrink 引数を1つずつ小さくし、成り立たないままの最も小さい値を返す
+func shrink(f reflect.Value, gens []Gen, args []interface{}, err error, maxShrinks int) ([]interface{}, int, error) {
+	shrinks := 0
+	for shrinks < maxShrinks {
+		smaller := false
+		for i, g := range gens {
+			for _, candidate := range g.Shrink(args[i]) {
+				next := append([]interface{}{}, args...)
+				next[i] = candidate
+				if e := call(f, next); e != nil {
+					args, err = next, e
+					smaller = true
+					shrinks++
+					break
+				}
+			}
+			if smaller {
+				break
+			}
+		}
+		if !smaller {
+			break
+		}
+	}
+	return args, shrinks, err
+}
+
+func formatArgs(args []interface{}) string {
+	var b strings.Builder
+	for i, a := range args {
+		fmt.Fprintf(&b, "    引数%d: %#v\n", i, a)
+	}
+	return b.String()
+}
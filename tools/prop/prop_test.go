@@ -0,0 +1,115 @@
+package prop
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeT Checkが報告したエラーを覚えておくTB
+type fakeT struct {
+	errors []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestCheck(t *testing.T) {
+	t.Run("成り立つプロパティ", func(t *testing.T) {
+		t.Parallel()
+		runs := 0
+		Check(t, func(s []int) bool {
+			runs++
+			sorted := append([]int{}, s...)
+			sort.Ints(sorted)
+			return sort.IntsAreSorted(sorted) && len(sorted) == len(s)
+		}, SliceOf(Int(-100, 100), 0, 20))
+		assert.Equal(t, DefaultRuns, runs)
+	})
+
+	t.Run("成り立たない値を小さくして報告する", func(t *testing.T) {
+		t.Parallel()
+		ft := &fakeT{}
+		// 10以上の要素を含むスライスで成り立たない
+		Config{Seed: 1}.Check(ft, func(s []int) bool {
+			for _, v := range s {
+				if v >= 10 {
+					return false
+				}
+			}
+			return true
+		}, SliceOf(Int(-100, 100), 0, 20))
+		if assert.Len(t, ft.errors, 1) {
+			assert.True(t, strings.HasPrefix(ft.errors[0], "プロパティが成り立ちません (seed=1, "), ft.errors[0])
+			assert.Contains(t, ft.errors[0], "回縮小)\n    引数0: []int{10}\n  理由: falseを返しました\n  元の値:\n")
+		}
+	})
+
+	t.Run("errorを返すプロパティ", func(t *testing.T) {
+		t.Parallel()
+		ft := &fakeT{}
+		Config{Seed: 1}.Check(ft, func(x, y int) error {
+			if x > 0 && y > 0 {
+				return errors.New("両方とも正の数です")
+			}
+			return nil
+		}, Int(-10, 10), Int(-10, 10))
+		if assert.Len(t, ft.errors, 1) {
+			assert.Contains(t, ft.errors[0], "    引数0: 1\n    引数1: 1\n  理由: 両方とも正の数です\n")
+		}
+	})
+
+	t.Run("パニックは成り立たないとみなす", func(t *testing.T) {
+		t.Parallel()
+		ft := &fakeT{}
+		Config{Seed: 1}.Check(ft, func(s string) bool {
+			return s[len(s)-1] != 0
+		}, String(0, 10))
+		if assert.Len(t, ft.errors, 1) {
+			assert.Contains(t, ft.errors[0], "    引数0: \"\"\n  理由: パニックしました: runtime error: index out of range [-1]\n")
+		}
+	})
+
+	t.Run("同じシードなら同じ値を生成する", func(t *testing.T) {
+		t.Parallel()
+		generated := func() []string {
+			var values []string
+			Config{Seed: 42, Runs: 10}.Check(t, func(s string) bool {
+				values = append(values, s)
+				return true
+			}, String(0, 10))
+			return values
+		}
+		assert.Equal(t, generated(), generated())
+	})
+}
+
+func TestCheck_Signature(t *testing.T) {
+	tests := []struct {
+		name     string
+		property interface{}
+		gens     []Gen
+		want     string
+	}{
+		{name: "関数でない", property: 1, want: "prop: プロパティは関数にしてください: int"},
+		{name: "引数の数が違う", property: func(int) bool { return true }, want: "prop: プロパティの引数は1個ですが、ジェネレータは0個です"},
+		{name: "型が違う", property: func(string) bool { return true }, gens: []Gen{Int(0, 1)}, want: "prop: 引数0の型stringにintは渡せません"},
+		{name: "戻り値が違う", property: func(int) int { return 0 }, gens: []Gen{Int(0, 1)}, want: "prop: プロパティはboolかerrorを1つ返してください: func(int) int"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ft := &fakeT{}
+			Check(ft, tt.property, tt.gens...)
+			assert.Equal(t, []string{tt.want}, ft.errors)
+		})
+	}
+}